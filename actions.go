@@ -0,0 +1,299 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/Native-Robotics/asset-fetch/afetch"
+)
+
+// Workflow runs are listed newest first, following pagination up to
+// maxWorkflowRunPages pages
+const (
+	workflowRunsPerPage = 100
+	maxWorkflowRunPages = 5
+)
+
+// RunFilter narrows the listed workflow runs
+type RunFilter struct {
+	Workflow string // workflow file name (e.g. "nightly.yml") or ID
+	Branch   string
+	Status   string // e.g. "success", "failure", "completed", "in_progress"
+}
+
+// WorkflowRun structure for storing GitHub Actions run information
+type WorkflowRun struct {
+	ID           int64  `json:"id"`
+	Name         string `json:"name"`
+	DisplayTitle string `json:"display_title"`
+	RunNumber    int    `json:"run_number"`
+	HeadBranch   string `json:"head_branch"`
+	HeadSHA      string `json:"head_sha"`
+	Event        string `json:"event"`
+	Status       string `json:"status"`
+	Conclusion   string `json:"conclusion"`
+	Path         string `json:"path"`
+	CreatedAt    string `json:"created_at"`
+	HTMLURL      string `json:"html_url"`
+}
+
+// Label returns a short identifier for the run, e.g. "nightly #42"
+func (r WorkflowRun) Label() string {
+	return fmt.Sprintf("%s #%d", r.Name, r.RunNumber)
+}
+
+// Result returns the conclusion of a finished run, or its status otherwise
+func (r WorkflowRun) Result() string {
+	if r.Conclusion != "" {
+		return r.Conclusion
+	}
+	return r.Status
+}
+
+// Artifact structure for storing workflow artifact information
+type Artifact struct {
+	ID                 int64  `json:"id"`
+	Name               string `json:"name"`
+	SizeInBytes        int64  `json:"size_in_bytes"`
+	URL                string `json:"url"`
+	ArchiveDownloadURL string `json:"archive_download_url"`
+	Expired            bool   `json:"expired"`
+	Digest             string `json:"digest"`
+	CreatedAt          string `json:"created_at"`
+	UpdatedAt          string `json:"updated_at"`
+	ExpiresAt          string `json:"expires_at"`
+}
+
+type workflowRunsResponse struct {
+	TotalCount   int           `json:"total_count"`
+	WorkflowRuns []WorkflowRun `json:"workflow_runs"`
+}
+
+type artifactsResponse struct {
+	TotalCount int        `json:"total_count"`
+	Artifacts  []Artifact `json:"artifacts"`
+}
+
+// workflowRunsMsg carries the listed workflow runs
+type workflowRunsMsg []WorkflowRun
+
+// artifactsMsg carries the downloadable artifacts of a run
+type artifactsMsg struct {
+	run     WorkflowRun
+	assets  []AssetInfo
+	expired int
+}
+
+// fetchWorkflowRuns lists the workflow runs of owner/repo matching filter
+func fetchWorkflowRuns(owner, repo string, filter RunFilter) tea.Cmd {
+	return func() tea.Msg {
+		var token string
+		if config, err := loadConfig(); err == nil {
+			token = config.GitHubToken
+		}
+
+		apiURL := fmt.Sprintf("%s/repos/%s/%s/actions/runs", githubAPIURL, owner, repo)
+		if filter.Workflow != "" {
+			apiURL = fmt.Sprintf("%s/repos/%s/%s/actions/workflows/%s/runs", githubAPIURL, owner, repo, url.PathEscape(filter.Workflow))
+		}
+		query := url.Values{}
+		query.Set("per_page", strconv.Itoa(workflowRunsPerPage))
+		if filter.Branch != "" {
+			query.Set("branch", filter.Branch)
+		}
+		if filter.Status != "" {
+			query.Set("status", filter.Status)
+		}
+
+		var runs []WorkflowRun
+		apiURL += "?" + query.Encode()
+		for page := 0; apiURL != "" && page < maxWorkflowRunPages; page++ {
+			var result workflowRunsResponse
+			next, err := getGitHubJSON(apiURL, token, &result)
+			if err != nil {
+				return errorMsg(err.Error())
+			}
+			runs = append(runs, result.WorkflowRuns...)
+			apiURL = next
+		}
+		if len(runs) == 0 {
+			return errorMsg(fmt.Sprintf("no workflow runs found for %s/%s", owner, repo))
+		}
+		return workflowRunsMsg(runs)
+	}
+}
+
+// fetchRunArtifacts lists the artifacts of run as downloadable assets
+func fetchRunArtifacts(owner, repo string, run WorkflowRun) tea.Cmd {
+	return func() tea.Msg {
+		var token string
+		if config, err := loadConfig(); err == nil {
+			token = config.GitHubToken
+		}
+
+		apiURL := fmt.Sprintf("%s/repos/%s/%s/actions/runs/%d/artifacts?per_page=100", githubAPIURL, owner, repo, run.ID)
+		var artifacts []Artifact
+		for apiURL != "" {
+			var result artifactsResponse
+			next, err := getGitHubJSON(apiURL, token, &result)
+			if err != nil {
+				return errorMsg(err.Error())
+			}
+			artifacts = append(artifacts, result.Artifacts...)
+			apiURL = next
+		}
+
+		msg := artifactsMsg{run: run}
+		formatter := AssetFormatter{}
+		for _, artifact := range artifacts {
+			if artifact.Expired {
+				msg.expired++
+				continue
+			}
+			asset := formatter.FormatArtifactInfo(artifact, run)
+			asset.Repository = owner + "/" + repo
+			msg.assets = append(msg.assets, asset)
+		}
+		return msg
+	}
+}
+
+// getGitHubJSON fetches apiURL and decodes the JSON response into v. It
+// returns the URL of the next page of a paginated response, or "".
+func getGitHubJSON(apiURL, token string, v interface{}) (string, error) {
+	req, err := afetch.NewRequest(context.Background(), apiURL, token)
+	if err != nil {
+		return "", err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GitHub API error: %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return "", err
+	}
+	return afetch.NextPageURL(resp), nil
+}
+
+// artifactDirName returns the directory an artifact is extracted into
+func artifactDirName(name string) string {
+	return strings.NewReplacer("/", "-", "\\", "-").Replace(name)
+}
+
+// extractArtifact extracts the zip archive at zipPath into a new directory
+// that then replaces dir, so that files of an earlier extraction do not
+// linger in it
+func extractArtifact(zipPath, dir string) error {
+	tmp, err := os.MkdirTemp(filepath.Dir(dir), "."+filepath.Base(dir)+".extract-*")
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, 0o755); err != nil {
+		_ = os.RemoveAll(tmp)
+		return err
+	}
+	if err := extractZip(zipPath, tmp); err != nil {
+		_ = os.RemoveAll(tmp)
+		return err
+	}
+
+	// An existing target is only removed once the new one is in place
+	previous := ""
+	if _, err := os.Lstat(dir); err == nil {
+		previous = tmp + ".old"
+		if err := os.Rename(dir, previous); err != nil {
+			_ = os.RemoveAll(tmp)
+			return err
+		}
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		if previous != "" {
+			_ = os.Rename(previous, dir)
+		}
+		_ = os.RemoveAll(tmp)
+		return err
+	}
+	if previous != "" {
+		return os.RemoveAll(previous)
+	}
+	return nil
+}
+
+// extractZip extracts the zip archive at zipPath into dir, refusing entries
+// that would escape it
+func extractZip(zipPath, dir string) error {
+	reader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	for _, file := range reader.File {
+		target := filepath.Join(root, filepath.FromSlash(file.Name))
+		if target != root && !strings.HasPrefix(target, root+string(os.PathSeparator)) {
+			return fmt.Errorf("archive entry %q escapes %s", file.Name, dir)
+		}
+		if file.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		if err := extractZipFile(file, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractZipFile writes a single zip entry to target
+func extractZipFile(file *zip.File, target string) error {
+	in, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+
+	mode := file.Mode().Perm()
+	if mode == 0 {
+		mode = 0o644
+	}
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseActionsArgs(t *testing.T) {
+	for _, test := range []struct {
+		args   []string
+		owner  string
+		name   string
+		filter RunFilter
+	}{
+		{[]string{"owner/repo"}, "owner", "repo", RunFilter{}},
+		{[]string{"--workflow", "nightly.yml", "--branch", "main", "--status", "success", "owner/repo"}, "owner", "repo",
+			RunFilter{Workflow: "nightly.yml", Branch: "main", Status: "success"}},
+		{[]string{"--status=failure", "https://github.com/owner/repo/actions"}, "owner", "repo", RunFilter{Status: "failure"}},
+	} {
+		owner, name, filter, err := parseActionsArgs(test.args)
+		if err != nil || owner != test.owner || name != test.name || filter != test.filter {
+			t.Errorf("%v: got %s/%s %+v, %v", test.args, owner, name, filter, err)
+		}
+	}
+
+	for _, args := range [][]string{{}, {"owner/repo", "other/repo"}, {"owner"}, {"--event", "push", "owner/repo"}, {"--workflow"}} {
+		if _, _, _, err := parseActionsArgs(args); err == nil {
+			t.Errorf("%v accepted", args)
+		}
+	}
+}
+
+// writeZip writes a zip archive of the named files and returns its path
+func writeZip(t *testing.T, files map[string]string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "artifact.zip")
+	file, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := zip.NewWriter(file)
+	for name, content := range files {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestExtractZipRefusesEscapingEntries(t *testing.T) {
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "artifact")
+	for _, name := range []string{"../evil.sh", "bin/../../evil.sh", "bin/../../../evil.sh"} {
+		err := extractZip(writeZip(t, map[string]string{name: "#!/bin/sh"}), dir)
+		if err == nil || !strings.Contains(err.Error(), "escapes") {
+			t.Errorf("%s: got %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(parent, "evil.sh")); !os.IsNotExist(err) {
+		t.Error("entry written outside the target directory")
+	}
+
+	if err := extractZip(writeZip(t, map[string]string{"bin/tool": "binary", "docs/../README": "readme"}), dir); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"bin/tool": "binary", "README": "readme"} {
+		if content, _ := os.ReadFile(filepath.Join(dir, name)); string(content) != want {
+			t.Errorf("%s = %q, want %q", name, content, want)
+		}
+	}
+}
+
+func TestExtractArtifactReplacesDirectory(t *testing.T) {
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "build-output")
+	if err := extractArtifact(writeZip(t, map[string]string{"old.txt": "old build", "shared.txt": "old"}), dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// Files of the earlier extraction are not merged into the new one
+	if err := extractArtifact(writeZip(t, map[string]string{"shared.txt": "new"}), dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "old.txt")); !os.IsNotExist(err) {
+		t.Error("file of the earlier extraction kept")
+	}
+	if content, _ := os.ReadFile(filepath.Join(dir, "shared.txt")); string(content) != "new" {
+		t.Errorf("shared.txt = %q", content)
+	}
+
+	// A failed extraction leaves the existing directory alone
+	if err := extractArtifact(writeZip(t, map[string]string{"../evil.sh": "#!/bin/sh"}), dir); err == nil {
+		t.Fatal("escaping entry extracted")
+	}
+	if content, _ := os.ReadFile(filepath.Join(dir, "shared.txt")); string(content) != "new" {
+		t.Errorf("after a failed extraction, shared.txt = %q", content)
+	}
+	if entries, _ := os.ReadDir(parent); len(entries) != 1 {
+		t.Errorf("temporary directories left behind: %v", entries)
+	}
+}
+
+// serveWorkflowRuns serves total workflow runs of owner/repo, newest first,
+// in pages of per_page runs linked by Link headers
+func serveWorkflowRuns(t *testing.T, total int) *[]string {
+	t.Helper()
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RequestURI())
+		if r.URL.Path != "/repos/owner/repo/actions/runs" {
+			http.NotFound(w, r)
+			return
+		}
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		page = max(page, 1)
+		var runs []WorkflowRun
+		for n := total - (page-1)*perPage; n > 0 && len(runs) < perPage; n-- {
+			runs = append(runs, WorkflowRun{ID: int64(n), RunNumber: n, Name: "build"})
+		}
+		if page*perPage < total {
+			query := r.URL.Query()
+			query.Set("page", strconv.Itoa(page+1))
+			w.Header().Set("Link", fmt.Sprintf(`<http://%s%s?%s>; rel="next"`, r.Host, r.URL.Path, query.Encode()))
+		}
+		_ = json.NewEncoder(w).Encode(workflowRunsResponse{TotalCount: total, WorkflowRuns: runs})
+	}))
+	t.Cleanup(server.Close)
+	previous := githubAPIURL
+	githubAPIURL = server.URL
+	t.Cleanup(func() { githubAPIURL = previous })
+	return &requests
+}
+
+func TestFetchWorkflowRunsFollowsPagination(t *testing.T) {
+	setupFakeGitHub(t)
+	requests := serveWorkflowRuns(t, workflowRunsPerPage+20)
+
+	msg := fetchWorkflowRuns("owner", "repo", RunFilter{Status: "success"})()
+	runs, ok := msg.(workflowRunsMsg)
+	if !ok {
+		t.Fatalf("got %#v, want workflowRunsMsg", msg)
+	}
+	if len(runs) != workflowRunsPerPage+20 || runs[0].RunNumber != workflowRunsPerPage+20 || runs[len(runs)-1].RunNumber != 1 {
+		t.Errorf("listed %d runs", len(runs))
+	}
+	if len(*requests) != 2 || !strings.Contains((*requests)[1], "status=success") {
+		t.Errorf("requests = %q, want two pages with the filter", *requests)
+	}
+}
+
+func TestFetchWorkflowRunsStopsAtPageLimit(t *testing.T) {
+	setupFakeGitHub(t)
+	requests := serveWorkflowRuns(t, workflowRunsPerPage*(maxWorkflowRunPages+2))
+
+	runs, ok := fetchWorkflowRuns("owner", "repo", RunFilter{})().(workflowRunsMsg)
+	if !ok || len(runs) != workflowRunsPerPage*maxWorkflowRunPages {
+		t.Errorf("listed %d runs, want the newest %d", len(runs), workflowRunsPerPage*maxWorkflowRunPages)
+	}
+	if len(*requests) != maxWorkflowRunPages {
+		t.Errorf("%d requests, want %d", len(*requests), maxWorkflowRunPages)
+	}
+}
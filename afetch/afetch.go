@@ -0,0 +1,210 @@
+// Package afetch lists the releases of GitHub repositories, matches their
+// assets against glob masks and downloads them with digest verification.
+// It is the library the afetch command is built on.
+package afetch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+)
+
+// DefaultAPIURL is the base URL of the GitHub REST API
+const DefaultAPIURL = "https://api.github.com"
+
+// Client sends requests to the GitHub REST API. The zero value talks to
+// api.github.com anonymously.
+type Client struct {
+	// Base URL of the API, DefaultAPIURL when empty; e.g. an "afetch serve" proxy
+	APIURL string
+	// Token authorizes requests when not empty
+	Token string
+	// HTTPClient sends requests, http.DefaultClient when nil
+	HTTPClient *http.Client
+}
+
+// NewClient returns a client for api.github.com authorized by token
+func NewClient(token string) *Client {
+	return &Client{Token: token}
+}
+
+// User is a GitHub account
+type User struct {
+	Login string `json:"login"`
+}
+
+// Asset is a file attached to a release
+type Asset struct {
+	ID                 int    `json:"id"`
+	Name               string `json:"name"`
+	URL                string `json:"url"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+	ContentType        string `json:"content_type"`
+	State              string `json:"state"`
+	Size               int64  `json:"size"`
+	CreatedAt          string `json:"created_at"`
+	UpdatedAt          string `json:"updated_at"`
+	Digest             string `json:"digest"`
+	DownloadCount      int    `json:"download_count"`
+	Uploader           *User  `json:"uploader"`
+}
+
+// Release is a GitHub release and its assets
+type Release struct {
+	TagName     string  `json:"tag_name"`
+	Name        string  `json:"name"`
+	PublishedAt string  `json:"published_at"`
+	TarballURL  string  `json:"tarball_url"`
+	ZipballURL  string  `json:"zipball_url"`
+	Assets      []Asset `json:"assets"`
+
+	// Raw is the release as returned by the API, including fields not
+	// decoded above
+	Raw json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decodes a release and keeps the raw document in Raw
+func (r *Release) UnmarshalJSON(data []byte) error {
+	type release Release
+	if err := json.Unmarshal(data, (*release)(r)); err != nil {
+		return err
+	}
+	r.Raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+// NewRequest creates a GET request for the GitHub REST API with the
+// standard headers and, if token is not empty, an authorization header
+func NewRequest(ctx context.Context, apiURL, token string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	return req, nil
+}
+
+var nextLinkPattern = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// NextPageURL returns the URL of the next page from a paginated response's
+// Link header, or "" on the last page
+func NextPageURL(resp *http.Response) string {
+	if match := nextLinkPattern.FindStringSubmatch(resp.Header.Get("Link")); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+func (c *Client) apiURL() string {
+	if c.APIURL == "" {
+		return DefaultAPIURL
+	}
+	return c.APIURL
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient == nil {
+		return http.DefaultClient
+	}
+	return c.HTTPClient
+}
+
+// getJSON decodes the API document at apiURL into v and returns the response
+func (c *Client) getJSON(ctx context.Context, apiURL string, v interface{}) (*http.Response, error) {
+	req, err := NewRequest(ctx, apiURL, c.Token)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp, newAPIError(resp)
+	}
+	return resp, json.NewDecoder(resp.Body).Decode(v)
+}
+
+// ListReleases returns all releases of owner/repo, newest first, following
+// pagination
+func (c *Client) ListReleases(ctx context.Context, owner, repo string) ([]Release, error) {
+	apiURL := fmt.Sprintf("%s/repos/%s/%s/releases?per_page=100", c.apiURL(), owner, repo)
+	var releases []Release
+	for apiURL != "" {
+		var page []Release
+		resp, err := c.getJSON(ctx, apiURL, &page)
+		if err != nil {
+			return nil, err
+		}
+		releases = append(releases, page...)
+		apiURL = NextPageURL(resp)
+	}
+	return releases, nil
+}
+
+// GetRelease returns the release of owner/repo tagged tag, or the latest
+// release when tag is ""
+func (c *Client) GetRelease(ctx context.Context, owner, repo, tag string) (*Release, error) {
+	apiURL := fmt.Sprintf("%s/repos/%s/%s/releases/latest", c.apiURL(), owner, repo)
+	if tag != "" {
+		apiURL = fmt.Sprintf("%s/repos/%s/%s/releases/tags/%s", c.apiURL(), owner, repo, tag)
+	}
+	var release Release
+	if _, err := c.getJSON(ctx, apiURL, &release); err != nil {
+		return nil, err
+	}
+	return &release, nil
+}
+
+// ResolveAssets returns the assets whose names match mask ("" matches all)
+// of the release tagged tag, or of the latest release when tag is "". It
+// returns an error wrapping ErrNoAssets when none match.
+func (c *Client) ResolveAssets(ctx context.Context, owner, repo, tag, mask string) ([]Asset, error) {
+	release, err := c.GetRelease(ctx, owner, repo, tag)
+	if err != nil {
+		return nil, err
+	}
+	assets, err := MatchAssets(release.Assets, mask)
+	if err != nil {
+		return nil, err
+	}
+	if len(assets) == 0 {
+		return nil, fmt.Errorf("%w matching %q in release %s", ErrNoAssets, mask, release.TagName)
+	}
+	return assets, nil
+}
+
+// MatchMask reports whether name matches the glob mask; "" matches all
+func MatchMask(mask, name string) (bool, error) {
+	if mask == "" {
+		return true, nil
+	}
+	matched, err := path.Match(mask, name)
+	if err != nil {
+		return false, fmt.Errorf("invalid mask %q: %w", mask, err)
+	}
+	return matched, nil
+}
+
+// MatchAssets returns the assets whose names match the glob mask
+func MatchAssets(assets []Asset, mask string) ([]Asset, error) {
+	var matched []Asset
+	for _, asset := range assets {
+		ok, err := MatchMask(mask, asset.Name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, asset)
+		}
+	}
+	return matched, nil
+}
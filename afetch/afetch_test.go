@@ -0,0 +1,349 @@
+package afetch_test
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Native-Robotics/asset-fetch/afetch"
+	"github.com/Native-Robotics/asset-fetch/internal/githubtest"
+)
+
+func newTestServer(t *testing.T) (*githubtest.Server, *afetch.Client) {
+	t.Helper()
+	server := githubtest.NewServer(t)
+	server.AddRelease("owner/repo", githubtest.Release{Tag: "v1.1.0", Assets: []githubtest.Asset{
+		{Name: "app_linux_amd64.tar.gz", Content: []byte("linux build 1.1.0")},
+		{Name: "app_darwin_arm64.tar.gz", Content: []byte("darwin build 1.1.0")},
+		{Name: "checksums.txt", Content: []byte("checksums 1.1.0")},
+	}})
+	server.AddRelease("owner/repo", githubtest.Release{Tag: "v1.0.0", Assets: []githubtest.Asset{
+		{Name: "app_linux_amd64.tar.gz", Content: []byte("linux build 1.0.0")},
+	}})
+	return server, &afetch.Client{APIURL: server.URL}
+}
+
+func TestListReleasesFollowsPagination(t *testing.T) {
+	server := githubtest.NewServer(t)
+	server.MaxPerPage = 2
+	for i := 5; i > 0; i-- {
+		server.AddRelease("owner/repo", githubtest.Release{Tag: fmt.Sprintf("v%d", i)})
+	}
+
+	releases, err := (&afetch.Client{APIURL: server.URL}).ListReleases(context.Background(), "owner", "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tags []string
+	for _, release := range releases {
+		tags = append(tags, release.TagName)
+	}
+	if got := strings.Join(tags, " "); got != "v5 v4 v3 v2 v1" {
+		t.Errorf("tags = %q, want all five releases in order", got)
+	}
+	if len(server.Requests()) != 3 {
+		t.Errorf("%d requests, want 3 pages", len(server.Requests()))
+	}
+	if !strings.Contains(string(releases[0].Raw), `"html_url"`) {
+		t.Errorf("Raw = %s, want the undecoded fields kept", releases[0].Raw)
+	}
+}
+
+func TestGetRelease(t *testing.T) {
+	_, client := newTestServer(t)
+
+	latest, err := client.GetRelease(context.Background(), "owner", "repo", "")
+	if err != nil || latest.TagName != "v1.1.0" {
+		t.Fatalf("latest = %v, %v; want v1.1.0", latest, err)
+	}
+	tagged, err := client.GetRelease(context.Background(), "owner", "repo", "v1.0.0")
+	if err != nil || tagged.TagName != "v1.0.0" || len(tagged.Assets) != 1 {
+		t.Fatalf("tagged = %v, %v; want v1.0.0 with one asset", tagged, err)
+	}
+
+	_, err = client.GetRelease(context.Background(), "owner", "repo", "v9")
+	var apiErr *afetch.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 404 || apiErr.RateLimited {
+		t.Errorf("err = %v, want a 404 APIError", err)
+	}
+}
+
+func TestResolveAssets(t *testing.T) {
+	_, client := newTestServer(t)
+	ctx := context.Background()
+
+	assets, err := client.ResolveAssets(ctx, "owner", "repo", "", "*.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(assets) != 2 || assets[0].Name != "app_linux_amd64.tar.gz" || assets[1].Name != "app_darwin_arm64.tar.gz" {
+		t.Errorf("assets = %v, want both tarballs of the latest release", assets)
+	}
+
+	assets, err = client.ResolveAssets(ctx, "owner", "repo", "v1.0.0", "")
+	if err != nil || len(assets) != 1 {
+		t.Errorf("assets = %v, %v; want the one asset of v1.0.0", assets, err)
+	}
+
+	if _, err := client.ResolveAssets(ctx, "owner", "repo", "", "*.zip"); !errors.Is(err, afetch.ErrNoAssets) {
+		t.Errorf("err = %v, want ErrNoAssets", err)
+	}
+	if _, err := client.ResolveAssets(ctx, "owner", "repo", "", "[a-"); err == nil {
+		t.Error("invalid mask accepted")
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	server, client := newTestServer(t)
+	server.SetRateLimited(true)
+
+	_, err := client.ListReleases(context.Background(), "owner", "repo")
+	var apiErr *afetch.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 403 || !apiErr.RateLimited {
+		t.Fatalf("err = %v, want a rate-limited APIError", err)
+	}
+	if !strings.Contains(err.Error(), "rate limit") {
+		t.Errorf("error %q does not mention the rate limit", err)
+	}
+}
+
+func TestToken(t *testing.T) {
+	server, client := newTestServer(t)
+	server.Token = "secret"
+
+	if _, err := client.ListReleases(context.Background(), "owner", "repo"); err == nil {
+		t.Error("anonymous request accepted")
+	}
+	client.Token = "secret"
+	if _, err := client.ListReleases(context.Background(), "owner", "repo"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDownload(t *testing.T) {
+	_, client := newTestServer(t)
+	ctx := context.Background()
+	assets, err := client.ResolveAssets(ctx, "owner", "repo", "", "app_linux_*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dest := filepath.Join(t.TempDir(), "app.tar.gz")
+	var downloaded, total int64
+	err = client.Download(ctx, assets[0], dest, &afetch.DownloadOptions{
+		Progress: func(d, t int64) { downloaded, total = d, t },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile(dest); string(content) != "linux build 1.1.0" {
+		t.Errorf("content = %q", content)
+	}
+	if downloaded != total || total != assets[0].Size {
+		t.Errorf("last progress %d/%d, want %d/%d", downloaded, total, assets[0].Size, assets[0].Size)
+	}
+	if _, err := os.Stat(afetch.PartialPath(dest)); !os.IsNotExist(err) {
+		t.Errorf("partial file left behind: %v", err)
+	}
+}
+
+func TestDownloadChecksumMismatch(t *testing.T) {
+	server := githubtest.NewServer(t)
+	server.AddRelease("owner/repo", githubtest.Release{Tag: "v1", Assets: []githubtest.Asset{
+		{Name: "tampered.bin", Content: []byte("tampered"), Digest: githubtest.Digest([]byte("original"))},
+	}})
+	client := &afetch.Client{APIURL: server.URL}
+	assets, err := client.ResolveAssets(context.Background(), "owner", "repo", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dest := filepath.Join(t.TempDir(), "tampered.bin")
+	err = client.Download(context.Background(), assets[0], dest, nil)
+	var checksumErr *afetch.ChecksumError
+	if !errors.As(err, &checksumErr) {
+		t.Fatalf("err = %v, want a ChecksumError", err)
+	}
+	for _, name := range []string{dest, afetch.PartialPath(dest)} {
+		if _, err := os.Stat(name); !os.IsNotExist(err) {
+			t.Errorf("%s kept after a checksum mismatch", name)
+		}
+	}
+}
+
+func TestDownloadResume(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 100))
+	server := githubtest.NewServer(t)
+	server.AddRelease("owner/repo", githubtest.Release{Tag: "v1", Assets: []githubtest.Asset{{Name: "big.bin", Content: content}}})
+	client := &afetch.Client{APIURL: server.URL}
+	assets, err := client.ResolveAssets(context.Background(), "owner", "repo", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dest := filepath.Join(t.TempDir(), "big.bin")
+	if err := os.WriteFile(afetch.PartialPath(dest), content[:300], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Download(context.Background(), assets[0], dest, &afetch.DownloadOptions{Resume: true}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(dest); string(got) != string(content) {
+		t.Errorf("resumed file has %d bytes, want the original %d", len(got), len(content))
+	}
+
+	requests := server.Requests()
+	if rangeHeader := requests[len(requests)-1].Header.Get("Range"); rangeHeader != "bytes=300-" {
+		t.Errorf("Range = %q, want bytes=300-", rangeHeader)
+	}
+}
+
+func TestDownloadInterrupted(t *testing.T) {
+	server := githubtest.NewServer(t)
+	server.AddRelease("owner/repo", githubtest.Release{Tag: "v1", Assets: []githubtest.Asset{
+		{Name: "big.bin", Content: []byte(strings.Repeat("x", 8<<20))},
+	}})
+	client := &afetch.Client{APIURL: server.URL}
+	assets, err := client.ResolveAssets(context.Background(), "owner", "repo", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	dest := filepath.Join(t.TempDir(), "big.bin")
+	err = client.Download(ctx, assets[0], dest, &afetch.DownloadOptions{
+		Progress: func(int64, int64) { cancel() },
+	})
+	var incomplete *afetch.IncompleteError
+	if !errors.As(err, &incomplete) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want an IncompleteError caused by the cancellation", err)
+	}
+	info, err := os.Stat(afetch.PartialPath(dest))
+	if err != nil || info.Size() != incomplete.Downloaded {
+		t.Errorf("partial file %v, %v; want it kept with %d bytes", info, err, incomplete.Downloaded)
+	}
+}
+
+func TestDownloadHTTPError(t *testing.T) {
+	server, client := newTestServer(t)
+	assets, err := client.ResolveAssets(context.Background(), "owner", "repo", "", "checksums.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Fail(strings.TrimPrefix(assets[0].URL, server.URL), 502)
+
+	err = client.Download(context.Background(), assets[0], filepath.Join(t.TempDir(), "checksums.txt"), nil)
+	var httpErr *afetch.HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != 502 {
+		t.Errorf("err = %v, want a 502 HTTPError", err)
+	}
+}
+
+func TestDownloadVerifyRejects(t *testing.T) {
+	_, client := newTestServer(t)
+	assets, err := client.ResolveAssets(context.Background(), "owner", "repo", "", "checksums.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rejected := errors.New("unsigned")
+	dest := filepath.Join(t.TempDir(), "checksums.txt")
+	err = client.Download(context.Background(), assets[0], dest, &afetch.DownloadOptions{
+		Verify: func(string) error { return rejected },
+	})
+	if !errors.Is(err, rejected) {
+		t.Errorf("err = %v, want the Verify error", err)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Error("rejected file was kept")
+	}
+}
+
+func TestVerifyDigest(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "file")
+	content := []byte("content")
+	if err := os.WriteFile(filename, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum512 := sha512.Sum512(content)
+
+	tests := []struct {
+		digest string
+		ok     bool
+	}{
+		{"", true},
+		{githubtest.Digest(content), true},
+		{"sha512:" + hex.EncodeToString(sum512[:]), true},
+		{githubtest.Digest([]byte("other")), false},
+		{"md5:abc", false},
+		{"sha256", false},
+	}
+	for _, test := range tests {
+		if err := afetch.VerifyDigest(filename, test.digest); (err == nil) != test.ok {
+			t.Errorf("VerifyDigest(%q) = %v, want ok = %v", test.digest, err, test.ok)
+		}
+	}
+}
+
+func TestDownloadResumeWithoutDigest(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 800<<10))
+	server := githubtest.NewServer(t)
+	server.AddRelease("owner/repo", githubtest.Release{Tag: "v1", Assets: []githubtest.Asset{
+		{Name: "big.bin", Content: content, Digest: "-"},
+	}})
+	client := &afetch.Client{APIURL: server.URL}
+	assets, err := client.ResolveAssets(context.Background(), "owner", "repo", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(t.TempDir(), "big.bin")
+	lastRequest := func() githubtest.Request {
+		requests := server.Requests()
+		return requests[len(requests)-1]
+	}
+
+	// The ETag of the interrupted download guards the resumed one
+	ctx, cancel := context.WithCancel(context.Background())
+	err = client.Download(ctx, assets[0], dest, &afetch.DownloadOptions{Progress: func(int64, int64) { cancel() }})
+	var incomplete *afetch.IncompleteError
+	if !errors.As(err, &incomplete) {
+		t.Fatalf("err = %v, want an IncompleteError", err)
+	}
+	first := lastRequest()
+	if err := client.Download(context.Background(), assets[0], dest, &afetch.DownloadOptions{Resume: true}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(dest); string(got) != string(content) {
+		t.Errorf("resumed file has %d bytes, want %d", len(got), len(content))
+	}
+	resumed := lastRequest()
+	if resumed.Header.Get("Range") != fmt.Sprintf("bytes=%d-", incomplete.Downloaded) || resumed.Header.Get("If-Range") == "" {
+		t.Errorf("resumed with Range %q, If-Range %q", resumed.Header.Get("Range"), resumed.Header.Get("If-Range"))
+	}
+	if first.Header.Get("If-Range") != "" {
+		t.Errorf("first request sent If-Range %q", first.Header.Get("If-Range"))
+	}
+
+	// Without a saved ETag nothing proves the partial file is of this asset
+	if err := os.WriteFile(afetch.PartialPath(dest), []byte("stale"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Download(context.Background(), assets[0], dest, &afetch.DownloadOptions{Resume: true}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(dest); string(got) != string(content) {
+		t.Errorf("file has %d bytes after an unsafe resume, want %d", len(got), len(content))
+	}
+	if rangeHeader := lastRequest().Header.Get("Range"); rangeHeader != "" {
+		t.Errorf("partial file of unknown origin resumed with Range %q", rangeHeader)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(dest)); len(entries) != 1 {
+		t.Errorf("files left next to the download: %v", entries)
+	}
+}
@@ -0,0 +1,283 @@
+package afetch
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DownloadOptions configures Download; the zero value downloads a release
+// asset from the start without progress reports
+type DownloadOptions struct {
+	// Progress is called with the bytes written so far and the expected
+	// total (0 when unknown), at most every ProgressInterval and at the end
+	Progress         func(downloaded, total int64)
+	ProgressInterval time.Duration
+
+	// Resume continues the partial file left by an interrupted download.
+	// The rest is requested with the ETag of the first response in
+	// If-Range, so a changed asset is downloaded again from the start;
+	// without a saved ETag, only assets with a digest are continued.
+	Resume bool
+	// Partial is the file written before verification, PartialPath(dest)
+	// when empty
+	Partial string
+
+	// Accept is the Accept header of the request, application/octet-stream
+	// when empty
+	Accept string
+	// Fetch replaces the request to the asset's URL, e.g. for other
+	// servers; offset is the number of bytes already downloaded and
+	// ifRange, if not empty, the validator to send in If-Range
+	Fetch func(ctx context.Context, offset int64, ifRange string) (*http.Response, error)
+
+	// Verify checks the partial file once its digest matched; an error
+	// discards the file and is returned by Download
+	Verify func(partial string) error
+}
+
+// PartialPath returns the file a download to dest is written to before it
+// is verified and renamed
+func PartialPath(dest string) string {
+	dir, name := filepath.Split(dest)
+	return filepath.Join(dir, "."+name+".part")
+}
+
+// etagPath returns the file the ETag of a partial download is saved in
+func etagPath(partial string) string {
+	return partial + ".etag"
+}
+
+// RemovePartial removes the partial file of an interrupted download along
+// with what was kept for resuming it
+func RemovePartial(partial string) error {
+	_ = os.Remove(etagPath(partial))
+	return os.Remove(partial)
+}
+
+// OpenAsset starts the download of assetURL from the GitHub API. accept is
+// the Accept header, application/octet-stream when empty; a non-zero
+// offset requests the rest of the file from that byte on, if the asset
+// still has the ETag ifRange when that is set.
+func (c *Client) OpenAsset(ctx context.Context, assetURL, accept string, offset int64, ifRange string) (*http.Response, error) {
+	req, err := NewRequest(ctx, assetURL, c.Token)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %v", err)
+	}
+	if accept == "" {
+		accept = "application/octet-stream"
+	}
+	req.Header.Set("Accept", accept)
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		if ifRange != "" {
+			req.Header.Set("If-Range", ifRange)
+		}
+	}
+	return c.httpClient().Do(req)
+}
+
+// Download downloads asset to dest. The file is written to a partial file
+// next to dest and renamed once its digest (and opts.Verify) accepted it.
+// When the transfer stops early an *IncompleteError is returned and the
+// partial file is kept for opts.Resume; a digest mismatch returns a
+// *ChecksumError and an unexpected status an *HTTPError.
+func (c *Client) Download(ctx context.Context, asset Asset, dest string, opts *DownloadOptions) error {
+	if opts == nil {
+		opts = &DownloadOptions{}
+	}
+	partial := opts.Partial
+	if partial == "" {
+		partial = PartialPath(dest)
+	}
+
+	// Appending to a partial file of another version of the asset could
+	// only be detected by its digest
+	var offset int64
+	var etag string
+	if opts.Resume {
+		if saved, err := os.ReadFile(etagPath(partial)); err == nil {
+			etag = string(saved)
+		}
+		if info, err := os.Stat(partial); err == nil && (etag != "" || asset.Digest != "") {
+			offset = info.Size()
+		}
+	}
+
+	fetch := opts.Fetch
+	if fetch == nil {
+		fetch = func(ctx context.Context, offset int64, ifRange string) (*http.Response, error) {
+			return c.OpenAsset(ctx, asset.URL, opts.Accept, offset, ifRange)
+		}
+	}
+	resp, err := fetch(ctx, offset, etag)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// A server that ignores the Range header sends the whole file again
+	var out *os.File
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0 && contentRangeStart(resp.Header.Get("Content-Range")) == offset:
+		out, err = os.OpenFile(partial, os.O_WRONLY|os.O_APPEND, 0)
+	case resp.StatusCode == http.StatusOK:
+		offset = 0
+		out, err = os.Create(partial)
+		if err == nil {
+			err = saveETag(partial, resp.Header.Get("ETag"))
+		}
+	default:
+		return &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	if err != nil {
+		return err
+	}
+
+	// The response length stands in for sizes the listing did not report
+	total := asset.Size
+	if total <= 0 && resp.ContentLength > 0 {
+		total = offset + resp.ContentLength
+	}
+	reader := &progressReader{
+		reader:     resp.Body,
+		total:      total,
+		downloaded: offset,
+		onProgress: opts.Progress,
+		interval:   opts.ProgressInterval,
+	}
+
+	// The file is closed before verification so it can be renamed on every
+	// platform
+	_, err = io.Copy(out, reader)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return &IncompleteError{Downloaded: reader.downloaded, Err: err}
+	}
+
+	// The partial file is complete, whatever the outcome
+	_ = os.Remove(etagPath(partial))
+	if err := VerifyDigest(partial, asset.Digest); err != nil {
+		_ = os.Remove(partial)
+		return err
+	}
+	if opts.Verify != nil {
+		if err := opts.Verify(partial); err != nil {
+			_ = os.Remove(partial)
+			return err
+		}
+	}
+	return os.Rename(partial, dest)
+}
+
+// saveETag records the ETag of a new partial download for resuming it;
+// weak ETags cannot be used in If-Range
+func saveETag(partial, etag string) error {
+	if etag == "" || strings.HasPrefix(etag, "W/") {
+		err := os.Remove(etagPath(partial))
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return os.WriteFile(etagPath(partial), []byte(etag), 0644)
+}
+
+// contentRangeStart returns the first byte of a "bytes <start>-<end>/<size>"
+// Content-Range header, or -1
+func contentRangeStart(header string) int64 {
+	spec, ok := strings.CutPrefix(header, "bytes ")
+	if !ok {
+		return -1
+	}
+	start, _, ok := strings.Cut(spec, "-")
+	if !ok {
+		return -1
+	}
+	n, err := strconv.ParseInt(start, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// VerifyDigest checks filename against a "sha256:<hex>" or "sha512:<hex>"
+// digest as reported by GitHub and OCI registries; "" is not checked
+func VerifyDigest(filename, digest string) error {
+	if digest == "" {
+		return nil
+	}
+
+	algorithm, expected, ok := strings.Cut(digest, ":")
+	var h hash.Hash
+	switch {
+	case ok && algorithm == "sha256":
+		h = sha256.New()
+	case ok && algorithm == "sha512":
+		h = sha512.New()
+	default:
+		return fmt.Errorf("invalid digest format: %s", digest)
+	}
+
+	actual, err := fileHash(filename, h)
+	if err != nil {
+		return fmt.Errorf("error calculating checksum: %v", err)
+	}
+	if actual != expected {
+		return &ChecksumError{Expected: expected, Actual: actual}
+	}
+	return nil
+}
+
+// fileHash returns the hex-encoded hash of a file
+func fileHash(filename string, h hash.Hash) (string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// progressReader reports the bytes read through it
+type progressReader struct {
+	reader     io.Reader
+	total      int64
+	downloaded int64
+	onProgress func(downloaded, total int64)
+
+	// Minimum time between onProgress calls; the end of the stream is
+	// always reported
+	interval time.Duration
+	reported time.Time
+}
+
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.reader.Read(p)
+	pr.downloaded += int64(n)
+
+	if pr.onProgress != nil {
+		now := time.Now()
+		if err != nil || now.Sub(pr.reported) >= pr.interval {
+			pr.reported = now
+			pr.onProgress(pr.downloaded, pr.total)
+		}
+	}
+
+	return n, err
+}
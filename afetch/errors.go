@@ -0,0 +1,68 @@
+package afetch
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrNoAssets is wrapped by ResolveAssets when no asset matches the mask
+var ErrNoAssets = errors.New("no assets")
+
+// APIError is returned when the GitHub API answers with an unexpected status
+type APIError struct {
+	StatusCode int
+	URL        string
+	// RateLimited is set when the request was refused by the rate limit
+	RateLimited bool
+}
+
+func (e *APIError) Error() string {
+	if e.RateLimited {
+		return fmt.Sprintf("GitHub API error: %d (rate limit exceeded)", e.StatusCode)
+	}
+	return fmt.Sprintf("GitHub API error: %d", e.StatusCode)
+}
+
+func newAPIError(resp *http.Response) *APIError {
+	rateLimited := resp.StatusCode == http.StatusTooManyRequests ||
+		(resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0")
+	return &APIError{StatusCode: resp.StatusCode, URL: resp.Request.URL.String(), RateLimited: rateLimited}
+}
+
+// HTTPError is returned when a download answers with an unexpected status
+type HTTPError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *HTTPError) Error() string {
+	return "HTTP error: " + e.Status
+}
+
+// ChecksumError is returned when a downloaded file does not have the
+// asset's digest
+type ChecksumError struct {
+	Expected string
+	Actual   string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("checksum verification failed: expected %s, got %s", e.Expected, e.Actual)
+}
+
+// IncompleteError is returned when a download stops before the end, e.g.
+// because its context was cancelled. The partial file is kept and can be
+// resumed.
+type IncompleteError struct {
+	Downloaded int64
+	Err        error
+}
+
+func (e *IncompleteError) Error() string {
+	return fmt.Sprintf("download stopped after %d bytes: %v", e.Downloaded, e.Err)
+}
+
+func (e *IncompleteError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,687 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	_ "crypto/sha512" // SHA-384 for P-384 certificates
+	"crypto/x509"
+	_ "embed"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Native-Robotics/asset-fetch/afetch"
+)
+
+// Sigstore public-good trust root (Fulcio CAs and Rekor keys) used to verify
+// attestation bundles offline; SIGSTORE_TRUSTED_ROOT replaces it
+//
+//go:embed trustroot/public-good.json
+var bundledTrustedRoot []byte
+
+// OIDC issuer of GitHub Actions workflow identities
+const githubActionsIssuer = "https://token.actions.githubusercontent.com"
+
+// Label of verified provenance in the progress table
+const attestationLabel = "slsa"
+
+// Fulcio certificate extensions describing the workflow that signed
+var (
+	oidFulcioIssuerV1         = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	oidFulcioIssuer           = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+	oidFulcioBuildSignerURI   = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 9}
+	oidFulcioSourceRepository = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 12}
+	oidFulcioSourceRef        = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 14}
+)
+
+// AttestationPolicy describes the provenance a repository's downloads must
+// have. Empty fields are not checked, except Repository, which defaults to
+// the repository the asset was downloaded from.
+type AttestationPolicy struct {
+	Repository string // owner/repo whose workflow must have built the asset
+	Workflow   string // workflow file, e.g. ".github/workflows/release.yml"
+	Ref        string // glob matched against the source ref, e.g. "refs/tags/v*"
+}
+
+// attestationPolicy returns the policy for repo, with repository-specific
+// settings overriding those for all repositories, or nil if none applies
+func (c *Config) attestationPolicy(repo string) *AttestationPolicy {
+	global, hasGlobal := c.AttestationPolicies[""]
+	scoped, hasScoped := c.AttestationPolicies[strings.ToLower(repo)]
+	if !hasGlobal && !hasScoped {
+		return nil
+	}
+	policy := global
+	if scoped.Repository != "" {
+		policy.Repository = scoped.Repository
+	}
+	if scoped.Workflow != "" {
+		policy.Workflow = scoped.Workflow
+	}
+	if scoped.Ref != "" {
+		policy.Ref = scoped.Ref
+	}
+	return &policy
+}
+
+// setAttestationOption applies a configuration key such as
+// "ATTESTATION_WORKFLOW@owner/repo"; keys without "@" apply to all
+// repositories. It reports whether key is an attestation option.
+func (c *Config) setAttestationOption(key, value string) bool {
+	name, repo, _ := strings.Cut(key, "@")
+	policy := c.AttestationPolicies[strings.ToLower(repo)]
+	switch name {
+	case "VERIFY_ATTESTATIONS":
+		if value != "true" && value != "1" && value != "yes" {
+			return true
+		}
+	case "ATTESTATION_REPOSITORY":
+		policy.Repository = value
+	case "ATTESTATION_WORKFLOW":
+		policy.Workflow = value
+	case "ATTESTATION_REF":
+		policy.Ref = value
+	default:
+		return false
+	}
+	if c.AttestationPolicies == nil {
+		c.AttestationPolicies = make(map[string]AttestationPolicy)
+	}
+	c.AttestationPolicies[strings.ToLower(repo)] = policy
+	return true
+}
+
+// verifyAssetAttestations checks that the downloaded asset in filename has a
+// build provenance attestation satisfying the policy configured for its
+// repository. The status is empty when no policy applies.
+func verifyAssetAttestations(ctx context.Context, filename string, asset AssetInfo, config *Config) signatureResult {
+	if !attestable(asset) {
+		return signatureResult{}
+	}
+	policy := config.attestationPolicy(asset.Repository)
+	if policy == nil {
+		return signatureResult{}
+	}
+	failed := func(err error) signatureResult {
+		return signatureResult{status: "✗ " + attestationLabel, err: err}
+	}
+
+	root, err := loadTrustedRoot(config.SigstoreTrustedRoot)
+	if err != nil {
+		return failed(fmt.Errorf("SIGSTORE_TRUSTED_ROOT: %v", err))
+	}
+	sum, err := calculateSHA256(filename)
+	if err != nil {
+		return failed(err)
+	}
+	bundles, err := fetchAttestations(ctx, asset.Repository, "sha256:"+sum, config.GitHubToken)
+	if err != nil {
+		return failed(err)
+	}
+	if len(bundles) == 0 {
+		return failed(fmt.Errorf("no attestations found for %s", asset.Name))
+	}
+
+	expectedRepo := policy.Repository
+	if expectedRepo == "" {
+		expectedRepo = asset.Repository
+	}
+	// Any attestation that passes is enough; otherwise report the first reason
+	var firstErr error
+	for _, bundle := range bundles {
+		identity, err := root.verifyBundle(bundle, sum)
+		if err == nil {
+			err = identity.check(*policy, expectedRepo)
+		}
+		if err == nil {
+			return signatureResult{status: "✓ " + attestationLabel}
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return failed(firstErr)
+}
+
+// attestable reports whether asset can have build provenance: source
+// archives are generated by GitHub on request and workflow artifacts are not
+// attested by digest, so only release assets are checked
+func attestable(asset AssetInfo) bool {
+	return asset.Repository != "" && !asset.Artifact && !asset.SourceArchive
+}
+
+// combine merges the attestation result into the signature result
+func (r signatureResult) combine(provenance signatureResult) signatureResult {
+	switch {
+	case provenance.status == "":
+		return r
+	case provenance.err != nil:
+		return provenance
+	case strings.HasPrefix(r.status, "✓ "):
+		return signatureResult{status: r.status + "+" + attestationLabel}
+	default:
+		return provenance
+	}
+}
+
+// fetchAttestations lists the Sigstore bundles attested for digest in repo
+func fetchAttestations(ctx context.Context, repo, digest, token string) ([]json.RawMessage, error) {
+	apiURL := fmt.Sprintf("%s/repos/%s/attestations/%s", githubAPIURL, repo, digest)
+	req, err := afetch.NewRequest(ctx, apiURL, token)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitHub API error: %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Attestations []struct {
+			Bundle json.RawMessage `json:"bundle"`
+		} `json:"attestations"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	var bundles []json.RawMessage
+	for _, attestation := range result.Attestations {
+		if len(attestation.Bundle) > 0 && string(attestation.Bundle) != "null" {
+			bundles = append(bundles, attestation.Bundle)
+		}
+	}
+	return bundles, nil
+}
+
+// validity is the period a trust root key or CA was in use
+type validity struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// covers reports whether t falls within the validity period
+func (v validity) covers(t time.Time) bool {
+	return !t.Before(v.Start) && (v.End.IsZero() || !t.After(v.End))
+}
+
+// trustedRoot is the subset of a Sigstore trusted_root.json used to verify bundles
+type trustedRoot struct {
+	Tlogs []struct {
+		PublicKey struct {
+			RawBytes string   `json:"rawBytes"`
+			ValidFor validity `json:"validFor"`
+		} `json:"publicKey"`
+		LogID struct {
+			KeyID string `json:"keyId"`
+		} `json:"logId"`
+	} `json:"tlogs"`
+	CertificateAuthorities []struct {
+		CertChain struct {
+			Certificates []struct {
+				RawBytes string `json:"rawBytes"`
+			} `json:"certificates"`
+		} `json:"certChain"`
+		ValidFor validity `json:"validFor"`
+	} `json:"certificateAuthorities"`
+}
+
+// loadTrustedRoot reads the trusted root at rootPath, or the bundled one
+func loadTrustedRoot(rootPath string) (*trustedRoot, error) {
+	content := bundledTrustedRoot
+	if rootPath != "" {
+		var err error
+		if content, err = os.ReadFile(rootPath); err != nil {
+			return nil, err
+		}
+	}
+	root := &trustedRoot{}
+	if err := json.Unmarshal(content, root); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// verificationMaterial is the signing certificate and transparency log
+// entries of a Sigstore bundle (v0.1 to v0.3)
+type verificationMaterial struct {
+	Certificate *struct {
+		RawBytes string `json:"rawBytes"`
+	} `json:"certificate"`
+	X509CertificateChain *struct {
+		Certificates []struct {
+			RawBytes string `json:"rawBytes"`
+		} `json:"certificates"`
+	} `json:"x509CertificateChain"`
+	TlogEntries               []tlogEntry `json:"tlogEntries"`
+	TimestampVerificationData *struct {
+		RFC3161Timestamps []json.RawMessage `json:"rfc3161Timestamps"`
+	} `json:"timestampVerificationData"`
+}
+
+// errTimestampOnly reports a bundle without a transparency log entry, as
+// made by GitHub's Sigstore instance for private repositories
+var errTimestampOnly = errors.New("attestation is not in a transparency log; attestations of private repositories, signed by GitHub's own Sigstore instance, cannot be verified")
+
+// tlogEntry is a Rekor transparency log entry recorded in a bundle
+type tlogEntry struct {
+	LogIndex string `json:"logIndex"`
+	LogID    struct {
+		KeyID string `json:"keyId"`
+	} `json:"logId"`
+	IntegratedTime    string            `json:"integratedTime"`
+	InclusionPromise  *inclusionPromise `json:"inclusionPromise"`
+	CanonicalizedBody string            `json:"canonicalizedBody"`
+}
+
+// inclusionPromise holds the log's signed entry timestamp (SET)
+type inclusionPromise struct {
+	SignedEntryTimestamp string `json:"signedEntryTimestamp"`
+}
+
+// sigstoreBundle is the subset of a Sigstore bundle holding a DSSE-signed
+// in-toto statement
+type sigstoreBundle struct {
+	VerificationMaterial verificationMaterial `json:"verificationMaterial"`
+	DSSEEnvelope         *struct {
+		Payload     string `json:"payload"`
+		PayloadType string `json:"payloadType"`
+		Signatures  []struct {
+			Sig string `json:"sig"`
+		} `json:"signatures"`
+	} `json:"dsseEnvelope"`
+}
+
+// inTotoStatement is the subset of an in-toto statement checked against the download
+type inTotoStatement struct {
+	Subject []struct {
+		Name   string            `json:"name"`
+		Digest map[string]string `json:"digest"`
+	} `json:"subject"`
+	PredicateType string `json:"predicateType"`
+}
+
+// signerIdentity is the workflow identity recorded in a Fulcio certificate
+type signerIdentity struct {
+	issuer     string
+	signerURI  string // e.g. https://github.com/o/r/.github/workflows/release.yml@refs/tags/v1
+	sourceRepo string // e.g. https://github.com/o/r
+	sourceRef  string // e.g. refs/tags/v1
+}
+
+// verifyBundle verifies an attestation bundle offline: the certificate chains
+// to a Fulcio CA at the time the Rekor log integrated the entry, the log's
+// signed entry timestamp is valid, the envelope is signed by the certificate,
+// and the statement is SLSA provenance for a subject with sha256 digest sum
+func (root *trustedRoot) verifyBundle(raw json.RawMessage, sum string) (*signerIdentity, error) {
+	var bundle sigstoreBundle
+	if err := json.Unmarshal(raw, &bundle); err != nil {
+		return nil, fmt.Errorf("malformed attestation bundle: %v", err)
+	}
+	envelope := bundle.DSSEEnvelope
+	if envelope == nil || len(envelope.Signatures) == 0 {
+		return nil, errors.New("attestation bundle has no signed envelope")
+	}
+	if envelope.PayloadType != "application/vnd.in-toto+json" {
+		return nil, fmt.Errorf("unsupported attestation payload type %s", envelope.PayloadType)
+	}
+	payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		return nil, errors.New("malformed attestation payload")
+	}
+	signature, err := base64.StdEncoding.DecodeString(envelope.Signatures[0].Sig)
+	if err != nil {
+		return nil, errors.New("malformed attestation signature")
+	}
+
+	material := bundle.VerificationMaterial
+	cert, err := material.certificate()
+	if err != nil {
+		return nil, err
+	}
+	if cert == nil {
+		return nil, errors.New("attestation bundle has no signing certificate")
+	}
+
+	// The transparency log entry proves when the short-lived certificate was used
+	if tsa := material.TimestampVerificationData; len(material.TlogEntries) == 0 && tsa != nil && len(tsa.RFC3161Timestamps) > 0 {
+		return nil, errTimestampOnly
+	}
+	signedAt, err := root.verifyLogEntry(material.TlogEntries)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkLogEntryBody(material.TlogEntries[0].CanonicalizedBody, payload, cert); err != nil {
+		return nil, err
+	}
+
+	if err := root.verifyCertificate(cert, signedAt); err != nil {
+		return nil, err
+	}
+	if err := verifyWithCertificateKey(cert, dssePAE(envelope.PayloadType, payload), signature); err != nil {
+		return nil, errors.New("attestation signature does not match its certificate")
+	}
+
+	var statement inTotoStatement
+	if err := json.Unmarshal(payload, &statement); err != nil {
+		return nil, fmt.Errorf("malformed in-toto statement: %v", err)
+	}
+	if !strings.HasPrefix(statement.PredicateType, "https://slsa.dev/provenance/") {
+		return nil, fmt.Errorf("attestation is %s, not SLSA provenance", statement.PredicateType)
+	}
+	matched := false
+	for _, subject := range statement.Subject {
+		if digest := subject.Digest["sha256"]; digest != "" && strings.EqualFold(digest, sum) {
+			matched = true
+		}
+	}
+	if !matched {
+		return nil, errors.New("attestation subject does not match the downloaded file")
+	}
+	return certificateIdentity(cert)
+}
+
+// certificate returns the signing certificate, or nil if the bundle was
+// signed with a key
+func (m verificationMaterial) certificate() (*x509.Certificate, error) {
+	var certDER string
+	switch {
+	case m.Certificate != nil:
+		certDER = m.Certificate.RawBytes
+	case m.X509CertificateChain != nil && len(m.X509CertificateChain.Certificates) > 0:
+		certDER = m.X509CertificateChain.Certificates[0].RawBytes
+	default:
+		return nil, nil
+	}
+	der, err := base64.StdEncoding.DecodeString(certDER)
+	if err != nil {
+		return nil, errors.New("malformed signing certificate")
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("malformed signing certificate: %v", err)
+	}
+	return cert, nil
+}
+
+// verifyLogEntry checks the signed entry timestamp of the first of entries
+// and returns the time the log integrated it
+func (root *trustedRoot) verifyLogEntry(entries []tlogEntry) (time.Time, error) {
+	if len(entries) == 0 {
+		return time.Time{}, errors.New("bundle has no transparency log entry")
+	}
+	entry := entries[0]
+	if entry.InclusionPromise == nil {
+		return time.Time{}, errors.New("transparency log entry has no signed entry timestamp")
+	}
+	integratedTime, err := strconv.ParseInt(entry.IntegratedTime, 10, 64)
+	if err != nil {
+		return time.Time{}, errors.New("malformed transparency log entry")
+	}
+	logIndex, err := strconv.ParseInt(entry.LogIndex, 10, 64)
+	if err != nil {
+		return time.Time{}, errors.New("malformed transparency log entry")
+	}
+	signedAt := time.Unix(integratedTime, 0)
+	if err := root.verifySignedEntryTimestamp(entry.LogID.KeyID, entry.CanonicalizedBody, integratedTime, logIndex, entry.InclusionPromise.SignedEntryTimestamp, signedAt); err != nil {
+		return time.Time{}, err
+	}
+	return signedAt, nil
+}
+
+// verifySignedEntryTimestamp checks the Rekor signature over a log entry
+func (root *trustedRoot) verifySignedEntryTimestamp(keyID, body string, integratedTime, logIndex int64, set string, signedAt time.Time) error {
+	logID, err := base64.StdEncoding.DecodeString(keyID)
+	if err != nil {
+		return errors.New("malformed transparency log ID")
+	}
+	sig, err := base64.StdEncoding.DecodeString(set)
+	if err != nil {
+		return errors.New("malformed signed entry timestamp")
+	}
+	// Rekor signs the canonical JSON of these fields, keys in sorted order
+	payload, err := json.Marshal(struct {
+		Body           string `json:"body"`
+		IntegratedTime int64  `json:"integratedTime"`
+		LogID          string `json:"logID"`
+		LogIndex       int64  `json:"logIndex"`
+	}{body, integratedTime, hex.EncodeToString(logID), logIndex})
+	if err != nil {
+		return err
+	}
+
+	for _, tlog := range root.Tlogs {
+		if tlog.LogID.KeyID != keyID || !tlog.PublicKey.ValidFor.covers(signedAt) {
+			continue
+		}
+		der, err := base64.StdEncoding.DecodeString(tlog.PublicKey.RawBytes)
+		if err != nil {
+			return err
+		}
+		key, err := x509.ParsePKIXPublicKey(der)
+		if err != nil {
+			return err
+		}
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		digest := sha256.Sum256(payload)
+		if !ok || !ecdsa.VerifyASN1(ecKey, digest[:], sig) {
+			return errors.New("invalid transparency log signed entry timestamp")
+		}
+		return nil
+	}
+	return errors.New("transparency log is not in the trusted root")
+}
+
+// checkLogEntryBody checks that the logged dsse or intoto entry is for
+// payload, signed with cert
+func checkLogEntryBody(encoded string, payload []byte, cert *x509.Certificate) error {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return errors.New("malformed transparency log entry")
+	}
+	type hashValue struct {
+		Algorithm string `json:"algorithm"`
+		Value     string `json:"value"`
+	}
+	var body struct {
+		Kind string `json:"kind"`
+		Spec struct {
+			PayloadHash *hashValue `json:"payloadHash"`
+			Signatures  []struct {
+				Verifier string `json:"verifier"`
+			} `json:"signatures"`
+			Content struct {
+				PayloadHash *hashValue `json:"payloadHash"`
+				Envelope    struct {
+					Signatures []struct {
+						PublicKey string `json:"publicKey"`
+					} `json:"signatures"`
+				} `json:"envelope"`
+			} `json:"content"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return errors.New("malformed transparency log entry")
+	}
+	hash := body.Spec.PayloadHash
+	var verifiers []string
+	for _, sig := range body.Spec.Signatures {
+		verifiers = append(verifiers, sig.Verifier)
+	}
+	if body.Kind == "intoto" {
+		hash = body.Spec.Content.PayloadHash
+		verifiers = nil
+		for _, sig := range body.Spec.Content.Envelope.Signatures {
+			verifiers = append(verifiers, sig.PublicKey)
+		}
+	}
+	if hash == nil || hash.Algorithm != "sha256" {
+		return fmt.Errorf("unsupported transparency log entry kind %q", body.Kind)
+	}
+	sum := sha256.Sum256(payload)
+	if !strings.EqualFold(hash.Value, hex.EncodeToString(sum[:])) {
+		return errors.New("transparency log entry is for a different attestation")
+	}
+
+	// The log must have recorded the certificate of the bundle, or the entry
+	// could be borrowed from another signer's attestation of the same payload
+	for _, verifier := range verifiers {
+		certPEM, err := base64.StdEncoding.DecodeString(verifier)
+		if err != nil {
+			continue
+		}
+		if block, _ := pem.Decode(certPEM); block != nil && bytes.Equal(block.Bytes, cert.Raw) {
+			return nil
+		}
+	}
+	return errors.New("transparency log entry is for a different certificate")
+}
+
+// verifyCertificate checks that cert chains to a Fulcio CA valid at signedAt
+func (root *trustedRoot) verifyCertificate(cert *x509.Certificate, signedAt time.Time) error {
+	for _, ca := range root.CertificateAuthorities {
+		certs := ca.CertChain.Certificates
+		if len(certs) == 0 || !ca.ValidFor.covers(signedAt) {
+			continue
+		}
+		roots, intermediates := x509.NewCertPool(), x509.NewCertPool()
+		for i, c := range certs {
+			der, err := base64.StdEncoding.DecodeString(c.RawBytes)
+			if err != nil {
+				return err
+			}
+			parsed, err := x509.ParseCertificate(der)
+			if err != nil {
+				return err
+			}
+			if i == len(certs)-1 {
+				roots.AddCert(parsed)
+			} else {
+				intermediates.AddCert(parsed)
+			}
+		}
+		_, err := cert.Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			CurrentTime:   signedAt,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		})
+		if err == nil {
+			return nil
+		}
+	}
+	return errors.New("signing certificate is not issued by a trusted Fulcio CA")
+}
+
+// dssePAE returns the DSSE pre-authentication encoding that is signed
+func dssePAE(payloadType string, payload []byte) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "DSSEv1 %d %s %d ", len(payloadType), payloadType, len(payload))
+	b.Write(payload)
+	return b.Bytes()
+}
+
+// verifyWithCertificateKey checks an ECDSA signature over message
+func verifyWithCertificateKey(cert *x509.Certificate, message, sig []byte) error {
+	key, ok := cert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("unsupported certificate key type %T", cert.PublicKey)
+	}
+	hash := crypto.SHA256
+	if key.Curve == elliptic.P384() {
+		hash = crypto.SHA384
+	}
+	h := hash.New()
+	h.Write(message)
+	if !ecdsa.VerifyASN1(key, h.Sum(nil), sig) {
+		return errors.New("signature does not match")
+	}
+	return nil
+}
+
+// certificateIdentity reads the workflow identity from Fulcio extensions
+func certificateIdentity(cert *x509.Certificate) (*signerIdentity, error) {
+	identity := &signerIdentity{}
+	for _, ext := range cert.Extensions {
+		var value string
+		switch {
+		case ext.Id.Equal(oidFulcioIssuerV1):
+			if identity.issuer == "" {
+				identity.issuer = string(ext.Value)
+			}
+			continue
+		case ext.Id.Equal(oidFulcioIssuer), ext.Id.Equal(oidFulcioBuildSignerURI),
+			ext.Id.Equal(oidFulcioSourceRepository), ext.Id.Equal(oidFulcioSourceRef):
+			if _, err := asn1.UnmarshalWithParams(ext.Value, &value, "utf8"); err != nil {
+				return nil, fmt.Errorf("malformed certificate extension %s", ext.Id)
+			}
+		default:
+			continue
+		}
+		switch {
+		case ext.Id.Equal(oidFulcioIssuer):
+			identity.issuer = value
+		case ext.Id.Equal(oidFulcioBuildSignerURI):
+			identity.signerURI = value
+		case ext.Id.Equal(oidFulcioSourceRepository):
+			identity.sourceRepo = value
+		case ext.Id.Equal(oidFulcioSourceRef):
+			identity.sourceRef = value
+		}
+	}
+	return identity, nil
+}
+
+// check compares the signer identity with policy
+func (id *signerIdentity) check(policy AttestationPolicy, expectedRepo string) error {
+	if id.issuer != githubActionsIssuer {
+		return fmt.Errorf("attestation was not signed by GitHub Actions (issuer %q)", id.issuer)
+	}
+	if !strings.EqualFold(id.sourceRepo, "https://github.com/"+expectedRepo) {
+		return fmt.Errorf("attestation was built from %s, expected %s", strings.TrimPrefix(id.sourceRepo, "https://github.com/"), expectedRepo)
+	}
+	if policy.Workflow != "" {
+		// The signer URI is https://github.com/<owner>/<repo>/<workflow path>@<ref>;
+		// the workflow belongs to the expected repository unless the policy
+		// names a reusable workflow of another one as owner/repo/<path>
+		signer, _, _ := strings.Cut(strings.TrimPrefix(id.signerURI, "https://github.com/"), "@")
+		parts := strings.SplitN(signer, "/", 3)
+		signerRepo, workflow := expectedRepo, strings.TrimPrefix(policy.Workflow, "/")
+		if !strings.Contains(workflow, "/") {
+			workflow = ".github/workflows/" + workflow
+		} else if p := strings.SplitN(workflow, "/", 3); !strings.HasPrefix(workflow, ".github/") && len(p) == 3 {
+			signerRepo, workflow = p[0]+"/"+p[1], p[2]
+		}
+		if len(parts) != 3 || !strings.EqualFold(parts[0]+"/"+parts[1], signerRepo) || parts[2] != workflow {
+			return fmt.Errorf("attestation was signed by workflow %s, expected %s/%s", id.signerURI, signerRepo, workflow)
+		}
+	}
+	if policy.Ref != "" {
+		if matched, err := path.Match(policy.Ref, id.sourceRef); err != nil || !matched {
+			return fmt.Errorf("attestation was built from ref %s, expected %s", id.sourceRef, policy.Ref)
+		}
+	}
+	return nil
+}
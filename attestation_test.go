@@ -0,0 +1,285 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Provenance of the sigstore 2.0.0 npm package, built by GitHub Actions and
+// recorded in the public-good Rekor log
+const recordedBundle = "testdata/sigstore-js-2.0.0-provenance.sigstore.json"
+
+// workflowCert issues a certificate to a GitHub Actions workflow of repo
+func (s *testSigstore) workflowCert(t *testing.T, repo, workflow, ref string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	signer := "https://github.com/" + repo + "/" + workflow + "@" + ref
+	return s.issue(t, signer,
+		fulcioExtension(t, oidFulcioIssuer, githubActionsIssuer),
+		fulcioExtension(t, oidFulcioBuildSignerURI, signer),
+		fulcioExtension(t, oidFulcioSourceRepository, "https://github.com/"+repo),
+		fulcioExtension(t, oidFulcioSourceRef, ref))
+}
+
+// attestationBundle returns a Sigstore bundle of SLSA provenance for a file
+// with sha256 digest sum, signed with cert and logged at integratedAt
+func (s *testSigstore) attestationBundle(t *testing.T, cert *x509.Certificate, key *ecdsa.PrivateKey, sum string, integratedAt time.Time) []byte {
+	t.Helper()
+	payload, err := json.Marshal(map[string]any{
+		"_type":         "https://in-toto.io/Statement/v1",
+		"subject":       []any{map[string]any{"name": "app.bin", "digest": map[string]string{"sha256": sum}}},
+		"predicateType": "https://slsa.dev/provenance/v1",
+		"predicate":     map[string]any{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const payloadType = "application/vnd.in-toto+json"
+	digest := sha256.Sum256(dssePAE(payloadType, payload))
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	payloadHash := sha256.Sum256(payload)
+	entry := s.logEntry(t, map[string]any{
+		"apiVersion": "0.0.1",
+		"kind":       "dsse",
+		"spec": map[string]any{
+			"payloadHash": map[string]string{"algorithm": "sha256", "value": hex.EncodeToString(payloadHash[:])},
+			"signatures": []any{map[string]any{
+				"signature": sig,
+				"verifier":  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}),
+			}},
+		},
+	}, integratedAt)
+	bundle, err := json.Marshal(map[string]any{
+		"mediaType": "application/vnd.dev.sigstore.bundle.v0.3+json",
+		"verificationMaterial": map[string]any{
+			"certificate": map[string]any{"rawBytes": cert.Raw},
+			"tlogEntries": []tlogEntry{entry},
+		},
+		"dsseEnvelope": map[string]any{
+			"payload":     payload,
+			"payloadType": payloadType,
+			"signatures":  []any{map[string]any{"sig": sig}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bundle
+}
+
+// editBundle decodes bundle, applies edit and encodes it again
+func editBundle(t *testing.T, bundle []byte, edit func(map[string]any)) []byte {
+	t.Helper()
+	var decoded map[string]any
+	if err := json.Unmarshal(bundle, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	edit(decoded)
+	edited, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return edited
+}
+
+func TestVerifyRecordedAttestation(t *testing.T) {
+	raw, err := os.ReadFile(recordedBundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := loadTrustedRoot("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The subject is an npm package with a sha512 digest only, so everything
+	// up to the subject check passes against the bundled trust root
+	if _, err := root.verifyBundle(raw, strings.Repeat("0", 64)); err == nil || !strings.Contains(err.Error(), "subject does not match") {
+		t.Fatalf("recorded bundle: got %v, want a subject mismatch", err)
+	}
+
+	tampered := editBundle(t, raw, func(bundle map[string]any) {
+		envelope := bundle["dsseEnvelope"].(map[string]any)
+		payload, _ := base64.StdEncoding.DecodeString(envelope["payload"].(string))
+		payload = []byte(strings.Replace(string(payload), "sigstore@2.0.0", "sigstore@2.0.1", 1))
+		envelope["payload"] = base64.StdEncoding.EncodeToString(payload)
+	})
+	if _, err := root.verifyBundle(tampered, strings.Repeat("0", 64)); err == nil || !strings.Contains(err.Error(), "different attestation") {
+		t.Errorf("tampered payload: got %v", err)
+	}
+	if _, err := newTestSigstore(t).root(t).verifyBundle(raw, strings.Repeat("0", 64)); err == nil || !strings.Contains(err.Error(), "not in the trusted root") {
+		t.Errorf("other trust root: got %v", err)
+	}
+
+	var bundle sigstoreBundle
+	if err := json.Unmarshal(raw, &bundle); err != nil {
+		t.Fatal(err)
+	}
+	cert, err := bundle.VerificationMaterial.certificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity, err := certificateIdentity(cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := signerIdentity{
+		issuer:     githubActionsIssuer,
+		signerURI:  "https://github.com/sigstore/sigstore-js/.github/workflows/release.yml@refs/heads/main",
+		sourceRepo: "https://github.com/sigstore/sigstore-js",
+		sourceRef:  "refs/heads/main",
+	}
+	if *identity != want {
+		t.Fatalf("identity = %+v, want %+v", *identity, want)
+	}
+
+	for _, test := range []struct {
+		policy AttestationPolicy
+		repo   string
+		want   string
+	}{
+		{AttestationPolicy{}, "sigstore/sigstore-js", ""},
+		{AttestationPolicy{Workflow: "release.yml", Ref: "refs/heads/main"}, "Sigstore/Sigstore-JS", ""},
+		{AttestationPolicy{Workflow: ".github/workflows/release.yml", Ref: "refs/heads/*"}, "sigstore/sigstore-js", ""},
+		{AttestationPolicy{}, "owner/repo", "built from sigstore/sigstore-js"},
+		{AttestationPolicy{Workflow: "ci.yml"}, "sigstore/sigstore-js", "signed by workflow"},
+		{AttestationPolicy{Ref: "refs/tags/*"}, "sigstore/sigstore-js", "built from ref"},
+	} {
+		err := identity.check(test.policy, test.repo)
+		if (test.want == "" && err != nil) || (test.want != "" && (err == nil || !strings.Contains(err.Error(), test.want))) {
+			t.Errorf("check(%+v, %s) = %v, want %q", test.policy, test.repo, err, test.want)
+		}
+	}
+}
+
+// root loads the trusted root of the test Sigstore
+func (s *testSigstore) root(t *testing.T) *trustedRoot {
+	t.Helper()
+	root, err := loadTrustedRoot(s.rootPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestVerifyAttestationBundle(t *testing.T) {
+	sigstore := newTestSigstore(t)
+	root := sigstore.root(t)
+	sum := strings.Repeat("ab", 32)
+	cert, key := sigstore.workflowCert(t, "owner/repo", ".github/workflows/release.yml", "refs/tags/v1")
+
+	identity, err := root.verifyBundle(sigstore.attestationBundle(t, cert, key, sum, time.Now()), sum)
+	if err != nil {
+		t.Fatalf("good bundle: %v", err)
+	}
+	if err := identity.check(AttestationPolicy{Workflow: "release.yml", Ref: "refs/tags/v*"}, "owner/repo"); err != nil {
+		t.Errorf("policy: %v", err)
+	}
+
+	foreignCert, foreignKey := newTestSigstore(t).workflowCert(t, "owner/repo", ".github/workflows/release.yml", "refs/tags/v1")
+	otherCert, otherKey := sigstore.workflowCert(t, "owner/repo", ".github/workflows/release.yml", "refs/tags/v1")
+	for name, test := range map[string]struct {
+		bundle []byte
+		want   string
+	}{
+		"other file": {
+			bundle: sigstore.attestationBundle(t, cert, key, strings.Repeat("cd", 32), time.Now()),
+			want:   "subject does not match",
+		},
+		"certificate expired when logged": {
+			bundle: sigstore.attestationBundle(t, cert, key, sum, time.Now().Add(30*time.Minute)),
+			want:   "not issued by a trusted Fulcio CA",
+		},
+		"foreign certificate": {
+			bundle: sigstore.attestationBundle(t, foreignCert, foreignKey, sum, time.Now()),
+			want:   "not issued by a trusted Fulcio CA",
+		},
+		"signed by another key": {
+			bundle: editBundle(t, sigstore.attestationBundle(t, cert, key, sum, time.Now()), func(bundle map[string]any) {
+				other := sigstore.attestationBundle(t, foreignCert, foreignKey, sum, time.Now())
+				var decoded map[string]any
+				_ = json.Unmarshal(other, &decoded)
+				bundle["dsseEnvelope"].(map[string]any)["signatures"] = decoded["dsseEnvelope"].(map[string]any)["signatures"]
+			}),
+			want: "does not match its certificate",
+		},
+		"log entry of another certificate": {
+			bundle: editBundle(t, sigstore.attestationBundle(t, cert, key, sum, time.Now()), func(bundle map[string]any) {
+				other := sigstore.attestationBundle(t, otherCert, otherKey, sum, time.Now())
+				var decoded map[string]any
+				_ = json.Unmarshal(other, &decoded)
+				bundle["verificationMaterial"].(map[string]any)["certificate"] = decoded["verificationMaterial"].(map[string]any)["certificate"]
+				bundle["dsseEnvelope"] = decoded["dsseEnvelope"]
+			}),
+			want: "transparency log entry is for a different certificate",
+		},
+	} {
+		if _, err := root.verifyBundle(test.bundle, sum); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: got %v, want %q", name, err, test.want)
+		}
+	}
+
+	// Bundles of GitHub's private Sigstore instance have no log entry
+	private := editBundle(t, sigstore.attestationBundle(t, cert, key, sum, time.Now()), func(bundle map[string]any) {
+		material := bundle["verificationMaterial"].(map[string]any)
+		delete(material, "tlogEntries")
+		material["timestampVerificationData"] = map[string]any{
+			"rfc3161Timestamps": []any{map[string]any{"signedTimestamp": "MIIC"}},
+		}
+	})
+	if _, err := root.verifyBundle(private, sum); !errors.Is(err, errTimestampOnly) {
+		t.Errorf("timestamp-only bundle: got %v, want errTimestampOnly", err)
+	}
+}
+
+func TestSignerIdentityWorkflowRepository(t *testing.T) {
+	// A workflow of another repository at the same path as the expected one
+	identity := signerIdentity{
+		issuer:     githubActionsIssuer,
+		signerURI:  "https://github.com/other/tools/.github/workflows/release.yml@refs/heads/main",
+		sourceRepo: "https://github.com/owner/repo",
+		sourceRef:  "refs/tags/v1",
+	}
+	for _, workflow := range []string{"release.yml", ".github/workflows/release.yml", "owner/repo/.github/workflows/release.yml"} {
+		if err := identity.check(AttestationPolicy{Workflow: workflow}, "owner/repo"); err == nil || !strings.Contains(err.Error(), "signed by workflow") {
+			t.Errorf("ATTESTATION_WORKFLOW=%s: got %v", workflow, err)
+		}
+	}
+
+	// Reusable workflows are allowed by naming their repository
+	if err := identity.check(AttestationPolicy{Workflow: "Other/Tools/.github/workflows/release.yml"}, "owner/repo"); err != nil {
+		t.Errorf("reusable workflow: %v", err)
+	}
+	if err := identity.check(AttestationPolicy{}, "owner/repo"); err != nil {
+		t.Errorf("without a workflow policy: %v", err)
+	}
+}
+
+func TestAttestable(t *testing.T) {
+	for _, test := range []struct {
+		asset AssetInfo
+		want  bool
+	}{
+		{AssetInfo{Name: "app.bin", Repository: "owner/repo"}, true},
+		{AssetInfo{Name: "app.bin"}, false},
+		{AssetInfo{Name: "Source code (zip)", Repository: "owner/repo", SourceArchive: true}, false},
+		{AssetInfo{Name: "build.zip", Repository: "owner/repo", Artifact: true}, false},
+	} {
+		if got := attestable(test.asset); got != test.want {
+			t.Errorf("attestable(%s) = %v, want %v", test.asset.Name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,461 @@
+package main
+
+import (
+	"cmp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// SortColumn identifies the column a list is ordered by
+type SortColumn int
+
+const (
+	SortNone SortColumn = iota // API order, or match score while searching
+	SortName
+	SortSize
+	SortDate
+	SortDownloads
+	SortTag
+	SortStars
+)
+
+// Sort columns offered by 's', in cycling order
+var (
+	assetSortColumns   = []SortColumn{SortNone, SortName, SortSize, SortDate, SortDownloads, SortTag}
+	releaseSortColumns = []SortColumn{SortNone, SortTag, SortDate}
+	repoSortColumns    = []SortColumn{SortNone, SortName, SortStars, SortDate}
+	recentSortColumns  = []SortColumn{SortNone, SortName, SortDate}
+	runSortColumns     = []SortColumn{SortNone, SortName, SortDate}
+)
+
+// Maximum width of the name columns before values are truncated
+const maxNameColumnWidth = 60
+
+// listColumn describes one aligned column of a list
+type listColumn struct {
+	title      string
+	sorts      []SortColumn // sort columns indicated on this header
+	width      int
+	alignRight bool
+	value      func(item interface{}) string
+}
+
+// CycleSort switches to the next sort column for the current list
+func (ulv *UnifiedListView) CycleSort() {
+	columns := releaseSortColumns
+	switch ulv.kind {
+	case listAssets:
+		columns = assetSortColumns
+	case listRepositories:
+		columns = repoSortColumns
+	case listRecent:
+		columns = recentSortColumns
+	case listRuns:
+		columns = runSortColumns
+	}
+	next := columns[0]
+	for i, c := range columns {
+		if c == ulv.sortColumn {
+			next = columns[(i+1)%len(columns)]
+			break
+		}
+	}
+	ulv.SetSort(next, ulv.sortDesc)
+}
+
+// ReverseSort flips the sort direction
+func (ulv *UnifiedListView) ReverseSort() {
+	if ulv.sortColumn == SortNone {
+		return
+	}
+	ulv.SetSort(ulv.sortColumn, !ulv.sortDesc)
+}
+
+// SetSort orders the list by column, keeping the cursor on the same item
+func (ulv *UnifiedListView) SetSort(column SortColumn, desc bool) {
+	current := -1
+	if ulv.cursor < len(ulv.filteredItems) {
+		current = ulv.originalIndex(ulv.cursor)
+	}
+
+	ulv.visualActive = false
+	ulv.visualSnapshot = nil
+	ulv.sortColumn = column
+	ulv.sortDesc = desc
+	ulv.applyFilterAndSort()
+
+	ulv.cursor = 0
+	for i := range ulv.filteredItems {
+		if ulv.originalIndex(i) == current {
+			ulv.cursor = i
+			break
+		}
+	}
+	ulv.ensureCursorVisible()
+}
+
+// HandleSortKey applies sorting keys shared by the release and asset views
+func (ulv *UnifiedListView) HandleSortKey(key string) bool {
+	switch key {
+	case "s":
+		ulv.CycleSort()
+	case "r":
+		ulv.ReverseSort()
+	default:
+		return false
+	}
+	return true
+}
+
+// columns returns the column layout for the current items, sized to fit
+// every item so that widths stay stable while filtering
+func (ulv *UnifiedListView) columns() []listColumn {
+	var columns []listColumn
+	switch ulv.kind {
+	case listAssets:
+		tags := map[string]bool{}
+		for _, item := range ulv.items {
+			if asset, ok := item.(AssetInfo); ok {
+				tags[asset.ReleaseTag] = true
+			}
+		}
+		columns = []listColumn{
+			{title: "Name", sorts: []SortColumn{SortName}, value: func(item interface{}) string {
+				return item.(AssetInfo).Name
+			}},
+			{title: "Size", sorts: []SortColumn{SortSize}, alignRight: true, value: func(item interface{}) string {
+				return item.(AssetInfo).SizeStr
+			}},
+			{title: "Date", sorts: []SortColumn{SortDate}, value: func(item interface{}) string {
+				return item.(AssetInfo).FormattedDate
+			}},
+			{title: "Downloads", sorts: []SortColumn{SortDownloads}, alignRight: true, value: func(item interface{}) string {
+				return strconv.Itoa(item.(AssetInfo).DownloadCount)
+			}},
+		}
+		// The tag column only adds information when assets span several releases
+		if len(tags) > 1 || ulv.sortColumn == SortTag {
+			columns = append(columns, listColumn{title: "Tag", sorts: []SortColumn{SortTag}, value: func(item interface{}) string {
+				return item.(AssetInfo).ReleaseTag
+			}})
+		}
+	case listRepositories:
+		columns = []listColumn{
+			{title: "Repository", sorts: []SortColumn{SortName}, value: func(item interface{}) string {
+				return item.(Repository).FullName
+			}},
+			{title: "Latest release", value: func(item interface{}) string {
+				repo := item.(Repository)
+				switch {
+				case repo.ReleaseCheckFailed:
+					return "?"
+				case repo.ReleaseCheckSkipped:
+					return "-"
+				case !repo.ReleasesChecked:
+					return "…"
+				case repo.LatestRelease == "":
+					return "none"
+				}
+				return repo.LatestRelease
+			}},
+			{title: "Stars", sorts: []SortColumn{SortStars}, alignRight: true, value: func(item interface{}) string {
+				return strconv.Itoa(item.(Repository).StargazersCount)
+			}},
+			{title: "Pushed", sorts: []SortColumn{SortDate}, value: func(item interface{}) string {
+				return formatCreatedAt(item.(Repository).PushedAt)
+			}},
+			{title: "Description", value: func(item interface{}) string {
+				return item.(Repository).Description
+			}},
+		}
+	case listRecent:
+		columns = []listColumn{
+			{title: "★", value: func(item interface{}) string {
+				if item.(RepositoryHistory).Bookmarked {
+					return "★"
+				}
+				return ""
+			}},
+			{title: "Repository", sorts: []SortColumn{SortName}, value: func(item interface{}) string {
+				return item.(RepositoryHistory).FullName()
+			}},
+			{title: "Last tag", value: func(item interface{}) string {
+				return item.(RepositoryHistory).LastTag
+			}},
+			{title: "Last mask", value: func(item interface{}) string {
+				return item.(RepositoryHistory).LastMask
+			}},
+			{title: "Last used", sorts: []SortColumn{SortDate}, value: func(item interface{}) string {
+				return item.(RepositoryHistory).LastUsed.Local().Format("2006-01-02 15:04")
+			}},
+		}
+	case listRuns:
+		columns = []listColumn{
+			{title: "Workflow", sorts: []SortColumn{SortName}, value: func(item interface{}) string {
+				return item.(WorkflowRun).Label()
+			}},
+			{title: "Branch", value: func(item interface{}) string {
+				return item.(WorkflowRun).HeadBranch
+			}},
+			{title: "Event", value: func(item interface{}) string {
+				return item.(WorkflowRun).Event
+			}},
+			{title: "Status", value: func(item interface{}) string {
+				return item.(WorkflowRun).Result()
+			}},
+			{title: "Started", sorts: []SortColumn{SortDate}, value: func(item interface{}) string {
+				return formatCreatedAt(item.(WorkflowRun).CreatedAt)
+			}},
+			{title: "Title", value: func(item interface{}) string {
+				return item.(WorkflowRun).DisplayTitle
+			}},
+		}
+	default:
+		columns = []listColumn{
+			{title: "Tag", sorts: []SortColumn{SortTag}, value: func(item interface{}) string {
+				return item.(Release).TagName
+			}},
+			{title: "Name", value: func(item interface{}) string {
+				return item.(Release).Name
+			}},
+			{title: "Published", sorts: []SortColumn{SortDate}, value: func(item interface{}) string {
+				return formatCreatedAt(item.(Release).PublishedAt)
+			}},
+		}
+	}
+
+	for i := range columns {
+		columns[i].width = ansi.StringWidth(ulv.columnTitle(columns[i]))
+		for _, item := range ulv.items {
+			if w := ansi.StringWidth(columns[i].value(item)); w > columns[i].width {
+				columns[i].width = w
+			}
+		}
+		if columns[i].width > maxNameColumnWidth {
+			columns[i].width = maxNameColumnWidth
+		}
+	}
+	return columns
+}
+
+// columnTitle returns the header text of column with a sort indicator
+func (ulv *UnifiedListView) columnTitle(column listColumn) string {
+	for _, sorted := range column.sorts {
+		if sorted != ulv.sortColumn {
+			continue
+		}
+		if ulv.sortDesc {
+			return column.title + " ▼"
+		}
+		return column.title + " ▲"
+	}
+	return column.title
+}
+
+// formatHeader renders the column titles aligned with formatRow
+func (ulv *UnifiedListView) formatHeader(columns []listColumn) string {
+	cells := make([]string, len(columns))
+	for i, column := range columns {
+		cells[i] = alignCell(ulv.columnTitle(column), column.width, column.alignRight)
+	}
+	return strings.TrimRight(strings.Join(cells, "  "), " ")
+}
+
+// formatRow renders item as aligned column cells
+func formatRow(columns []listColumn, item interface{}) string {
+	cells := make([]string, len(columns))
+	for i, column := range columns {
+		cells[i] = alignCell(ansi.Truncate(column.value(item), column.width, "…"), column.width, column.alignRight)
+	}
+	return strings.TrimRight(strings.Join(cells, "  "), " ")
+}
+
+// alignCell pads value to width, on the left when alignRight is set
+func alignCell(value string, width int, alignRight bool) string {
+	pad := width - ansi.StringWidth(value)
+	if pad <= 0 {
+		return value
+	}
+	if alignRight {
+		return strings.Repeat(" ", pad) + value
+	}
+	return value + strings.Repeat(" ", pad)
+}
+
+// compareItems orders two releases or two assets by column
+func compareItems(a, b interface{}, column SortColumn) int {
+	switch x := a.(type) {
+	case Release:
+		y := b.(Release)
+		switch column {
+		case SortTag:
+			return compareVersions(x.TagName, y.TagName)
+		case SortDate:
+			return strings.Compare(x.PublishedAt, y.PublishedAt)
+		}
+	case Repository:
+		y := b.(Repository)
+		switch column {
+		case SortName:
+			return strings.Compare(strings.ToLower(x.FullName), strings.ToLower(y.FullName))
+		case SortStars:
+			return compareInts(int64(x.StargazersCount), int64(y.StargazersCount))
+		case SortDate:
+			return strings.Compare(x.PushedAt, y.PushedAt)
+		}
+	case RepositoryHistory:
+		y := b.(RepositoryHistory)
+		switch column {
+		case SortName:
+			return strings.Compare(strings.ToLower(x.FullName()), strings.ToLower(y.FullName()))
+		case SortDate:
+			return x.LastUsed.Compare(y.LastUsed)
+		}
+	case WorkflowRun:
+		y := b.(WorkflowRun)
+		switch column {
+		case SortName:
+			if c := strings.Compare(strings.ToLower(x.Name), strings.ToLower(y.Name)); c != 0 {
+				return c
+			}
+			return compareInts(int64(x.RunNumber), int64(y.RunNumber))
+		case SortDate:
+			return strings.Compare(x.CreatedAt, y.CreatedAt)
+		}
+	case AssetInfo:
+		y := b.(AssetInfo)
+		switch column {
+		case SortName:
+			return strings.Compare(strings.ToLower(x.Name), strings.ToLower(y.Name))
+		case SortSize:
+			return compareInts(x.Size, y.Size)
+		case SortDate:
+			return strings.Compare(x.CreatedAt, y.CreatedAt)
+		case SortDownloads:
+			return compareInts(int64(x.DownloadCount), int64(y.DownloadCount))
+		case SortTag:
+			return compareVersions(x.ReleaseTag, y.ReleaseTag)
+		}
+	}
+	return 0
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compareVersions compares version-like tags such as "v1.10.0" and
+// "1.9.2-rc1" numerically; a pre-release sorts before its release
+func compareVersions(a, b string) int {
+	coreA, preA := splitVersion(a)
+	coreB, preB := splitVersion(b)
+	for i := 0; i < len(coreA) || i < len(coreB); i++ {
+		var x, y int64
+		if i < len(coreA) {
+			x = coreA[i]
+		}
+		if i < len(coreB) {
+			y = coreB[i]
+		}
+		if c := compareInts(x, y); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case preA == preB:
+		return strings.Compare(a, b)
+	case preA == "":
+		return 1
+	case preB == "":
+		return -1
+	}
+	if c := comparePrereleases(preA, preB); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+// comparePrereleases orders pre-release suffixes by their dot-separated
+// identifiers as in semver: numeric identifiers compare numerically and
+// below alphanumeric ones, and a longer list of identifiers sorts higher.
+// Digits within an identifier compare numerically too, so "rc2" sorts
+// before "rc10"
+func comparePrereleases(a, b string) int {
+	idsA, idsB := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(idsA) && i < len(idsB); i++ {
+		x, errX := strconv.ParseUint(idsA[i], 10, 64)
+		y, errY := strconv.ParseUint(idsB[i], 10, 64)
+		var c int
+		switch {
+		case errX == nil && errY == nil:
+			c = cmp.Compare(x, y)
+		case errX == nil:
+			c = -1
+		case errY == nil:
+			c = 1
+		default:
+			c = compareNatural(idsA[i], idsB[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return compareInts(int64(len(idsA)), int64(len(idsB)))
+}
+
+// compareNatural compares strings with runs of digits ordered numerically
+func compareNatural(a, b string) int {
+	for a != "" && b != "" {
+		runA, runB := leadingRun(a), leadingRun(b)
+		a, b = a[len(runA):], b[len(runB):]
+		x, errX := strconv.ParseUint(runA, 10, 64)
+		y, errY := strconv.ParseUint(runB, 10, 64)
+		if errX == nil && errY == nil {
+			if x != y {
+				return cmp.Compare(x, y)
+			}
+			continue
+		}
+		if c := strings.Compare(runA, runB); c != 0 {
+			return c
+		}
+	}
+	return compareInts(int64(len(a)), int64(len(b)))
+}
+
+// leadingRun returns the leading digits of s, or its leading non-digits
+func leadingRun(s string) string {
+	digit := s[0] >= '0' && s[0] <= '9'
+	for i := 1; i < len(s); i++ {
+		if (s[i] >= '0' && s[i] <= '9') != digit {
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// splitVersion extracts the numeric components and pre-release suffix of a tag
+func splitVersion(tag string) ([]int64, string) {
+	tag = strings.TrimLeftFunc(tag, func(r rune) bool { return !unicode.IsDigit(r) })
+	tag, _, _ = strings.Cut(tag, "+")
+	core, pre, _ := strings.Cut(tag, "-")
+
+	var parts []int64
+	for _, field := range strings.FieldsFunc(core, func(r rune) bool { return !unicode.IsDigit(r) }) {
+		n, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			break
+		}
+		parts = append(parts, n)
+	}
+	return parts, pre
+}
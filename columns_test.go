@@ -0,0 +1,61 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	// Each tag sorts before the next one
+	ordered := []string{
+		"v1.0.0-alpha",
+		"v1.0.0-alpha.1",
+		"v1.0.0-alpha.beta",
+		"v1.0.0-beta.2",
+		"v1.0.0-beta.11",
+		"v1.0.0-rc1",
+		"v1.0.0-rc2",
+		"v1.0.0-rc10",
+		"v1.0.0",
+		"v1.9.0",
+		"v1.10.0",
+		"v2.0.0-rc.1",
+		"v2.0.0",
+	}
+	for i := range ordered {
+		for j := range ordered {
+			want := compareInts(int64(i), int64(j))
+			if got := compareVersions(ordered[i], ordered[j]); got != want {
+				t.Errorf("compareVersions(%q, %q) = %d, want %d", ordered[i], ordered[j], got, want)
+			}
+		}
+	}
+
+	// Build metadata does not make a release a pre-release
+	if c := compareVersions("v1.0.0+build.5", "v1.0.0-rc1"); c != 1 {
+		t.Errorf("release with build metadata sorts %d against its pre-release", c)
+	}
+}
+
+func TestSortReleasesByTag(t *testing.T) {
+	var items []interface{}
+	for _, tag := range []string{"v1.0.0", "v1.0.0-rc10", "v1.10.0", "v1.0.0-rc2", "v1.9.0"} {
+		items = append(items, Release{TagName: tag})
+	}
+	slices.SortStableFunc(items, func(a, b interface{}) int { return compareItems(a, b, SortTag) })
+
+	var got []string
+	for _, item := range items {
+		got = append(got, item.(Release).TagName)
+	}
+	want := []string{"v1.0.0-rc2", "v1.0.0-rc10", "v1.0.0", "v1.9.0", "v1.10.0"}
+	if !slices.Equal(got, want) {
+		t.Errorf("releases sorted as %v, want %v", got, want)
+	}
+
+	// Assets of those releases sort the same way
+	a, b := AssetInfo{ReleaseTag: "v1.0.0-rc10"}, AssetInfo{ReleaseTag: "v1.0.0-rc2"}
+	if c := compareItems(a, b, SortTag); c != 1 {
+		t.Errorf("asset of rc10 sorts %d against rc2", c)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -8,6 +9,9 @@ import (
 	"strings"
 )
 
+// errConfigNotFound reports that no configuration file exists
+var errConfigNotFound = errors.New("configuration file not found")
+
 // loadConfig loads configuration from file with Windows support
 func loadConfig() (*Config, error) {
 	scriptDir, err := filepath.Abs(filepath.Dir(os.Args[0]))
@@ -42,9 +46,9 @@ func loadConfig() (*Config, error) {
 
 	if fileToRead == "" {
 		if homeConfigFile != "" {
-			return nil, fmt.Errorf("configuration file not found in %s or %s", configFile, homeConfigFile)
+			return nil, fmt.Errorf("%w in %s or %s", errConfigNotFound, configFile, homeConfigFile)
 		} else {
-			return nil, fmt.Errorf("configuration file not found in %s", configFile)
+			return nil, fmt.Errorf("%w in %s", errConfigNotFound, configFile)
 		}
 	}
 
@@ -57,7 +61,16 @@ func loadConfig() (*Config, error) {
 	config := &Config{}
 	lines := strings.Split(string(content), "\n")
 
-	for _, line := range lines {
+	// Invalid settings are collected and reported together
+	var problems []error
+	report := func(lineNumber int, format string, args ...any) {
+		problems = append(problems, fmt.Errorf("%s:%d: %s", fileToRead, lineNumber, fmt.Sprintf(format, args...)))
+	}
+	warn := func(lineNumber int, format string, args ...any) {
+		config.Warnings = append(config.Warnings, fmt.Sprintf("%s:%d: %s", fileToRead, lineNumber, fmt.Sprintf(format, args...)))
+	}
+
+	for i, line := range lines {
 		line = strings.TrimSpace(line)
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
@@ -65,6 +78,7 @@ func loadConfig() (*Config, error) {
 
 		parts := strings.SplitN(line, "=", 2)
 		if len(parts) != 2 {
+			warn(i+1, "line without \"=\" is ignored")
 			continue
 		}
 
@@ -79,14 +93,126 @@ func loadConfig() (*Config, error) {
 		switch key {
 		case "GITHUB_TOKEN":
 			config.GitHubToken = value
+		case "GITHUB_API_URL":
+			config.GitHubAPIURL = strings.TrimRight(value, "/")
 		case "REPO_OWNER":
 			config.RepoOwner = value
 		case "REPO_NAME":
 			config.RepoName = value
 		case "ASSET_MASK":
 			config.AssetMask = value
+		case "MINISIGN_PUBKEY":
+			config.MinisignPublicKey = value
+		case "GPG_KEYRING":
+			config.GPGKeyring = value
+		case "COSIGN_PUBKEY":
+			config.CosignPublicKey = value
+		case "COSIGN_IDENTITY":
+			config.CosignIdentity = value
+		case "COSIGN_IDENTITY_REGEXP":
+			config.CosignIdentityRegexp = value
+		case "COSIGN_ISSUER":
+			config.CosignIssuer = value
+		case "REQUIRE_SIGNATURE":
+			config.RequireSignature = value == "true" || value == "1" || value == "yes"
+		case "SIGSTORE_TRUSTED_ROOT":
+			config.SigstoreTrustedRoot = value
+		case "OVERWRITE_POLICY":
+			if policy, err := parseOverwritePolicy(value); err != nil {
+				report(i+1, "%v", err)
+			} else {
+				config.OverwritePolicy = policy
+			}
+		case "REGISTRY_HOST":
+			config.RegistryHost = value
+		case "REGISTRY_USERNAME":
+			config.RegistryUsername = value
+		case "REGISTRY_PASSWORD":
+			config.RegistryPassword = value
+		case "SERVE_CLIENT_TOKEN":
+			config.ServeClientToken = value
+		case "PROXY_URL":
+			config.ProxyURL = value
+		case "CA_CERT_FILES":
+			for _, file := range strings.Split(value, ",") {
+				if file = strings.TrimSpace(file); file != "" {
+					config.CACertFiles = append(config.CACertFiles, file)
+				}
+			}
+		case "CLIENT_CERT":
+			config.ClientCert = value
+		case "CLIENT_KEY":
+			config.ClientKey = value
+		case "INSECURE_SKIP_VERIFY":
+			for _, host := range strings.Split(value, ",") {
+				if host = strings.ToLower(strings.TrimSpace(host)); host == "" {
+					continue
+				} else if host == "true" || host == "1" || host == "yes" {
+					report(i+1, "INSECURE_SKIP_VERIFY lists the hosts whose certificates are not verified, not %q", value)
+				} else {
+					config.InsecureHosts = append(config.InsecureHosts, host)
+				}
+			}
+		default:
+			if strings.HasPrefix(key, "HOOK") {
+				if err := config.addHook(key, value); err != nil {
+					report(i+1, "%v", err)
+				}
+			} else if !config.setAttestationOption(key, value) {
+				// A misspelled security setting would silently turn a check off
+				if setting := misspelledSecuritySetting(key); setting != "" {
+					report(i+1, "unknown setting %s, did you mean %s?", key, setting)
+				} else {
+					warn(i+1, "unknown setting %s is ignored", key)
+				}
+			}
 		}
 	}
 
+	if len(problems) > 0 {
+		return nil, errors.Join(problems...)
+	}
 	return config, nil
 }
+
+// securitySettings are the settings that enable signature and provenance
+// checks, which are not ignored when misspelled
+var securitySettings = []string{
+	"MINISIGN_PUBKEY", "GPG_KEYRING", "COSIGN_PUBKEY", "COSIGN_IDENTITY", "COSIGN_IDENTITY_REGEXP",
+	"COSIGN_ISSUER",
+	"REQUIRE_SIGNATURE", "VERIFY_ATTESTATIONS", "ATTESTATION_REPOSITORY",
+	"ATTESTATION_WORKFLOW", "ATTESTATION_REF", "SIGSTORE_TRUSTED_ROOT",
+}
+
+// misspelledSecuritySetting returns the security setting that key, without
+// an "@owner/repo" suffix, is at most two edits away from, or ""
+func misspelledSecuritySetting(key string) string {
+	name, _, _ := strings.Cut(strings.ToUpper(key), "@")
+	for _, setting := range securitySettings {
+		if editDistance(name, setting) <= 2 {
+			return setting
+		}
+	}
+	return ""
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
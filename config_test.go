@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigReportsUnknownSettings(t *testing.T) {
+	setupFakeGitHub(t)
+	if _, err := loadConfig(); !errors.Is(err, errConfigNotFound) {
+		t.Errorf("no configuration file: got %v, want errConfigNotFound", err)
+	}
+
+	writeConfig(t, `ATTESTATION_WORKFLW="release.yml"`, "VERIFY_ATTESTATIONS@owner/repo=true", "# comment", "REQUIRE_SIGNATUR=true")
+	_, err := loadConfig()
+	if err == nil {
+		t.Fatal("misspelled settings accepted")
+	}
+	for _, want := range []string{
+		"afetch.conf:1: unknown setting ATTESTATION_WORKFLW, did you mean ATTESTATION_WORKFLOW?",
+		"afetch.conf:4: unknown setting REQUIRE_SIGNATUR, did you mean REQUIRE_SIGNATURE?",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+	writeConfig(t, "verify_attestation@owner/repo=true")
+	if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), "did you mean VERIFY_ATTESTATIONS?") {
+		t.Errorf("scoped misspelling: got %v", err)
+	}
+
+	// Other unknown settings and malformed lines are only warned about
+	writeConfig(t, `ATTESTATION_WORKFLOW="release.yml"`, "VERIFY_ATTESTATIONS@owner/repo=true", "OLD_SETTING=1", "GITHUB_TOKEN")
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy := config.attestationPolicy("owner/repo"); policy == nil || policy.Workflow != "release.yml" {
+		t.Errorf("policy = %+v", policy)
+	}
+	want := []string{"afetch.conf:3: unknown setting OLD_SETTING is ignored", `afetch.conf:4: line without "=" is ignored`}
+	if len(config.Warnings) != len(want) {
+		t.Fatalf("warnings = %q", config.Warnings)
+	}
+	for i, warning := range config.Warnings {
+		if !strings.HasSuffix(warning, want[i]) {
+			t.Errorf("warning %q, want %q", warning, want[i])
+		}
+	}
+}
+
+func TestLoadConfigRejectsInvalidOverwritePolicy(t *testing.T) {
+	setupFakeGitHub(t)
+	writeConfig(t, "OVERWRITE_POLICY=replace")
+	_, err := loadConfig()
+	if err == nil || !strings.Contains(err.Error(), `afetch.conf:1: invalid OVERWRITE_POLICY "replace", expected skip-if-same-digest, overwrite, rename or ask`) {
+		t.Errorf("got %v", err)
+	}
+
+	writeConfig(t, "OVERWRITE_POLICY=Rename")
+	if config, err := loadConfig(); err != nil || config.overwritePolicy() != PolicyRename {
+		t.Errorf("got %v, %v", config, err)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// detailField is one labelled row of the asset detail pane
+type detailField struct {
+	label string
+	value string
+}
+
+// assetDetailFields returns the metadata rows shown for asset
+func assetDetailFields(asset AssetInfo) []detailField {
+	orUnknown := func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		return s
+	}
+	release := asset.ReleaseTag
+	if asset.ReleaseName != "" && asset.ReleaseName != asset.ReleaseTag {
+		release += " (" + asset.ReleaseName + ")"
+	}
+	size := asset.SizeStr
+	if asset.Size > 0 {
+		size = fmt.Sprintf("%s (%d bytes)", asset.SizeStr, asset.Size)
+	}
+	return []detailField{
+		{"Name", asset.Name},
+		{"Release", orUnknown(release)},
+		{"Content type", orUnknown(asset.ContentType)},
+		{"Size", size},
+		{"Downloads", fmt.Sprintf("%d", asset.DownloadCount)},
+		{"Uploader", orUnknown(asset.Uploader)},
+		{"Created", orUnknown(formatCreatedAt(asset.CreatedAt))},
+		{"Updated", orUnknown(formatCreatedAt(asset.UpdatedAt))},
+		{"State", orUnknown(asset.State)},
+		{"Digest", orUnknown(asset.Digest)},
+		{"API URL", orUnknown(asset.URL)},
+		{"Download URL", orUnknown(asset.DownloadURL)},
+		{"Saved as", asset.LocalFileName()},
+	}
+}
+
+// detailPaneHeight is the number of lines taken by the detail pane,
+// including the blank line and the separator above it
+func detailPaneHeight() int {
+	return len(assetDetailFields(AssetInfo{})) + 2
+}
+
+// renderAssetDetails renders the detail pane for asset, truncated to width
+func renderAssetDetails(asset AssetInfo, width int) string {
+	labelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("15"))
+
+	separatorWidth := 40
+	if width > 1 {
+		separatorWidth = width - 1
+	}
+	s := "\n" + labelStyle.Render(strings.Repeat("─", separatorWidth)) + "\n"
+	for _, field := range assetDetailFields(asset) {
+		row := labelStyle.Render(fmt.Sprintf("  %-13s", field.label)) + valueStyle.Render(field.value)
+		if width > 0 {
+			row = ansi.Truncate(row, width-1, "…")
+		}
+		s += row + "\n"
+	}
+	return s
+}
+
+// clipboardMsg reports the outcome of copying a value to the clipboard
+type clipboardMsg struct {
+	what string
+	err  error
+}
+
+// copyToClipboard copies value to the system clipboard using an OSC 52
+// escape sequence, which also works over SSH and inside tmux/screen. The
+// sequence is printed through the program, so it does not interleave with
+// the program's own terminal output.
+func copyToClipboard(value, what string) tea.Cmd {
+	if value == "" {
+		return func() tea.Msg {
+			return clipboardMsg{what: what, err: fmt.Errorf("no %s available", what)}
+		}
+	}
+	seq := osc52.New(value)
+	if os.Getenv("TMUX") != "" {
+		seq = seq.Tmux()
+	} else if strings.HasPrefix(os.Getenv("TERM"), "screen") {
+		seq = seq.Screen()
+	}
+	return tea.Sequence(tea.Printf("%s", seq), func() tea.Msg {
+		return clipboardMsg{what: what}
+	})
+}
@@ -4,122 +4,193 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"net/http"
 	"os"
-	"path"
-	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/Native-Robotics/asset-fetch/afetch"
+)
+
+// Minimum time between progress events of a download, and the number of
+// events buffered for the TUI before newer ones are dropped
+const (
+	progressInterval    = 100 * time.Millisecond
+	progressEventBuffer = 16
 )
 
-// downloadAsset download artifact using http.Client
-func downloadAsset(asset AssetInfo) tea.Cmd {
+// errDownloadPaused is the cancellation cause of a paused download, whose
+// partial file is kept to be resumed
+var errDownloadPaused = errors.New("download paused")
+
+// downloadAsset download artifact using http.Client, reporting progress
+// events to onProgress. The file is written next to its target as a partial
+// file and renamed once verified; a non-zero offset resumes a partial file
+// of that many bytes. An existing target is handled according to policy, or
+// to the configured policy when it is empty. Cancelling ctx stops the
+// download and removes the partial file, unless the cause is
+// errDownloadPaused.
+func downloadAsset(ctx context.Context, asset AssetInfo, offset int64, policy OverwritePolicy, onProgress func(downloaded, total int64)) tea.Cmd {
 	return func() tea.Msg {
-		config, err := loadConfig()
-		if err != nil {
-			return downloadErrorMsg(err.Error())
+		msg := fetchAsset(ctx, asset, offset, policy, onProgress)
+		// Failures caused by the cancellation are reported as such; a file
+		// that was already verified is kept
+		switch msg.(type) {
+		case checksumVerifiedMsg, downloadPausedMsg, downloadUpToDateMsg:
+			return msg
 		}
+		if ctx.Err() != nil {
+			if errors.Is(context.Cause(ctx), errDownloadPaused) {
+				// Paused while verifying; the download starts over
+				return downloadPausedMsg{}
+			}
+			return downloadCancelledMsg{}
+		}
+		return msg
+	}
+}
 
-		// Create HTTP client with context
-		client := &http.Client{}
+// fetchAsset downloads and verifies asset, returning the resulting message
+func fetchAsset(ctx context.Context, asset AssetInfo, offset int64, policy OverwritePolicy, onProgress func(downloaded, total int64)) tea.Msg {
+	// Public assets can be downloaded without a configuration file
+	config, err := loadConfig()
+	if errors.Is(err, errConfigNotFound) {
+		config = &Config{}
+	} else if err != nil {
+		return downloadErrorMsg(fmt.Sprintf("Error loading configuration: %v", err))
+	}
 
-		// Create request with context
-		req, err := http.NewRequestWithContext(downloadContext, "GET", asset.URL, nil)
-		if err != nil {
-			return downloadErrorMsg(fmt.Sprintf("Error creating request: %v", err))
-		}
+	// Artifact downloads require authentication even for public repositories
+	if asset.Artifact && config.GitHubToken == "" {
+		return downloadErrorMsg("GITHUB_TOKEN is required to download workflow artifacts")
+	}
 
-		// Set headers
-		req.Header.Set("Accept", "application/octet-stream")
-		// Only add authorization header if token is provided
-		if config.GitHubToken != "" {
-			req.Header.Set("Authorization", "Bearer "+config.GitHubToken)
-		}
-		req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	// Check for an existing file before downloading
+	if policy == "" {
+		policy = config.overwritePolicy()
+	}
+	target, upToDate, err := resolveTarget(asset, policy)
+	if err != nil {
+		return downloadErrorMsg(err.Error())
+	}
+	if upToDate {
+		return downloadUpToDateMsg{filename: target}
+	}
 
-		// Execute request
-		resp, err := client.Do(req)
-		if err != nil {
-			// Check if the error is due to context cancellation
-			if errors.Is(downloadContext.Err(), context.Canceled) {
-				return downloadErrorMsg("Download cancelled by user")
+	// Only resume a partial file that still has the expected size
+	filename := partialFileName(asset)
+	if info, err := os.Stat(filename); offset > 0 && (err != nil || info.Size() != offset) {
+		offset = 0
+	}
+
+	// Workflow artifacts are saved as a zip archive and unpacked below
+	dest := target
+	if asset.Artifact {
+		dest = asset.LocalFileName()
+	}
+
+	// Refuse to keep files whose detached signature or build provenance
+	// does not verify
+	var signature signatureResult
+	opts := &afetch.DownloadOptions{
+		Progress:         onProgress,
+		ProgressInterval: progressInterval,
+		Resume:           offset > 0,
+		Partial:          filename,
+		Accept:           githubAccept(asset),
+		Verify: func(partial string) error {
+			signature = verifyAssetSignatures(ctx, partial, asset, config)
+			if signature.err == nil {
+				signature = signature.combine(verifyAssetAttestations(ctx, partial, asset, config))
 			}
-			return downloadErrorMsg(fmt.Sprintf("Error downloading file: %v", err))
+			return signature.err
+		},
+	}
+	if asset.OCIBlob {
+		// Registries hand out their own pull tokens; blobs are addressed by
+		// their digest, so they cannot change between attempts
+		opts.Fetch = func(ctx context.Context, offset int64, _ string) (*http.Response, error) {
+			return fetchOCIBlob(ctx, asset, config, offset)
 		}
-		defer func() {
-			if closeErr := resp.Body.Close(); closeErr != nil {
-				// Log the error but don't return it as it's in defer
-			}
-		}()
+	}
 
-		// Check response status
-		if resp.StatusCode != http.StatusOK {
-			return downloadErrorMsg(fmt.Sprintf("HTTP error: %d", resp.StatusCode))
+	err = newGitHubClient(config).Download(ctx, afetch.Asset{Name: asset.Name, URL: asset.URL, Size: asset.Size, Digest: asset.Digest}, dest, opts)
+	var incomplete *afetch.IncompleteError
+	var checksumErr *afetch.ChecksumError
+	var httpErr *afetch.HTTPError
+	switch {
+	case err == nil:
+	case signature.err != nil:
+		return signatureFailedMsg{
+			filename:  target,
+			signature: signature.status,
+			err:       fmt.Sprintf("Signature verification failed for %s: %v", target, signature.err),
 		}
-
-		// Create output file
-		out, err := os.Create(asset.Name)
-		if err != nil {
-			return downloadErrorMsg(fmt.Sprintf("Error creating file: %v", err))
+	case errors.As(err, &incomplete):
+		// Keep what was written so far when pausing
+		if errors.Is(context.Cause(ctx), errDownloadPaused) {
+			return downloadPausedMsg{downloaded: incomplete.Downloaded}
 		}
-		defer func() {
-			if closeErr := out.Close(); closeErr != nil {
-				// Log the error but don't return it as it's in defer
-			}
-		}()
-
-		// Create a progress reader
-		progressReader := &ProgressReader{
-			reader: resp.Body,
-			total:  asset.Size,
-			onProgress: func(downloaded, total int64) {
-				// Update global progress variable
-				downloadProgressMutex.Lock()
-				downloadProgress = downloaded
-				downloadProgressMutex.Unlock()
-			},
+		if removeErr := afetch.RemovePartial(filename); removeErr != nil {
+			// Log the error but don't return it as we already have a write error
 		}
+		return downloadErrorMsg(fmt.Sprintf("Error writing file: %v", incomplete.Err))
+	case errors.As(err, &checksumErr):
+		return downloadErrorMsg(fmt.Sprintf("Checksum verification failed for %s: %v", target, err))
+	case errors.As(err, &httpErr):
+		return downloadErrorMsg(err.Error())
+	case errors.Is(context.Cause(ctx), errDownloadPaused):
+		return downloadPausedMsg{downloaded: offset}
+	default:
+		return downloadErrorMsg(fmt.Sprintf("Error downloading file: %v", err))
+	}
 
-		// Copy response body to file
-		_, err = io.Copy(out, progressReader)
-		if err != nil {
-			// Check if the error is due to context cancellation
-			if errors.Is(downloadContext.Err(), context.Canceled) {
-				// Clean up partial file
-				if removeErr := os.Remove(asset.Name); removeErr != nil {
-					// Log the error but don't return it as we already have a cancellation error
-				}
-				return downloadErrorMsg("Download cancelled by user")
-			}
-			// Clean up partial file
-			if removeErr := os.Remove(asset.Name); removeErr != nil {
-				// Log the error but don't return it as we already have a write error
-			}
-			return downloadErrorMsg(fmt.Sprintf("Error writing file: %v", err))
+	// Workflow artifacts are unpacked into a directory named after the
+	// artifact, which replaces an existing one as the policy allowed
+	if asset.Artifact {
+		if err := extractArtifact(dest, target); err != nil {
+			return downloadErrorMsg(fmt.Sprintf("Error extracting %s: %v", asset.LocalFileName(), err))
 		}
-
-		// Verify checksum if digest is provided
-		if err := verifyChecksum(asset.Name, asset.Digest); err != nil {
-			// Clean up file with incorrect checksum
-			if removeErr := os.Remove(asset.Name); removeErr != nil {
-				// Log the error but don't return it as we already have a checksum error
-			}
-			return downloadErrorMsg(fmt.Sprintf("Checksum verification failed for %s: %v", asset.Name, err))
+		if removeErr := os.Remove(dest); removeErr != nil {
+			// Log the error but don't return it as the artifact was extracted
 		}
+	}
 
-		return checksumVerifiedMsg{
-			filename: asset.Name,
-			success:  true,
-			err:      "",
-		}
+	// Run post-download hooks; the file is kept even if one fails
+	hookErr := ""
+	if err := runHooks(ctx, config, target, asset); err != nil {
+		hookErr = err.Error()
+	}
+
+	return checksumVerifiedMsg{
+		filename:  target,
+		success:   true,
+		err:       "",
+		signature: signature.status,
+		hookErr:   hookErr,
 	}
 }
 
+// fetchGitHubAsset starts the download of a release asset, source archive
+// or workflow artifact from the GitHub API
+func fetchGitHubAsset(ctx context.Context, asset AssetInfo, config *Config, offset int64) (*http.Response, error) {
+	return newGitHubClient(config).OpenAsset(ctx, asset.URL, githubAccept(asset), offset, "")
+}
+
+// githubAccept returns the Accept header for downloading asset; source
+// archive and artifact endpoints redirect to the archive
+func githubAccept(asset AssetInfo) string {
+	if asset.SourceArchive || asset.Artifact {
+		return "application/vnd.github+json"
+	}
+	return "application/octet-stream"
+}
+
 // fetchReleases get list of releases with ASSET_MASK filtering
 func fetchReleases(m model) tea.Cmd {
 	return func() tea.Msg {
@@ -138,72 +209,28 @@ func fetchReleases(m model) tea.Cmd {
 			repoName = config.RepoName
 		}
 
-		var apiURL string
-		if m.tag != "" {
-			apiURL = fmt.Sprintf("https://api.github.com/repos/%s/%s/releases/tags/%s", repoOwner, repoName, m.tag)
-		} else {
-			apiURL = fmt.Sprintf("https://api.github.com/repos/%s/%s/releases", repoOwner, repoName)
-		}
-
-		client := &http.Client{}
-		req, err := http.NewRequest("GET", apiURL, nil)
-		if err != nil {
-			return errorMsg(err.Error())
-		}
-
-		req.Header.Set("Accept", "application/vnd.github+json")
-
-		// Use token from config if available
-		var token string
-		if config != nil {
-			token = config.GitHubToken
-		}
-
-		// Only add authorization header if token is provided
-		if token != "" {
-			req.Header.Set("Authorization", "Bearer "+token)
-		}
-		req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
-
-		resp, err := client.Do(req)
-		if err != nil {
-			return errorMsg(err.Error())
-		}
-		defer func() {
-			if closeErr := resp.Body.Close(); closeErr != nil {
-				// Log the error but don't return it as it's in defer
-			}
-		}()
-
-		if resp.StatusCode != http.StatusOK {
-			return errorMsg(fmt.Sprintf("GitHub API error: %d", resp.StatusCode))
-		}
-
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return errorMsg(err.Error())
-		}
+		client := newGitHubClient(config)
 
-		// If a specific tag is requested, the API returns a single release object
+		// If a specific tag is requested, only that release is listed
 		if m.tag != "" {
-			var release Release
-			err = json.Unmarshal(body, &release)
+			release, err := client.GetRelease(context.Background(), repoOwner, repoName, m.tag)
 			if err != nil {
 				return errorMsg(err.Error())
 			}
-			releases := []Release{release}
+			releases := []Release{*release}
 			var assets []AssetInfo
 			formatter := AssetFormatter{}
 			for _, asset := range release.Assets {
-				assetInfo := formatter.FormatAssetInfo(asset, release)
+				assetInfo := formatter.FormatAssetInfo(asset, *release)
 				assetInfo.DisplayLine = formatter.createDisplayLineWithoutTag(asset.Name, assetInfo.SizeStr, assetInfo.FormattedDate)
+				assetInfo.Repository = repoOwner + "/" + repoName
 				assets = append(assets, assetInfo)
 			}
-			return releasesMsg{assets: assets, releases: releases}
+			assets = append(assets, formatter.SourceArchiveAssets(*release, repoName)...)
+			return releasesMsg{assets: assets, releases: releases, owner: repoOwner, name: repoName}
 		}
 
-		var releases []Release
-		err = json.Unmarshal(body, &releases)
+		releases, err := client.ListReleases(context.Background(), repoOwner, repoName)
 		if err != nil {
 			return errorMsg(err.Error())
 		}
@@ -217,7 +244,7 @@ func fetchReleases(m model) tea.Cmd {
 
 		// If AssetMask is empty OR if we are starting with releases view from URL
 		if assetMaskValue == "" || m.startWithReleases {
-			return releasesMsg{releases: releases}
+			return releasesMsg{releases: releases, owner: repoOwner, name: repoName}
 		}
 
 		// Filter assets by ASSET_MASK
@@ -225,12 +252,22 @@ func fetchReleases(m model) tea.Cmd {
 		formatter := AssetFormatter{}
 
 		for _, release := range releases {
+			var candidates []AssetInfo
 			for _, asset := range release.Assets {
-				matched, err := path.Match(assetMaskValue, asset.Name)
+				candidates = append(candidates, formatter.FormatAssetInfo(asset, release))
+			}
+			// Source archives can be selected by mask like any other asset,
+			// shown with their release tag as the assets are
+			for _, archive := range formatter.SourceArchiveAssets(release, repoName) {
+				archive.DisplayLine = formatter.createDisplayLine(archive.Name, archive.SizeStr, archive.FormattedDate, release.TagName)
+				candidates = append(candidates, archive)
+			}
+			for _, assetInfo := range candidates {
+				matched, err := afetch.MatchMask(assetMaskValue, assetInfo.Name)
 				if err != nil || !matched {
 					continue
 				}
-				assetInfo := formatter.FormatAssetInfo(asset, release)
+				assetInfo.Repository = repoOwner + "/" + repoName
 				assets = append(assets, assetInfo)
 			}
 		}
@@ -239,7 +276,7 @@ func fetchReleases(m model) tea.Cmd {
 			return errorMsg("artifacts not found")
 		}
 
-		return releasesMsg{assets: assets, releases: releases}
+		return releasesMsg{assets: assets, releases: releases, owner: repoOwner, name: repoName}
 	}
 }
 
@@ -272,6 +309,11 @@ func formatSize(size int64) string {
 
 // calculateSHA256 calculates the SHA256 hash of a file
 func calculateSHA256(filename string) (string, error) {
+	return calculateFileHash(filename, sha256.New())
+}
+
+// calculateFileHash calculates the hex-encoded hash of a file
+func calculateFileHash(filename string, hash hash.Hash) (string, error) {
 	file, err := os.Open(filename)
 	if err != nil {
 		return "", err
@@ -282,39 +324,9 @@ func calculateSHA256(filename string) (string, error) {
 		}
 	}()
 
-	hash := sha256.New()
 	if _, err := io.Copy(hash, file); err != nil {
 		return "", err
 	}
 
 	return hex.EncodeToString(hash.Sum(nil)), nil
 }
-
-// verifyChecksum verifies the SHA256 checksum of a downloaded file
-func verifyChecksum(filename string, expectedDigest string) error {
-	// If no digest is provided, skip verification
-	if expectedDigest == "" {
-		return nil
-	}
-
-	// Extract the actual digest from the expectedDigest string
-	// GitHub API returns digest in format "sha256:abcdef..."
-	parts := strings.Split(expectedDigest, ":")
-	if len(parts) != 2 || parts[0] != "sha256" {
-		return fmt.Errorf("invalid digest format: %s", expectedDigest)
-	}
-	expectedSHA256 := parts[1]
-
-	// Calculate actual SHA256 of the file
-	actualSHA256, err := calculateSHA256(filename)
-	if err != nil {
-		return fmt.Errorf("error calculating checksum: %v", err)
-	}
-
-	// Compare checksums
-	if actualSHA256 != expectedSHA256 {
-		return fmt.Errorf("checksum verification failed: expected %s, got %s", expectedSHA256, actualSHA256)
-	}
-
-	return nil
-}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Native-Robotics/asset-fetch/internal/githubtest"
+)
+
+// setupFakeGitHub points afetch at a fake GitHub API and runs the test in an
+// empty working directory, without configuration or remembered state
+func setupFakeGitHub(t *testing.T) *githubtest.Server {
+	t.Helper()
+	server := githubtest.NewServer(t)
+	previous := githubAPIURL
+	githubAPIURL = server.URL
+	t.Cleanup(func() { githubAPIURL = previous })
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Chdir(t.TempDir())
+	return server
+}
+
+// writeConfig writes afetch.conf with the given lines to the user's
+// configuration directory
+func writeConfig(t *testing.T, lines ...string) {
+	t.Helper()
+	dir := filepath.Join(os.Getenv("HOME"), ".config")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "afetch.conf"), []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// addTestReleases adds two releases of owner/repo to server
+func addTestReleases(server *githubtest.Server) {
+	server.AddRelease("owner/repo", githubtest.Release{
+		Tag: "v1.1.0",
+		Assets: []githubtest.Asset{
+			{Name: "app_linux_amd64.tar.gz", Content: []byte("linux build 1.1.0")},
+			{Name: "app_windows_amd64.zip", Content: []byte("windows build 1.1.0")},
+		},
+		Source: []byte("source 1.1.0"),
+	})
+	server.AddRelease("owner/repo", githubtest.Release{
+		Tag: "v1.0.0",
+		Assets: []githubtest.Asset{
+			{Name: "app_linux_amd64.tar.gz", Content: []byte("linux build 1.0.0")},
+		},
+	})
+}
+
+func TestFetchReleasesListsAllPages(t *testing.T) {
+	server := setupFakeGitHub(t)
+	server.MaxPerPage = 1
+	addTestReleases(server)
+
+	msg := fetchReleases(model{repoOwner: "owner", repoName: "repo"})()
+	releases, ok := msg.(releasesMsg)
+	if !ok {
+		t.Fatalf("got %#v, want releasesMsg", msg)
+	}
+	if len(releases.releases) != 2 || releases.releases[0].TagName != "v1.1.0" || releases.releases[1].TagName != "v1.0.0" {
+		t.Errorf("releases = %v, want v1.1.0 and v1.0.0", releases.releases)
+	}
+	if len(releases.assets) != 0 {
+		t.Errorf("assets listed without a mask: %v", releases.assets)
+	}
+}
+
+func TestFetchReleasesFiltersByMask(t *testing.T) {
+	server := setupFakeGitHub(t)
+	addTestReleases(server)
+	writeConfig(t, `ASSET_MASK="*linux*"`)
+
+	msg := fetchReleases(model{repoOwner: "owner", repoName: "repo"})()
+	releases, ok := msg.(releasesMsg)
+	if !ok {
+		t.Fatalf("got %#v, want releasesMsg", msg)
+	}
+	if len(releases.assets) != 2 {
+		t.Fatalf("assets = %v, want the Linux build of both releases", releases.assets)
+	}
+	for _, asset := range releases.assets {
+		if asset.Name != "app_linux_amd64.tar.gz" || asset.Repository != "owner/repo" {
+			t.Errorf("asset %s of %s listed", asset.Name, asset.Repository)
+		}
+	}
+
+	// Source archives are matched like release assets
+	mask := "Source code (*)"
+	msg = fetchReleases(model{repoOwner: "owner", repoName: "repo", assetMask: &mask})()
+	if releases, ok := msg.(releasesMsg); !ok || len(releases.assets) != 2 {
+		t.Fatalf("got %#v, want the source archives of v1.1.0", msg)
+	} else {
+		for _, asset := range releases.assets {
+			if !asset.SourceArchive || asset.ReleaseTag != "v1.1.0" || asset.Repository != "owner/repo" || !strings.Contains(asset.DisplayLine, "v1.1.0") {
+				t.Errorf("asset = %+v", asset)
+			}
+		}
+	}
+
+	mask = "*.exe"
+	if msg := fetchReleases(model{repoOwner: "owner", repoName: "repo", assetMask: &mask})(); msg != errorMsg("artifacts not found") {
+		t.Errorf("got %#v for a mask matching nothing", msg)
+	}
+}
+
+func TestFetchReleasesByTag(t *testing.T) {
+	server := setupFakeGitHub(t)
+	addTestReleases(server)
+
+	msg := fetchReleases(model{repoOwner: "owner", repoName: "repo", tag: "v1.1.0"})()
+	releases, ok := msg.(releasesMsg)
+	if !ok {
+		t.Fatalf("got %#v, want releasesMsg", msg)
+	}
+	var names []string
+	for _, asset := range releases.assets {
+		names = append(names, asset.Name)
+	}
+	want := "app_linux_amd64.tar.gz app_windows_amd64.zip Source code (tar.gz) Source code (zip)"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("assets = %q, want %q", got, want)
+	}
+}
+
+func TestFetchReleasesErrors(t *testing.T) {
+	server := setupFakeGitHub(t)
+	addTestReleases(server)
+
+	if msg := fetchReleases(model{repoOwner: "owner", repoName: "missing"})(); msg != errorMsg("GitHub API error: 404") {
+		t.Errorf("missing repository: got %#v", msg)
+	}
+
+	server.SetRateLimited(true)
+	msg, ok := fetchReleases(model{repoOwner: "owner", repoName: "repo"})().(errorMsg)
+	if !ok || !strings.Contains(string(msg), "rate limit") {
+		t.Errorf("rate limited: got %#v", msg)
+	}
+}
+
+// releaseAsset returns the named asset of a release from the fake server
+func releaseAsset(t *testing.T, tag, name string) AssetInfo {
+	t.Helper()
+	msg := fetchReleases(model{repoOwner: "owner", repoName: "repo", tag: tag})()
+	releases, ok := msg.(releasesMsg)
+	if !ok {
+		t.Fatalf("got %#v, want releasesMsg", msg)
+	}
+	for _, asset := range releases.assets {
+		if asset.Name == name {
+			return asset
+		}
+	}
+	t.Fatalf("no asset %s in %s", name, tag)
+	return AssetInfo{}
+}
+
+func TestDownloadAsset(t *testing.T) {
+	server := setupFakeGitHub(t)
+	addTestReleases(server)
+	asset := releaseAsset(t, "v1.1.0", "app_linux_amd64.tar.gz")
+
+	var downloaded, total int64
+	msg := downloadAsset(context.Background(), asset, 0, "", func(d, t int64) { downloaded, total = d, t })()
+	if verified, ok := msg.(checksumVerifiedMsg); !ok || !verified.success || verified.filename != "app_linux_amd64.tar.gz" {
+		t.Fatalf("got %#v, want a verified download", msg)
+	}
+	if content, _ := os.ReadFile("app_linux_amd64.tar.gz"); string(content) != "linux build 1.1.0" {
+		t.Errorf("content = %q", content)
+	}
+	if downloaded != asset.Size || total != asset.Size {
+		t.Errorf("last progress %d/%d, want %d/%d", downloaded, total, asset.Size, asset.Size)
+	}
+
+	// The default policy keeps a file that already has the asset's digest
+	msg = downloadAsset(context.Background(), asset, 0, "", nil)()
+	if msg != (downloadUpToDateMsg{filename: "app_linux_amd64.tar.gz"}) {
+		t.Errorf("second download: got %#v, want downloadUpToDateMsg", msg)
+	}
+	msg = downloadAsset(context.Background(), asset, 0, PolicyRename, nil)()
+	if verified, ok := msg.(checksumVerifiedMsg); !ok || verified.filename != "app_linux_amd64 (1).tar.gz" {
+		t.Errorf("renamed download: got %#v", msg)
+	}
+}
+
+func TestDownloadAssetChecksumMismatch(t *testing.T) {
+	server := setupFakeGitHub(t)
+	server.AddRelease("owner/repo", githubtest.Release{Tag: "v1", Assets: []githubtest.Asset{
+		{Name: "app.bin", Content: []byte("tampered"), Digest: githubtest.Digest([]byte("original"))},
+	}})
+	asset := releaseAsset(t, "v1", "app.bin")
+
+	msg, ok := downloadAsset(context.Background(), asset, 0, "", nil)().(downloadErrorMsg)
+	if !ok || !strings.HasPrefix(string(msg), "Checksum verification failed for app.bin") {
+		t.Errorf("got %#v, want a checksum error", msg)
+	}
+	entries, _ := os.ReadDir(".")
+	if len(entries) != 0 {
+		t.Errorf("files left behind: %v", entries)
+	}
+}
+
+func TestDownloadAssetHTTPError(t *testing.T) {
+	server := setupFakeGitHub(t)
+	addTestReleases(server)
+	asset := releaseAsset(t, "v1.1.0", "app_windows_amd64.zip")
+	server.Fail(strings.TrimPrefix(asset.URL, server.URL), 500)
+
+	if msg := downloadAsset(context.Background(), asset, 0, "", nil)(); msg != downloadErrorMsg("HTTP error: 500 Internal Server Error") {
+		t.Errorf("got %#v", msg)
+	}
+}
+
+func TestDownloadSourceArchive(t *testing.T) {
+	server := setupFakeGitHub(t)
+	addTestReleases(server)
+	asset := releaseAsset(t, "v1.1.0", "Source code (tar.gz)")
+
+	msg := downloadAsset(context.Background(), asset, 0, "", nil)()
+	if verified, ok := msg.(checksumVerifiedMsg); !ok || verified.filename != "repo-v1.1.0.tar.gz" {
+		t.Fatalf("got %#v, want a verified download", msg)
+	}
+	if content, _ := os.ReadFile("repo-v1.1.0.tar.gz"); string(content) != "source 1.1.0" {
+		t.Errorf("content = %q", content)
+	}
+}
+
+func TestDownloadAssetInvalidConfig(t *testing.T) {
+	server := setupFakeGitHub(t)
+	addTestReleases(server)
+	asset := releaseAsset(t, "v1.1.0", "app_linux_amd64.tar.gz")
+
+	// Only a missing configuration file falls back to the defaults
+	writeConfig(t, "OVERWRITE_POLICY=sometimes")
+	msg, ok := downloadAsset(context.Background(), asset, 0, "", nil)().(downloadErrorMsg)
+	if !ok || !strings.Contains(string(msg), `invalid OVERWRITE_POLICY "sometimes"`) {
+		t.Errorf("got %#v, want a configuration error", msg)
+	}
+	if _, err := os.Stat("app_linux_amd64.tar.gz"); !os.IsNotExist(err) {
+		t.Error("downloaded despite the invalid configuration")
+	}
+}
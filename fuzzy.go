@@ -0,0 +1,166 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Fuzzy match scoring weights
+const (
+	fuzzyMatchScore       = 16
+	fuzzyBoundaryBonus    = 24
+	fuzzyCamelBonus       = 16
+	fuzzyConsecutiveBonus = 20
+	fuzzyFirstCharBonus   = 8
+	fuzzyGapPenalty       = 2
+	fuzzyMaxGapPenalty    = 12
+)
+
+// fuzzyResult holds the outcome of matching a pattern against one field
+type fuzzyResult struct {
+	score     int
+	field     string // the text that was matched
+	positions []int  // rune indices of matched characters within field
+}
+
+// fuzzyMatch performs case-insensitive subsequence matching of pattern
+// against text. Matches at word boundaries and consecutive runs score
+// higher; ok is false if pattern is not a subsequence of text.
+func fuzzyMatch(pattern, text string) (fuzzyResult, bool) {
+	// Runes are lowered one by one so indices into t and orig agree even
+	// where lowering a whole string changes its length
+	p := []rune(pattern)
+	for i, r := range p {
+		p[i] = unicode.ToLower(r)
+	}
+	orig := []rune(text)
+	t := make([]rune, len(orig))
+	for i, r := range orig {
+		t[i] = unicode.ToLower(r)
+	}
+	if len(p) == 0 {
+		return fuzzyResult{field: text}, true
+	}
+	if len(p) > len(t) {
+		return fuzzyResult{}, false
+	}
+
+	// best[i][j] is the best score matching p[:i+1] with p[i] at t[j];
+	// prev[i][j] is the position of p[i-1] on that best path
+	const unmatched = -1 << 30
+	best := make([][]int, len(p))
+	prev := make([][]int, len(p))
+	for i := range p {
+		best[i] = make([]int, len(t))
+		prev[i] = make([]int, len(t))
+		for j := range t {
+			best[i][j] = unmatched
+			prev[i][j] = -1
+		}
+	}
+
+	for i := range p {
+		for j := i; j < len(t); j++ {
+			if t[j] != p[i] {
+				continue
+			}
+			bonus := fuzzyMatchScore + fuzzyCharBonus(orig, j)
+			if i == 0 {
+				if j == 0 {
+					bonus += fuzzyFirstCharBonus
+				}
+				best[i][j] = bonus
+				continue
+			}
+			for k := i - 1; k < j; k++ {
+				if best[i-1][k] == unmatched {
+					continue
+				}
+				score := best[i-1][k] + bonus
+				if k == j-1 {
+					score += fuzzyConsecutiveBonus
+				} else {
+					gap := (j - k - 1) * fuzzyGapPenalty
+					if gap > fuzzyMaxGapPenalty {
+						gap = fuzzyMaxGapPenalty
+					}
+					score -= gap
+				}
+				if score > best[i][j] {
+					best[i][j] = score
+					prev[i][j] = k
+				}
+			}
+		}
+	}
+
+	last := len(p) - 1
+	end := -1
+	for j := range t {
+		if best[last][j] != unmatched && (end < 0 || best[last][j] > best[last][end]) {
+			end = j
+		}
+	}
+	if end < 0 {
+		return fuzzyResult{}, false
+	}
+
+	positions := make([]int, len(p))
+	for i, j := last, end; i >= 0; i-- {
+		positions[i] = j
+		j = prev[i][j]
+	}
+	return fuzzyResult{score: best[last][end], field: text, positions: positions}, true
+}
+
+// fuzzyCharBonus rewards characters that start a word in text
+func fuzzyCharBonus(text []rune, j int) int {
+	if j == 0 {
+		return fuzzyBoundaryBonus
+	}
+	prevRune, cur := text[j-1], text[j]
+	switch {
+	case !unicode.IsLetter(prevRune) && !unicode.IsDigit(prevRune):
+		return fuzzyBoundaryBonus
+	case unicode.IsLower(prevRune) && unicode.IsUpper(cur):
+		return fuzzyCamelBonus
+	case unicode.IsLetter(prevRune) && unicode.IsDigit(cur):
+		return fuzzyCamelBonus
+	}
+	return 0
+}
+
+// bestFuzzyMatch returns the highest scoring match of pattern across fields
+func bestFuzzyMatch(pattern string, fields ...string) (fuzzyResult, bool) {
+	var best fuzzyResult
+	found := false
+	for _, field := range fields {
+		if field == "" {
+			continue
+		}
+		if res, ok := fuzzyMatch(pattern, field); ok && (!found || res.score > best.score) {
+			best = res
+			found = true
+		}
+	}
+	return best, found
+}
+
+// highlightPositions maps matched positions within res.field onto rune
+// indices of line, which is expected to contain the field verbatim
+func highlightPositions(line string, res fuzzyResult) map[int]bool {
+	if len(res.positions) == 0 {
+		return nil
+	}
+	byteOffset := strings.Index(line, res.field)
+	if byteOffset < 0 {
+		return nil
+	}
+	runeOffset := utf8.RuneCountInString(line[:byteOffset])
+	set := make(map[int]bool, len(res.positions))
+	for _, pos := range res.positions {
+		set[runeOffset+pos] = true
+	}
+	return set
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFuzzyMatchPositions(t *testing.T) {
+	for _, test := range []struct {
+		pattern, text string
+		positions     []int // nil when there is no match
+	}{
+		{"lnxarm", "app_linux_arm64.tar.gz", []int{4, 6, 8, 10, 11, 12}},
+		{"LNX", "app_linux_arm64", []int{4, 6, 8}},
+		{"übr", "Download_Über_Tool", []int{9, 10, 12}},
+		{"ubr", "Download_Über_Tool", nil},
+		{"İst", "İstanbul.zip", []int{0, 1, 2}},
+		{"tool", "Download_Über_Tool", []int{14, 15, 16, 17}},
+		{"xyz", "app_linux_arm64", nil},
+		{"armlinux", "app_linux_arm64", nil},
+		{"toolong", "tool", nil},
+	} {
+		res, ok := fuzzyMatch(test.pattern, test.text)
+		if ok != (test.positions != nil) || !slices.Equal(res.positions, test.positions) {
+			t.Errorf("fuzzyMatch(%q, %q) = %v, %v, want %v", test.pattern, test.text, res.positions, ok, test.positions)
+		}
+	}
+
+	if res, ok := fuzzyMatch("", "anything"); !ok || res.score != 0 || res.positions != nil {
+		t.Errorf("empty pattern: %+v, %v", res, ok)
+	}
+}
+
+func TestFuzzyMatchRanking(t *testing.T) {
+	for _, test := range []struct {
+		pattern        string
+		better, weaker string
+	}{
+		// Word starts and runs beat scattered letters
+		{"lnxarm", "app_linux_arm64.tar.gz", "all_unix_tarballs_for_macos.zip"},
+		{"arm", "linux_arm64", "parmesan"},
+		// Boundaries: after a separator, camel case and letter-digit
+		{"ab", "a_b", "axb"},
+		{"fb", "fooBar", "foobar"},
+		{"a6", "arm64", "arm_x6"},
+		// Consecutive characters beat a gap, and short gaps beat long ones
+		{"ab", "abxx", "axxb"},
+		{"ac", "abcxxxxxxxxxx", "abbbbbbbc"},
+		// A match at the start of the text gets a bonus
+		{"app", "app.tar.gz", "my-app.tar.gz"},
+		// Non-ASCII letters are word characters like ASCII ones
+		{"über", "über_tool", "uxbxexr_über"},
+	} {
+		better, ok := fuzzyMatch(test.pattern, test.better)
+		if !ok {
+			t.Errorf("%q does not match %q", test.pattern, test.better)
+			continue
+		}
+		weaker, ok := fuzzyMatch(test.pattern, test.weaker)
+		if !ok {
+			t.Errorf("%q does not match %q", test.pattern, test.weaker)
+			continue
+		}
+		if better.score <= weaker.score {
+			t.Errorf("%q: %q scores %d, not above %q with %d", test.pattern, test.better, better.score, test.weaker, weaker.score)
+		}
+	}
+}
+
+func TestBestFuzzyMatch(t *testing.T) {
+	res, ok := bestFuzzyMatch("lnx", "", "release notes for linux", "linux")
+	if !ok || res.field != "linux" {
+		t.Errorf("got %+v, %v, want the match in %q", res, ok, "linux")
+	}
+	if _, ok := bestFuzzyMatch("lnx", "windows", ""); ok {
+		t.Error("match without a matching field")
+	}
+
+	// Positions of a field are mapped onto runes of the displayed line
+	res, _ = fuzzyMatch("tl", "Tool")
+	got := highlightPositions("v1 · Über · Tool 2MB", res)
+	want := map[int]bool{12: true, 15: true}
+	if len(got) != len(want) || !got[12] || !got[15] {
+		t.Errorf("highlight = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,17 @@
+package main
+
+import "github.com/Native-Robotics/asset-fetch/afetch"
+
+// githubAPIURL is the API requests are sent to; GITHUB_API_URL points it at
+// a proxy such as "afetch serve"
+var githubAPIURL = afetch.DefaultAPIURL
+
+// newGitHubClient returns a client for the configured API, authorized by
+// the configured token; config may be nil
+func newGitHubClient(config *Config) *afetch.Client {
+	client := &afetch.Client{APIURL: githubAPIURL, HTTPClient: httpClient}
+	if config != nil {
+		client.Token = config.GitHubToken
+	}
+	return client
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Maximum length of hook output quoted in a failure message
+const maxHookOutput = 200
+
+// Hook is a command run after an asset has been downloaded and verified
+type Hook struct {
+	Repository string // owner/repo, or "" for all repositories
+	Mask       string // glob matched against the asset name, or "" for all assets
+	Command    string
+}
+
+// addHook records a "HOOK" configuration key. The key may be scoped as
+// "HOOK@owner/repo", "HOOK@<mask>" or "HOOK@owner/repo:<mask>".
+func (c *Config) addHook(key, value string) error {
+	name, scope, scoped := strings.Cut(key, "@")
+	if name != "HOOK" {
+		return fmt.Errorf("unknown setting %s", key)
+	}
+	if value == "" {
+		return fmt.Errorf("%s has no command", key)
+	}
+	hook := Hook{Command: value}
+	if strings.Contains(scope, "/") {
+		hook.Repository, hook.Mask, _ = strings.Cut(scope, ":")
+		if owner, repo, _ := strings.Cut(hook.Repository, "/"); owner == "" || repo == "" || strings.Contains(repo, "/") {
+			return fmt.Errorf("%s: invalid repository %q, expected owner/repo", key, hook.Repository)
+		}
+	} else {
+		hook.Mask = scope
+	}
+	if scoped && hook.Repository == "" && hook.Mask == "" {
+		return fmt.Errorf("%s: empty scope", key)
+	}
+	if _, err := path.Match(hook.Mask, ""); err != nil {
+		return fmt.Errorf("%s: invalid mask %q", key, hook.Mask)
+	}
+	c.Hooks = append(c.Hooks, hook)
+	return nil
+}
+
+// hooksFor returns the configured hooks that apply to asset, in config order
+func (c *Config) hooksFor(asset AssetInfo) []Hook {
+	var hooks []Hook
+	for _, hook := range c.Hooks {
+		if hook.Repository != "" && !strings.EqualFold(hook.Repository, asset.Repository) {
+			continue
+		}
+		if hook.Mask != "" {
+			if matched, err := path.Match(hook.Mask, asset.Name); err != nil || !matched {
+				continue
+			}
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks
+}
+
+// runHooks runs the hooks that apply to asset, saved at filename, one after
+// another. Each hook gets the download described in AFETCH_* environment
+// variables; the first failing hook stops the rest.
+func runHooks(ctx context.Context, config *Config, filename string, asset AssetInfo) error {
+	hooks := config.hooksFor(asset)
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	absPath, err := filepath.Abs(filename)
+	if err != nil {
+		return err
+	}
+	digest := asset.Digest
+	if info, err := os.Stat(filename); digest == "" && err == nil && !info.IsDir() {
+		if sum, err := calculateSHA256(filename); err == nil {
+			digest = "sha256:" + sum
+		}
+	}
+	env := append(os.Environ(),
+		"AFETCH_FILE="+absPath,
+		"AFETCH_ASSET="+asset.Name,
+		"AFETCH_TAG="+asset.ReleaseTag,
+		"AFETCH_DIGEST="+digest,
+		"AFETCH_REPOSITORY="+asset.Repository,
+	)
+
+	for _, hook := range hooks {
+		cmd := hookCommand(ctx, hook.Command)
+		cmd.Env = env
+		// Hooks run while the TUI owns the terminal, so their output is
+		// captured and only quoted when they fail
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("hook %q: %v%s", hook.Command, err, formatHookOutput(output))
+		}
+	}
+	return nil
+}
+
+// hookCommand runs command through the platform shell
+func hookCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+// formatHookOutput returns the last line of a failed hook's output
+func formatHookOutput(output []byte) string {
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	if last == "" {
+		return ""
+	}
+	return ": " + truncateString(last, maxHookOutput)
+}
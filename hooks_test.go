@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestAddHookScopes(t *testing.T) {
+	config := &Config{}
+	for _, key := range []string{"HOOK", "HOOK@owner/repo", "HOOK@*.deb", "HOOK@owner/repo:*_linux_*"} {
+		if err := config.addHook(key, "true"); err != nil {
+			t.Fatalf("%s: %v", key, err)
+		}
+	}
+	want := []Hook{
+		{Command: "true"},
+		{Repository: "owner/repo", Command: "true"},
+		{Mask: "*.deb", Command: "true"},
+		{Repository: "owner/repo", Mask: "*_linux_*", Command: "true"},
+	}
+	for i, hook := range config.Hooks {
+		if hook != want[i] {
+			t.Errorf("hook %d = %+v, want %+v", i, hook, want[i])
+		}
+	}
+
+	for _, test := range []struct {
+		asset AssetInfo
+		want  int // number of hooks that apply
+	}{
+		{AssetInfo{Name: "tool.deb", Repository: "owner/repo"}, 3},
+		{AssetInfo{Name: "tool_linux_amd64.tar.gz", Repository: "Owner/Repo"}, 3},
+		{AssetInfo{Name: "tool.deb", Repository: "other/repo"}, 2},
+		{AssetInfo{Name: "tool.zip", Repository: "other/repo"}, 1},
+	} {
+		if hooks := config.hooksFor(test.asset); len(hooks) != test.want {
+			t.Errorf("%s of %s: %d hooks, want %d", test.asset.Name, test.asset.Repository, len(hooks), test.want)
+		}
+	}
+
+	for key, value := range map[string]string{
+		"HOOKS":               "true",
+		"HOOK_DEB":            "true",
+		"HOOK@":               "true",
+		"HOOK@owner/":         "true",
+		"HOOK@owner/repo/sub": "true",
+		"HOOK@[":              "true",
+		"HOOK@owner/repo:[":   "true",
+		"HOOK@*.deb":          "",
+	} {
+		if err := (&Config{}).addHook(key, value); err == nil {
+			t.Errorf("%s=%s accepted", key, value)
+		}
+	}
+}
+
+func TestLoadConfigReportsInvalidHooks(t *testing.T) {
+	setupFakeGitHub(t)
+	writeConfig(t, "HOOK=true", "HOOK@[a-=true", "HOOKS=true")
+	_, err := loadConfig()
+	if err == nil {
+		t.Fatal("invalid hooks accepted")
+	}
+	for _, want := range []string{`afetch.conf:2: HOOK@[a-: invalid mask "[a-"`, "afetch.conf:3: unknown setting HOOKS"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+}
+
+func TestRunHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks are written for sh")
+	}
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "tool.deb")
+	if err := os.WriteFile(filename, []byte("package"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	asset := AssetInfo{Name: "tool.deb", ReleaseTag: "v1", Repository: "owner/repo"}
+	log := filepath.Join(dir, "hooks.log")
+
+	config := &Config{}
+	for _, hook := range []struct{ key, command string }{
+		{"HOOK", `echo "$AFETCH_FILE|$AFETCH_ASSET|$AFETCH_TAG|$AFETCH_DIGEST|$AFETCH_REPOSITORY" >> ` + log},
+		{"HOOK@*.zip", "echo zip >> " + log},
+	} {
+		if err := config.addHook(hook.key, hook.command); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := runHooks(context.Background(), config, filename, asset); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(log)
+	sum, _ := calculateSHA256(filename)
+	want := filename + "|tool.deb|v1|sha256:" + sum + "|owner/repo\n"
+	if string(got) != want {
+		t.Errorf("hook environment:\n got %q\nwant %q", got, want)
+	}
+
+	// A failing hook stops the rest and reports its last line of output
+	config = &Config{}
+	_ = config.addHook("HOOK", "echo starting; echo 'disk full' >&2; exit 3")
+	_ = config.addHook("HOOK", "echo second >> "+log)
+	err := runHooks(context.Background(), config, filename, asset)
+	if err == nil || !strings.Contains(err.Error(), "exit status 3: disk full") {
+		t.Errorf("got %v, want the exit status and output", err)
+	}
+	if got, _ := os.ReadFile(log); string(got) != want {
+		t.Errorf("hook after a failure ran: %q", got)
+	}
+}
@@ -0,0 +1,374 @@
+// Package githubtest is a fake GitHub REST API for tests. It serves the
+// release endpoints afetch uses, with pagination, redirects from the API to
+// asset storage, Range requests, and injectable rate-limit and error
+// responses.
+package githubtest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Release is a release to serve; the first release added to a repository
+// is the latest one
+type Release struct {
+	Tag         string
+	Name        string
+	PublishedAt time.Time
+	Assets      []Asset
+	// Source archive served for the tarball and zipball URLs, if not nil
+	Source []byte
+}
+
+// Asset is a release asset to serve
+type Asset struct {
+	Name    string
+	Content []byte
+	// Digest overrides the digest reported by the API: "" reports the
+	// SHA-256 of Content, "-" reports none
+	Digest string
+}
+
+// Request is a request received by the server
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+}
+
+// Server is a fake GitHub API at URL
+type Server struct {
+	*httptest.Server
+
+	// PerPage is the default and MaxPerPage the largest page size of
+	// release lists
+	PerPage    int
+	MaxPerPage int
+	// Token, if set, is required as a bearer token on every API request
+	Token string
+
+	mu          sync.Mutex
+	repos       map[string][]storedRelease
+	assets      map[int]storedAsset
+	nextID      int
+	rateLimited bool
+	failures    map[string]int
+	requests    []Request
+}
+
+type storedRelease struct {
+	Release
+	assetIDs []int
+}
+
+type storedAsset struct {
+	Asset
+	repo string
+	tag  string
+	id   int
+}
+
+var (
+	releasesPath     = regexp.MustCompile(`^/repos/([^/]+)/([^/]+)/releases$`)
+	latestPath       = regexp.MustCompile(`^/repos/([^/]+)/([^/]+)/releases/latest$`)
+	tagPath          = regexp.MustCompile(`^/repos/([^/]+)/([^/]+)/releases/tags/(.+)$`)
+	assetPath        = regexp.MustCompile(`^/repos/([^/]+)/([^/]+)/releases/assets/(\d+)$`)
+	archivePath      = regexp.MustCompile(`^/repos/([^/]+)/([^/]+)/(tarball|zipball)/(.+)$`)
+	browserAssetPath = regexp.MustCompile(`^/([^/]+)/([^/]+)/releases/download/([^/]+)/([^/]+)$`)
+	storagePath      = regexp.MustCompile(`^/storage/(\d+)$`)
+	codeloadPath     = regexp.MustCompile(`^/codeload/([^/]+)/([^/]+)/(tarball|zipball)/(.+)$`)
+)
+
+// NewServer starts a fake GitHub API that is closed when the test ends
+func NewServer(t testing.TB) *Server {
+	s := &Server{
+		PerPage:    30,
+		MaxPerPage: 100,
+		repos:      make(map[string][]storedRelease),
+		assets:     make(map[int]storedAsset),
+		nextID:     1,
+		failures:   make(map[string]int),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// AddRepository adds owner/repo without releases
+func (s *Server) AddRepository(repo string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.repos[repo]; !ok {
+		s.repos[repo] = []storedRelease{}
+	}
+}
+
+// AddRelease adds a release to owner/repo, after the releases already added
+func (s *Server) AddRelease(repo string, release Release) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if release.PublishedAt.IsZero() {
+		release.PublishedAt = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Add(-time.Duration(len(s.repos[repo])) * 24 * time.Hour)
+	}
+	stored := storedRelease{Release: release}
+	for _, asset := range release.Assets {
+		id := s.nextID
+		s.nextID++
+		s.assets[id] = storedAsset{Asset: asset, repo: repo, tag: release.Tag, id: id}
+		stored.assetIDs = append(stored.assetIDs, id)
+	}
+	s.repos[repo] = append(s.repos[repo], stored)
+}
+
+// SetRateLimited makes API requests fail as if the rate limit was exceeded
+func (s *Server) SetRateLimited(limited bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rateLimited = limited
+}
+
+// Fail makes requests to path answer with status; 0 removes the failure
+func (s *Server) Fail(path string, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if status == 0 {
+		delete(s.failures, path)
+	} else {
+		s.failures[path] = status
+	}
+}
+
+// Requests returns the requests received so far
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// AssetURL returns the API URL of the named asset of owner/repo's release tag
+func (s *Server) AssetURL(repo, tag, name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, asset := range s.assets {
+		if asset.repo == repo && asset.tag == tag && asset.Name == name {
+			return fmt.Sprintf("%s/repos/%s/releases/assets/%d", s.URL, repo, id)
+		}
+	}
+	return ""
+}
+
+// Digest returns the "sha256:<hex>" digest of content
+func Digest(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.Path, Query: r.URL.Query(), Header: r.Header.Clone()})
+
+	if status, ok := s.failures[r.URL.Path]; ok {
+		writeError(w, status, http.StatusText(status))
+		return
+	}
+
+	// Asset storage and codeload are not part of the API
+	if match := storagePath.FindStringSubmatch(r.URL.Path); match != nil {
+		id, _ := strconv.Atoi(match[1])
+		asset, ok := s.assets[id]
+		if !ok {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		// Like GitHub's storage, responses carry an ETag that If-Range can name
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("ETag", `"`+strings.TrimPrefix(Digest(asset.Content), "sha256:")+`"`)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(asset.Content))
+		return
+	}
+	if match := codeloadPath.FindStringSubmatch(r.URL.Path); match != nil {
+		release, ok := s.release(match[1]+"/"+match[2], match[4])
+		if !ok || release.Source == nil {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(release.Source))
+		return
+	}
+	if match := browserAssetPath.FindStringSubmatch(r.URL.Path); match != nil {
+		for id, asset := range s.assets {
+			if asset.repo == match[1]+"/"+match[2] && asset.tag == match[3] && asset.Name == match[4] {
+				http.Redirect(w, r, fmt.Sprintf("%s/storage/%d", s.URL, id), http.StatusFound)
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	if s.rateLimited {
+		w.Header().Set("X-RateLimit-Limit", "60")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		writeError(w, http.StatusForbidden, "API rate limit exceeded")
+		return
+	}
+	if s.Token != "" && r.Header.Get("Authorization") != "Bearer "+s.Token {
+		writeError(w, http.StatusUnauthorized, "Bad credentials")
+		return
+	}
+
+	switch {
+	case releasesPath.MatchString(r.URL.Path):
+		match := releasesPath.FindStringSubmatch(r.URL.Path)
+		s.serveReleases(w, r, match[1]+"/"+match[2])
+	case latestPath.MatchString(r.URL.Path):
+		match := latestPath.FindStringSubmatch(r.URL.Path)
+		releases := s.repos[match[1]+"/"+match[2]]
+		if len(releases) == 0 {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		writeJSON(w, s.releaseJSON(match[1]+"/"+match[2], releases[0]))
+	case tagPath.MatchString(r.URL.Path):
+		match := tagPath.FindStringSubmatch(r.URL.Path)
+		release, ok := s.release(match[1]+"/"+match[2], match[3])
+		if !ok {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		writeJSON(w, s.releaseJSON(match[1]+"/"+match[2], release))
+	case assetPath.MatchString(r.URL.Path):
+		match := assetPath.FindStringSubmatch(r.URL.Path)
+		id, _ := strconv.Atoi(match[3])
+		asset, ok := s.assets[id]
+		if !ok || asset.repo != match[1]+"/"+match[2] {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		// Like GitHub, downloads are redirected to storage on another path
+		if r.Header.Get("Accept") == "application/octet-stream" {
+			http.Redirect(w, r, fmt.Sprintf("%s/storage/%d", s.URL, id), http.StatusFound)
+			return
+		}
+		writeJSON(w, s.assetJSON(asset))
+	case archivePath.MatchString(r.URL.Path):
+		match := archivePath.FindStringSubmatch(r.URL.Path)
+		http.Redirect(w, r, fmt.Sprintf("%s/codeload/%s/%s/%s/%s", s.URL, match[1], match[2], match[3], match[4]), http.StatusFound)
+	default:
+		writeError(w, http.StatusNotFound, "Not Found")
+	}
+}
+
+// serveReleases serves a page of a repository's releases with a Link header
+func (s *Server) serveReleases(w http.ResponseWriter, r *http.Request, repo string) {
+	perPage := s.PerPage
+	if n, err := strconv.Atoi(r.URL.Query().Get("per_page")); err == nil && n > 0 {
+		perPage = min(n, s.MaxPerPage)
+	}
+	page := 1
+	if n, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && n > 0 {
+		page = n
+	}
+
+	releases, ok := s.repos[repo]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	start := min((page-1)*perPage, len(releases))
+	end := min(start+perPage, len(releases))
+	if end < len(releases) {
+		next := *r.URL
+		query := next.Query()
+		query.Set("page", strconv.Itoa(page+1))
+		next.RawQuery = query.Encode()
+		w.Header().Set("Link", fmt.Sprintf(`<%s%s>; rel="next"`, s.URL, next.RequestURI()))
+	}
+
+	documents := []interface{}{}
+	for _, release := range releases[start:end] {
+		documents = append(documents, s.releaseJSON(repo, release))
+	}
+	writeJSON(w, documents)
+}
+
+func (s *Server) release(repo, tag string) (storedRelease, bool) {
+	for _, release := range s.repos[repo] {
+		if release.Tag == tag {
+			return release, true
+		}
+	}
+	return storedRelease{}, false
+}
+
+func (s *Server) releaseJSON(repo string, release storedRelease) map[string]interface{} {
+	assets := []interface{}{}
+	for _, id := range release.assetIDs {
+		assets = append(assets, s.assetJSON(s.assets[id]))
+	}
+	document := map[string]interface{}{
+		"tag_name":     release.Tag,
+		"name":         release.Name,
+		"published_at": release.PublishedAt.Format(time.RFC3339),
+		"html_url":     fmt.Sprintf("%s/%s/releases/tag/%s", s.URL, repo, release.Tag),
+		"assets":       assets,
+	}
+	if release.Source != nil {
+		document["tarball_url"] = fmt.Sprintf("%s/repos/%s/tarball/%s", s.URL, repo, release.Tag)
+		document["zipball_url"] = fmt.Sprintf("%s/repos/%s/zipball/%s", s.URL, repo, release.Tag)
+	}
+	return document
+}
+
+func (s *Server) assetJSON(asset storedAsset) map[string]interface{} {
+	document := map[string]interface{}{
+		"id":                   asset.id,
+		"name":                 asset.Name,
+		"url":                  fmt.Sprintf("%s/repos/%s/releases/assets/%d", s.URL, asset.repo, asset.id),
+		"browser_download_url": fmt.Sprintf("%s/%s/releases/download/%s/%s", s.URL, asset.repo, asset.tag, asset.Name),
+		"content_type":         "application/octet-stream",
+		"state":                "uploaded",
+		"size":                 len(asset.Content),
+		"created_at":           "2024-01-01T00:00:00Z",
+		"updated_at":           "2024-01-01T00:00:00Z",
+		"download_count":       0,
+		"uploader":             map[string]string{"login": strings.Split(asset.repo, "/")[0]},
+	}
+	switch asset.Digest {
+	case "":
+		document["digest"] = Digest(asset.Content)
+	case "-":
+	default:
+		document["digest"] = asset.Digest
+	}
+	return document
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"message":           message,
+		"documentation_url": "https://docs.github.com/rest",
+	})
+}
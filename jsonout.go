@@ -0,0 +1,332 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path"
+	"time"
+
+	"github.com/Native-Robotics/asset-fetch/afetch"
+)
+
+// Interval between progress events in the download event stream
+const jsonProgressInterval = 500 * time.Millisecond
+
+// jsonOptions holds the flags of the non-interactive JSON mode
+type jsonOptions struct {
+	enabled  bool
+	download bool
+	mask     string
+}
+
+// extractJSONFlags removes "--json", "--download" and "--mask <glob>" from args
+func extractJSONFlags(args []string) ([]string, jsonOptions, error) {
+	var opts jsonOptions
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; arg {
+		case "--json":
+			opts.enabled = true
+		case "--download":
+			opts.download = true
+		case "--mask":
+			if i+1 >= len(args) {
+				return nil, opts, fmt.Errorf("--mask requires a glob pattern")
+			}
+			i++
+			opts.mask = args[i]
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	if (opts.download || opts.mask != "") && !opts.enabled {
+		return nil, opts, fmt.Errorf("--download and --mask require --json")
+	}
+	if opts.mask != "" {
+		if _, err := path.Match(opts.mask, ""); err != nil {
+			return nil, opts, fmt.Errorf("invalid mask %q: %v", opts.mask, err)
+		}
+	}
+	return rest, opts, nil
+}
+
+// jsonAsset is the machine-readable form of an AssetInfo
+type jsonAsset struct {
+	Name          string   `json:"name"`
+	Kind          string   `json:"kind"`
+	Repository    string   `json:"repository,omitempty"`
+	ReleaseTag    string   `json:"release_tag,omitempty"`
+	ReleaseName   string   `json:"release_name,omitempty"`
+	FileName      string   `json:"file_name"`
+	Size          int64    `json:"size"`
+	Digest        string   `json:"digest,omitempty"`
+	ContentType   string   `json:"content_type,omitempty"`
+	State         string   `json:"state,omitempty"`
+	Uploader      string   `json:"uploader,omitempty"`
+	DownloadCount int      `json:"download_count"`
+	CreatedAt     string   `json:"created_at,omitempty"`
+	UpdatedAt     string   `json:"updated_at,omitempty"`
+	URL           string   `json:"url"`
+	DownloadURL   string   `json:"download_url,omitempty"`
+	Signatures    []string `json:"signatures,omitempty"`
+}
+
+// jsonRelease is the machine-readable form of a release and its assets
+type jsonRelease struct {
+	Tag         string      `json:"tag"`
+	Name        string      `json:"name"`
+	PublishedAt string      `json:"published_at"`
+	Assets      []jsonAsset `json:"assets"`
+}
+
+// jsonReleaseListing is printed by "--json" for a repository
+type jsonReleaseListing struct {
+	Repository string        `json:"repository"`
+	Releases   []jsonRelease `json:"releases"`
+}
+
+// jsonAssetListing is printed by "--json" for a tag, a mask or an OCI reference
+type jsonAssetListing struct {
+	Repository string      `json:"repository,omitempty"`
+	Assets     []jsonAsset `json:"assets"`
+}
+
+// downloadEvent is one line of the "--json --download" event stream
+type downloadEvent struct {
+	Event      string     `json:"event"`
+	Time       string     `json:"time"`
+	Asset      *jsonAsset `json:"asset,omitempty"`
+	Name       string     `json:"name,omitempty"`
+	File       string     `json:"file,omitempty"`
+	Downloaded int64      `json:"downloaded,omitempty"`
+	Total      int64      `json:"total,omitempty"`
+	Signature  string     `json:"signature,omitempty"`
+	HookError  string     `json:"hook_error,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	Succeeded  *int       `json:"succeeded,omitempty"`
+	Failed     *int       `json:"failed,omitempty"`
+}
+
+// newJSONAsset converts an AssetInfo for JSON output
+func newJSONAsset(asset AssetInfo) jsonAsset {
+	kind := "release-asset"
+	switch {
+	case asset.SourceArchive:
+		kind = "source-archive"
+	case asset.Artifact:
+		kind = "artifact"
+	case asset.OCIBlob:
+		kind = "oci-blob"
+	}
+	var signatures []string
+	for _, sig := range asset.Signatures {
+		signatures = append(signatures, sig.Name)
+	}
+	return jsonAsset{
+		Name:          asset.Name,
+		Kind:          kind,
+		Repository:    asset.Repository,
+		ReleaseTag:    asset.ReleaseTag,
+		ReleaseName:   asset.ReleaseName,
+		FileName:      asset.LocalFileName(),
+		Size:          asset.Size,
+		Digest:        asset.Digest,
+		ContentType:   asset.ContentType,
+		State:         asset.State,
+		Uploader:      asset.Uploader,
+		DownloadCount: asset.DownloadCount,
+		CreatedAt:     asset.CreatedAt,
+		UpdatedAt:     asset.UpdatedAt,
+		URL:           asset.URL,
+		DownloadURL:   asset.DownloadURL,
+		Signatures:    signatures,
+	}
+}
+
+// releaseAssets returns the assets and source archives of a release
+func releaseAssets(release Release, owner, name string) []AssetInfo {
+	formatter := AssetFormatter{}
+	var assets []AssetInfo
+	for _, asset := range release.Assets {
+		assetInfo := formatter.FormatAssetInfo(asset, release)
+		assetInfo.Repository = owner + "/" + name
+		assets = append(assets, assetInfo)
+	}
+	for _, assetInfo := range formatter.SourceArchiveAssets(release, name) {
+		assetInfo.Repository = owner + "/" + name
+		assets = append(assets, assetInfo)
+	}
+	return assets
+}
+
+// filterAssets returns the assets whose name matches mask ("" matches all)
+func filterAssets(assets []AssetInfo, mask string) []AssetInfo {
+	if mask == "" {
+		return assets
+	}
+	var matched []AssetInfo
+	for _, asset := range assets {
+		if ok, err := afetch.MatchMask(mask, asset.Name); err == nil && ok {
+			matched = append(matched, asset)
+		}
+	}
+	return matched
+}
+
+// runJSON lists releases or assets, or downloads assets, without the TUI,
+// writing to out. Only --mask filters assets, so that the shape of the
+// output does not depend on ASSET_MASK. It returns the process exit status.
+func runJSON(m model, opts jsonOptions, out io.Writer) int {
+	// Fetch full releases; masks are applied here rather than by fetchReleases
+	var msg interface{}
+	if m.ociReference != nil {
+		msg = fetchOCIAssets(*m.ociReference)()
+	} else {
+		emptyMask := ""
+		m.assetMask = &emptyMask
+		msg = fetchReleases(m)()
+	}
+	var data releasesMsg
+	switch msg := msg.(type) {
+	case errorMsg:
+		fmt.Fprintf(os.Stderr, "Error: %s\n", string(msg))
+		return 1
+	case releasesMsg:
+		data = msg
+	}
+
+	repository := ""
+	if data.owner != "" {
+		repository = data.owner + "/" + data.name
+	}
+	var listing interface{}
+	var assets []AssetInfo
+	if m.ociReference != nil {
+		assets = filterAssets(data.assets, opts.mask)
+	} else if opts.download {
+		// Downloads come from the requested tag, or else the latest release
+		if len(data.releases) > 0 {
+			assets = filterAssets(releaseAssets(data.releases[0], data.owner, data.name), opts.mask)
+		}
+	} else if m.tag != "" || opts.mask != "" {
+		for _, release := range data.releases {
+			assets = append(assets, filterAssets(releaseAssets(release, data.owner, data.name), opts.mask)...)
+		}
+	} else {
+		releases := jsonReleaseListing{Repository: repository, Releases: []jsonRelease{}}
+		for _, release := range data.releases {
+			jr := jsonRelease{
+				Tag:         release.TagName,
+				Name:        release.Name,
+				PublishedAt: release.PublishedAt,
+				Assets:      []jsonAsset{},
+			}
+			for _, asset := range releaseAssets(release, data.owner, data.name) {
+				jr.Assets = append(jr.Assets, newJSONAsset(asset))
+			}
+			releases.Releases = append(releases.Releases, jr)
+		}
+		listing = releases
+	}
+
+	if opts.download {
+		if len(assets) == 0 {
+			fmt.Fprintln(os.Stderr, "Error: no assets to download")
+			return 1
+		}
+		return downloadJSON(out, assets)
+	}
+
+	if listing == nil {
+		matched := jsonAssetListing{Repository: repository, Assets: []jsonAsset{}}
+		for _, asset := range assets {
+			matched.Assets = append(matched.Assets, newJSONAsset(asset))
+		}
+		listing = matched
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(listing); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// eventWriter writes download events as newline-delimited JSON
+type eventWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *eventWriter) emit(event downloadEvent) {
+	event.Time = time.Now().UTC().Format(time.RFC3339Nano)
+	_ = w.encoder.Encode(event)
+}
+
+// downloadJSON downloads assets one after another, streaming queued,
+// progress, verified, unchanged, failed and finished events to out
+func downloadJSON(out io.Writer, assets []AssetInfo) int {
+	events := &eventWriter{encoder: json.NewEncoder(out)}
+	for _, asset := range assets {
+		ja := newJSONAsset(asset)
+		events.emit(downloadEvent{Event: "queued", Asset: &ja})
+	}
+
+	// An interrupt stops the running download and the rest of the batch
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	succeeded, failed := 0, 0
+	for _, asset := range assets {
+		if ctx.Err() != nil {
+			events.emit(downloadEvent{Event: "failed", Name: asset.Name, Error: "Download cancelled by user"})
+			failed++
+			continue
+		}
+		var reported time.Time
+		msg := downloadAsset(ctx, asset, 0, "", func(downloaded, total int64) {
+			if now := time.Now(); now.Sub(reported) >= jsonProgressInterval || (total > 0 && downloaded >= total) {
+				reported = now
+				events.emit(downloadEvent{Event: "progress", Name: asset.Name, Downloaded: downloaded, Total: total})
+			}
+		})()
+
+		switch msg := msg.(type) {
+		case checksumVerifiedMsg:
+			events.emit(downloadEvent{
+				Event:     "verified",
+				Name:      asset.Name,
+				File:      msg.filename,
+				Signature: msg.signature,
+				HookError: msg.hookErr,
+			})
+			if msg.hookErr != "" {
+				failed++
+			} else {
+				succeeded++
+			}
+		case downloadUpToDateMsg:
+			events.emit(downloadEvent{Event: "unchanged", Name: asset.Name, File: msg.filename})
+			succeeded++
+		case signatureFailedMsg:
+			events.emit(downloadEvent{Event: "failed", Name: asset.Name, Signature: msg.signature, Error: msg.err})
+			failed++
+		case downloadErrorMsg:
+			events.emit(downloadEvent{Event: "failed", Name: asset.Name, Error: string(msg)})
+			failed++
+		case downloadCancelledMsg:
+			events.emit(downloadEvent{Event: "failed", Name: asset.Name, Error: "Download cancelled by user"})
+			failed++
+		}
+	}
+
+	events.emit(downloadEvent{Event: "finished", Succeeded: &succeeded, Failed: &failed})
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Native-Robotics/asset-fetch/internal/githubtest"
+)
+
+func TestExtractJSONFlags(t *testing.T) {
+	args, opts, err := extractJSONFlags([]string{"owner/repo", "--mask", "*.deb", "--json", "--download"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(args) != 1 || args[0] != "owner/repo" || opts != (jsonOptions{enabled: true, download: true, mask: "*.deb"}) {
+		t.Errorf("got %v, %+v", args, opts)
+	}
+	for _, args := range [][]string{{"--download"}, {"--json", "--mask"}, {"--json", "--mask", "["}} {
+		if _, _, err := extractJSONFlags(args); err == nil {
+			t.Errorf("%v accepted", args)
+		}
+	}
+}
+
+func TestRunJSONListing(t *testing.T) {
+	server := setupFakeGitHub(t)
+	addTestReleases(server)
+	// The configured mask is for the TUI and does not change the output
+	writeConfig(t, "ASSET_MASK=*windows*")
+	m := model{repoOwner: "owner", repoName: "repo"}
+
+	var out bytes.Buffer
+	if status := runJSON(m, jsonOptions{enabled: true}, &out); status != 0 {
+		t.Fatalf("exit status %d", status)
+	}
+	var releases jsonReleaseListing
+	if err := json.Unmarshal(out.Bytes(), &releases); err != nil {
+		t.Fatal(err)
+	}
+	if releases.Repository != "owner/repo" || len(releases.Releases) != 2 {
+		t.Fatalf("listing = %+v", releases)
+	}
+	latest := releases.Releases[0]
+	if latest.Tag != "v1.1.0" || len(latest.Assets) != 4 {
+		t.Fatalf("latest release = %+v", latest)
+	}
+	linux := latest.Assets[0]
+	if linux.Name != "app_linux_amd64.tar.gz" || linux.Kind != "release-asset" || linux.ReleaseTag != "v1.1.0" ||
+		linux.Size != int64(len("linux build 1.1.0")) || linux.Digest != githubtest.Digest([]byte("linux build 1.1.0")) {
+		t.Errorf("asset = %+v", linux)
+	}
+	if latest.Assets[2].Kind != "source-archive" {
+		t.Errorf("source archive = %+v", latest.Assets[2])
+	}
+
+	// A mask lists the matching assets of every release
+	out.Reset()
+	if status := runJSON(m, jsonOptions{enabled: true, mask: "*linux*"}, &out); status != 0 {
+		t.Fatalf("exit status %d", status)
+	}
+	var assets jsonAssetListing
+	if err := json.Unmarshal(out.Bytes(), &assets); err != nil {
+		t.Fatal(err)
+	}
+	if len(assets.Assets) != 2 || assets.Assets[0].ReleaseTag != "v1.1.0" || assets.Assets[1].ReleaseTag != "v1.0.0" {
+		t.Errorf("masked listing = %+v", assets)
+	}
+
+	if status := runJSON(model{repoOwner: "owner", repoName: "missing"}, jsonOptions{enabled: true}, &out); status != 1 {
+		t.Errorf("missing repository: exit status %d", status)
+	}
+}
+
+func TestRunJSONDownloadEvents(t *testing.T) {
+	server := setupFakeGitHub(t)
+	server.AddRelease("owner/repo", githubtest.Release{
+		Tag: "v2.0.0",
+		Assets: []githubtest.Asset{
+			{Name: "good.bin", Content: []byte("good build")},
+			{Name: "bad.bin", Content: []byte("tampered build"), Digest: githubtest.Digest([]byte("original build"))},
+			{Name: "notes.txt", Content: []byte("release notes")},
+		},
+	})
+
+	var out bytes.Buffer
+	status := runJSON(model{repoOwner: "owner", repoName: "repo"}, jsonOptions{enabled: true, download: true, mask: "*.bin"}, &out)
+	if status != 1 {
+		t.Errorf("exit status %d, want 1 for a failed download", status)
+	}
+
+	var events []downloadEvent
+	progress := make(map[string]int)
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var event downloadEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("%q: %v", line, err)
+		}
+		if event.Time == "" {
+			t.Errorf("event without a time: %s", line)
+		}
+		if event.Event == "progress" {
+			progress[event.Name]++
+			if event.Total != 10 && event.Total != 14 {
+				t.Errorf("progress of %s: total %d", event.Name, event.Total)
+			}
+			continue
+		}
+		events = append(events, event)
+	}
+
+	want := []string{"queued good.bin", "queued bad.bin", "verified good.bin", "failed bad.bin", "finished"}
+	var got []string
+	for _, event := range events {
+		name := event.Name
+		if event.Asset != nil {
+			name = event.Asset.Name
+		}
+		got = append(got, strings.TrimSpace(event.Event+" "+name))
+	}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Fatalf("events:\n got %v\nwant %v", got, want)
+	}
+	if progress["good.bin"] == 0 || progress["bad.bin"] == 0 {
+		t.Errorf("progress events = %v", progress)
+	}
+	if events[2].File != "good.bin" || !strings.Contains(events[3].Error, "Checksum verification failed") {
+		t.Errorf("verified %+v, failed %+v", events[2], events[3])
+	}
+	if finished := events[4]; *finished.Succeeded != 1 || *finished.Failed != 1 {
+		t.Errorf("finished = %+v", finished)
+	}
+}
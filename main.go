@@ -1,8 +1,8 @@
 package main
 
 import (
-	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"net/url"
 	"os"
@@ -20,30 +20,115 @@ var (
 )
 
 func main() {
-	// Create context with cancel function
-	downloadContext, downloadCancel = context.WithCancel(context.Background())
-	defer downloadCancel()
-
 	var repoOwner, repoName, tag string
 	var assetMask *string
 	var startWithReleases bool
+	var browseRepositories bool
+	var searchQuery *string
+	var showRecent bool
+	var showRuns bool
+	var runFilter RunFilter
+	var ociRef *ociReference
+
+	// Apply the configured proxy and TLS settings to all requests; most
+	// commands work without a configuration file
+	config, err := loadConfig()
+	if errors.Is(err, errConfigNotFound) {
+		config = &Config{}
+	} else if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(2)
+	}
+	if err := configureHTTPClient(config); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(2)
+	}
+	for _, warning := range config.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	warnInsecureHosts(config)
+
+	// Serve a caching proxy of the GitHub API for other afetch instances
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		os.Exit(runServe(os.Args[2:], config))
+	}
+
+	// Send API requests to a proxy instead of GitHub
+	if config.GitHubAPIURL != "" {
+		githubAPIURL = config.GitHubAPIURL
+	}
+
+	// Mirror all releases of repositories into a directory tree
+	if len(os.Args) > 1 && os.Args[1] == "mirror" {
+		os.Exit(runMirror(os.Args[2:], config))
+	}
 
-	if len(os.Args) > 1 {
-		arg := os.Args[1]
+	// "--json", "--download" and "--mask" may appear anywhere on the command line
+	args, jsonOpts, err := extractJSONFlags(os.Args[1:])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	if len(args) > 0 {
+		arg := args[0]
 		// Check for version flag
 		if arg == "--version" || arg == "-v" {
 			fmt.Printf("afetch version %s\n", version)
 			os.Exit(0)
 		}
 
+		// Pick from recently used and bookmarked repositories
+		if arg == "--recent" || arg == "-r" {
+			showRecent = true
+		}
+
+		// Search GitHub for repositories, optionally with an initial query
+		if arg == "--search" || arg == "-s" {
+			query := strings.Join(args[1:], " ")
+			searchQuery = &query
+		}
+
+		// List workflow runs of a repository and download their artifacts
+		if arg == "--actions" {
+			owner, name, filter, err := parseActionsArgs(args[1:])
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(2)
+			}
+			repoOwner, repoName, runFilter = owner, name, filter
+			showRuns = true
+		}
+
+		// Fetch files pushed to an OCI registry, e.g. with "oras push"
+		if strings.HasPrefix(arg, "oci://") || strings.HasPrefix(arg, "oci+http://") {
+			ref, err := parseOCIReference(arg)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(2)
+			}
+			ociRef = &ref
+			tag = ref.Reference
+		}
+
 		if strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://") {
 			parsedURL, err := url.Parse(arg)
 			if err == nil && (parsedURL.Host == "github.com" || parsedURL.Host == "www.github.com") {
 				pathParts := strings.Split(strings.Trim(parsedURL.Path, "/"), "/")
-				if len(pathParts) >= 2 {
+				if len(pathParts) == 1 && pathParts[0] != "" {
+					// User or organization URL: browse its repositories
+					repoOwner = pathParts[0]
+					browseRepositories = true
+				} else if len(pathParts) >= 2 {
 					repoOwner = pathParts[0]
 					repoName = pathParts[1]
-					if len(pathParts) > 3 && pathParts[2] == "releases" && pathParts[3] == "tag" {
+					if len(pathParts) > 2 && pathParts[2] == "actions" {
+						// Actions URL, optionally of a single workflow
+						showRuns = true
+						if len(pathParts) > 4 && pathParts[3] == "workflows" {
+							runFilter.Workflow = pathParts[4]
+						}
+					} else if len(pathParts) > 3 && pathParts[2] == "releases" && pathParts[3] == "tag" {
 						tag = pathParts[4]
 						emptyString := ""
 						assetMask = &emptyString
@@ -56,6 +141,37 @@ func main() {
 		}
 	}
 
+	// JSON output covers release and OCI listings only
+	if jsonOpts.enabled && (browseRepositories || searchQuery != nil || showRecent || showRuns) {
+		fmt.Println("Error: --json needs a repository, release or OCI reference")
+		os.Exit(2)
+	}
+
+	// Without a URL, a configured owner without a repository name (or a token
+	// without either) opens the repository browser
+	if repoOwner == "" && !browseRepositories && searchQuery == nil && !showRecent && ociRef == nil && !jsonOpts.enabled {
+		if config.RepoName == "" && (config.RepoOwner != "" || config.GitHubToken != "") {
+			repoOwner = config.RepoOwner
+			browseRepositories = true
+		} else if len(args) == 0 && config.RepoOwner == "" && config.RepoName == "" {
+			// Nothing to start from: offer remembered repositories, if any
+			showRecent = true
+		}
+	}
+
+	// Load remembered repositories; history is best effort
+	var history []RepositoryHistory
+	if state, err := loadState(); err == nil {
+		history = state.sortedRepositories()
+	}
+	if showRecent && len(history) == 0 {
+		if len(args) > 0 {
+			fmt.Println("No recent or bookmarked repositories yet")
+			os.Exit(0)
+		}
+		showRecent = false
+	}
+
 	// Initialize unified model
 	m := model{
 		loading:           true,
@@ -65,18 +181,75 @@ func main() {
 		tag:               tag,
 		assetMask:         assetMask,
 		startWithReleases: startWithReleases,
+		history:           history,
+		ociReference:      ociRef,
+		overwritePolicy:   config.overwritePolicy(),
+	}
+	if jsonOpts.enabled {
+		os.Exit(runJSON(m, jsonOpts, os.Stdout))
+	}
+	if showRecent {
+		m.state = StateRecent
+		m.loading = false
+		m.listView.SetRecentRepositories(history)
+	}
+	if browseRepositories {
+		m.state = StateRepositories
+		m.browseOwner = repoOwner
+		m.repoOwner = ""
+	}
+	if showRuns {
+		m.state = StateRuns
+		m.runFilter = runFilter
+	}
+	if searchQuery != nil {
+		m.state = StateRepositories
+		m.searchQuery = *searchQuery
+		m.querying = *searchQuery == ""
+		m.loading = !m.querying
 	}
 
 	// Run bubbletea
 	p := tea.NewProgram(m)
-	if _, err := p.Run(); err != nil {
+	finalModel, err := p.Run()
+	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Check if context was cancelled
-	if errors.Is(downloadContext.Err(), context.Canceled) {
-		fmt.Println("Download cancelled by user")
-		os.Exit(0)
+	// Failed downloads and post-download hooks are reported in the exit status
+	if final, ok := finalModel.(model); ok && final.downloadFinished && !final.downloadSuccess {
+		os.Exit(1)
+	}
+}
+
+// parseActionsArgs parses "--actions [--workflow W] [--branch B] [--status S] <owner/repo|URL>"
+func parseActionsArgs(args []string) (string, string, RunFilter, error) {
+	var filter RunFilter
+	flags := flag.NewFlagSet("actions", flag.ContinueOnError)
+	flags.StringVar(&filter.Workflow, "workflow", "", "workflow file name or ID")
+	flags.StringVar(&filter.Branch, "branch", "", "branch the runs were triggered on")
+	flags.StringVar(&filter.Status, "status", "", "run status or conclusion, e.g. success")
+	if err := flags.Parse(args); err != nil {
+		return "", "", filter, err
+	}
+	if flags.NArg() != 1 {
+		return "", "", filter, fmt.Errorf("usage: afetch --actions [--workflow W] [--branch B] [--status S] <owner/repo|URL>")
+	}
+
+	owner, name, err := parseRepositoryArg(flags.Arg(0))
+	return owner, name, filter, err
+}
+
+// parseRepositoryArg parses an "owner/repo" or GitHub URL argument
+func parseRepositoryArg(arg string) (string, string, error) {
+	repo := arg
+	if parsedURL, err := url.Parse(repo); err == nil && parsedURL.Host != "" {
+		repo = parsedURL.Path
+	}
+	parts := strings.Split(strings.Trim(repo, "/"), "/")
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid repository %q, expected owner/repo", arg)
 	}
+	return parts[0], parts[1], nil
 }
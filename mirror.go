@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Native-Robotics/asset-fetch/afetch"
+)
+
+// Name of the release metadata file written next to mirrored assets;
+// GitHub renames uploaded assets with a leading period, so no asset can
+// have this name
+const mirrorReleaseFile = ".release.json"
+
+// mirrorOptions holds the arguments of the mirror command
+type mirrorOptions struct {
+	dir   string
+	masks []string
+	repos [][2]string // owner, name
+	json  bool
+}
+
+// stringList is a flag that may be repeated
+type stringList []string
+
+func (l *stringList) String() string     { return strings.Join(*l, ",") }
+func (l *stringList) Set(v string) error { *l = append(*l, v); return nil }
+
+// mirrorStats counts the outcome of mirrored assets
+type mirrorStats struct {
+	downloaded, unchanged, failed int
+}
+
+// parseMirrorArgs parses "mirror [--dir D] [--mask GLOB]... [--json] [owner/repo|URL]..."
+func parseMirrorArgs(args []string) (mirrorOptions, error) {
+	var opts mirrorOptions
+	var masks stringList
+	flags := flag.NewFlagSet("mirror", flag.ContinueOnError)
+	flags.StringVar(&opts.dir, "dir", ".", "directory to mirror into")
+	flags.Var(&masks, "mask", "glob of asset names to mirror; may be repeated")
+	flags.BoolVar(&opts.json, "json", false, "print newline-delimited JSON events")
+	if err := flags.Parse(args); err != nil {
+		return opts, err
+	}
+	for _, mask := range masks {
+		if _, err := path.Match(mask, ""); err != nil {
+			return opts, fmt.Errorf("invalid mask %q: %v", mask, err)
+		}
+	}
+	opts.masks = masks
+	for _, arg := range flags.Args() {
+		owner, name, err := parseRepositoryArg(arg)
+		if err != nil {
+			return opts, err
+		}
+		opts.repos = append(opts.repos, [2]string{owner, name})
+	}
+	return opts, nil
+}
+
+// runMirror copies the release assets of repositories into a directory
+// tree, downloading only new or changed assets. It returns the process
+// exit status.
+func runMirror(args []string, config *Config) int {
+	opts, err := parseMirrorArgs(args)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		fmt.Println("usage: afetch mirror [--dir DIR] [--mask GLOB]... [--json] <owner/repo|URL>...")
+		return 2
+	}
+
+	if len(opts.repos) == 0 && config.RepoOwner != "" && config.RepoName != "" {
+		opts.repos = [][2]string{{config.RepoOwner, config.RepoName}}
+	}
+	if len(opts.repos) == 0 {
+		fmt.Println("Error: no repository to mirror")
+		return 2
+	}
+	if len(opts.masks) == 0 && config.AssetMask != "" {
+		opts.masks = []string{config.AssetMask}
+	}
+
+	var events *eventWriter
+	if opts.json {
+		events = &eventWriter{encoder: json.NewEncoder(os.Stdout)}
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var total mirrorStats
+	for _, repo := range opts.repos {
+		stats, err := mirrorRepository(ctx, repo[0], repo[1], opts, config, events)
+		total.downloaded += stats.downloaded
+		total.unchanged += stats.unchanged
+		total.failed += stats.failed
+		if err != nil {
+			total.failed++
+			if events != nil {
+				events.emit(downloadEvent{Event: "failed", Name: repo[0] + "/" + repo[1], Error: err.Error()})
+			} else {
+				fmt.Printf("%s/%s: %v\n", repo[0], repo[1], err)
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if events != nil {
+		succeeded := total.downloaded + total.unchanged
+		events.emit(downloadEvent{Event: "finished", Succeeded: &succeeded, Failed: &total.failed})
+	} else {
+		fmt.Printf("Mirrored %d new or changed, %d unchanged, %d failed\n", total.downloaded, total.unchanged, total.failed)
+	}
+	if total.failed > 0 || ctx.Err() != nil {
+		return 1
+	}
+	return 0
+}
+
+// mirrorRepository mirrors every release of owner/name into
+// <dir>/<owner>/<name>/<tag>/
+func mirrorRepository(ctx context.Context, owner, name string, opts mirrorOptions, config *Config, events *eventWriter) (mirrorStats, error) {
+	var stats mirrorStats
+	releases, err := newGitHubClient(config).ListReleases(ctx, owner, name)
+	if err != nil {
+		return stats, err
+	}
+	if events == nil {
+		fmt.Printf("%s/%s: %d releases\n", owner, name, len(releases))
+	}
+
+	formatter := AssetFormatter{}
+	for _, release := range releases {
+		tagDir := filepath.Join(opts.dir, owner, name, mirrorTagDir(release.TagName))
+		if err := os.MkdirAll(tagDir, 0755); err != nil {
+			return stats, err
+		}
+		previous := readMirroredRelease(tagDir)
+
+		complete := true
+		for _, asset := range release.Assets {
+			if !matchesAnyMask(asset.Name, opts.masks) {
+				continue
+			}
+			assetInfo := formatter.FormatAssetInfo(asset, release)
+			assetInfo.Repository = owner + "/" + name
+			assetInfo.FileName = filepath.Join(tagDir, asset.Name)
+			label := release.TagName + "/" + asset.Name
+
+			if mirroredAssetUnchanged(assetInfo, previous[asset.Name]) {
+				stats.unchanged++
+				if events != nil {
+					events.emit(downloadEvent{Event: "unchanged", Name: assetInfo.Name, File: assetInfo.FileName})
+				}
+				continue
+			}
+
+			msg := downloadAsset(ctx, assetInfo, 0, PolicyOverwrite, nil)()
+			switch msg := msg.(type) {
+			case checksumVerifiedMsg:
+				stats.downloaded++
+				if msg.hookErr != "" {
+					stats.failed++
+				}
+				if events != nil {
+					ja := newJSONAsset(assetInfo)
+					events.emit(downloadEvent{Event: "verified", Asset: &ja, File: msg.filename, Signature: msg.signature, HookError: msg.hookErr})
+				} else if msg.hookErr != "" {
+					fmt.Printf("  %s: downloaded, %s\n", label, msg.hookErr)
+				} else {
+					fmt.Printf("  %s: downloaded %s\n", label, formatSize(assetInfo.Size))
+				}
+			case downloadCancelledMsg:
+				return stats, fmt.Errorf("mirror cancelled by user")
+			default:
+				stats.failed++
+				complete = false
+				reason := fmt.Sprint(msg)
+				switch msg := msg.(type) {
+				case downloadErrorMsg:
+					reason = string(msg)
+				case signatureFailedMsg:
+					reason = msg.err
+				}
+				if events != nil {
+					events.emit(downloadEvent{Event: "failed", Name: assetInfo.Name, Error: reason})
+				} else {
+					fmt.Printf("  %s: %s\n", label, reason)
+				}
+			}
+		}
+
+		// The metadata vouches for the files on disk, so it is only updated
+		// once every asset of the release is current
+		if !complete {
+			continue
+		}
+		if err := writeMirroredRelease(tagDir, release.Raw); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// mirrorTagDir returns the directory name for a tag, which may contain slashes
+func mirrorTagDir(tag string) string {
+	return strings.NewReplacer("/", "_", "\\", "_").Replace(tag)
+}
+
+// matchesAnyMask reports whether name matches one of masks; no masks match all
+func matchesAnyMask(name string, masks []string) bool {
+	if len(masks) == 0 {
+		return true
+	}
+	for _, mask := range masks {
+		if matched, err := afetch.MatchMask(mask, name); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// readMirroredRelease returns the assets recorded by the previous mirror
+// run in tagDir, by name
+func readMirroredRelease(tagDir string) map[string]Asset {
+	assets := make(map[string]Asset)
+	content, err := os.ReadFile(filepath.Join(tagDir, mirrorReleaseFile))
+	if err != nil {
+		return assets
+	}
+	var release Release
+	if err := json.Unmarshal(content, &release); err != nil {
+		return assets
+	}
+	for _, asset := range release.Assets {
+		assets[asset.Name] = asset
+	}
+	return assets
+}
+
+// writeMirroredRelease saves the release metadata next to its assets
+func writeMirroredRelease(tagDir string, raw json.RawMessage) error {
+	var indented strings.Builder
+	var release interface{}
+	if err := json.Unmarshal(raw, &release); err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(&indented)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(release); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(tagDir, mirrorReleaseFile), []byte(indented.String()), 0644)
+}
+
+// mirroredAssetUnchanged reports whether the local copy of asset is current.
+// A file of the right size is compared with the digest recorded by the
+// previous run, and hashed only when that run recorded none or another one;
+// assets without a digest are compared by ID and update time.
+func mirroredAssetUnchanged(asset AssetInfo, previous Asset) bool {
+	info, err := os.Stat(asset.FileName)
+	if err != nil || info.IsDir() || info.Size() != asset.Size {
+		return false
+	}
+	if asset.Digest != "" {
+		if previous.Digest == asset.Digest {
+			return true
+		}
+		return afetch.VerifyDigest(asset.FileName, asset.Digest) == nil
+	}
+	return previous.ID == asset.ID && previous.UpdatedAt == asset.UpdatedAt
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Native-Robotics/asset-fetch/internal/githubtest"
+)
+
+func TestMirrorRepository(t *testing.T) {
+	server := setupFakeGitHub(t)
+	addTestReleases(server)
+	server.AddRelease("owner/repo", githubtest.Release{
+		Tag: "v0.9.0",
+		Assets: []githubtest.Asset{
+			{Name: "release.json", Content: []byte(`{"channel": "stable"}`)},
+			{Name: "notes.txt", Content: []byte("release notes"), Digest: "-"},
+		},
+	})
+	dir := t.TempDir()
+	opts := mirrorOptions{dir: dir}
+	mirror := func() mirrorStats {
+		t.Helper()
+		var events bytes.Buffer
+		stats, err := mirrorRepository(context.Background(), "owner", "repo", opts, &Config{}, &eventWriter{encoder: json.NewEncoder(&events)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return stats
+	}
+	repoDir := filepath.Join(dir, "owner", "repo")
+	linux := filepath.Join(repoDir, "v1.1.0", "app_linux_amd64.tar.gz")
+
+	if stats := mirror(); stats != (mirrorStats{downloaded: 5}) {
+		t.Fatalf("first run: %+v", stats)
+	}
+	if content, _ := os.ReadFile(filepath.Join(repoDir, "v0.9.0", "release.json")); string(content) != `{"channel": "stable"}` {
+		t.Errorf("asset named release.json = %q", content)
+	}
+	if recorded := readMirroredRelease(filepath.Join(repoDir, "v0.9.0")); len(recorded) != 2 {
+		t.Errorf("recorded assets = %v", recorded)
+	}
+
+	// Unchanged files are not downloaded again, and files with a recorded
+	// digest are not even hashed
+	downloads := storageRequests(server)
+	if err := os.WriteFile(linux, []byte("LINUX BUILD 1.1.0"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if stats := mirror(); stats != (mirrorStats{unchanged: 5}) {
+		t.Errorf("second run: %+v", stats)
+	}
+	if storageRequests(server) != downloads {
+		t.Error("unchanged assets downloaded again")
+	}
+
+	// A file of another size is replaced
+	if err := os.WriteFile(linux, []byte("truncated"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if stats := mirror(); stats != (mirrorStats{downloaded: 1, unchanged: 4}) {
+		t.Errorf("after truncating a file: %+v", stats)
+	}
+	if content, _ := os.ReadFile(linux); string(content) != "linux build 1.1.0" {
+		t.Errorf("replaced file = %q", content)
+	}
+
+	// Without recorded metadata, files are hashed and metadata is only
+	// written once every asset of the release was mirrored
+	tagDir := filepath.Join(repoDir, "v1.0.0")
+	if err := os.Remove(filepath.Join(tagDir, mirrorReleaseFile)); err != nil {
+		t.Fatal(err)
+	}
+	assetURL, _ := url.Parse(server.AssetURL("owner/repo", "v1.1.0", "app_windows_amd64.zip"))
+	server.Fail(assetURL.Path, http.StatusNotFound)
+	if err := os.Remove(filepath.Join(repoDir, "v1.1.0", "app_windows_amd64.zip")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(repoDir, "v1.1.0", mirrorReleaseFile)); err != nil {
+		t.Fatal(err)
+	}
+	if stats := mirror(); stats != (mirrorStats{unchanged: 4, failed: 1}) {
+		t.Errorf("with a failing asset: %+v", stats)
+	}
+	if _, err := os.Stat(filepath.Join(tagDir, mirrorReleaseFile)); err != nil {
+		t.Errorf("metadata of a complete release: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repoDir, "v1.1.0", mirrorReleaseFile)); !os.IsNotExist(err) {
+		t.Error("metadata written for a release with a failed asset")
+	}
+}
+
+func TestMirrorMasks(t *testing.T) {
+	server := setupFakeGitHub(t)
+	addTestReleases(server)
+	opts, err := parseMirrorArgs([]string{"--dir", t.TempDir(), "--mask", "*linux*", "owner/repo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err := mirrorRepository(context.Background(), "owner", "repo", opts, &Config{}, &eventWriter{encoder: json.NewEncoder(&bytes.Buffer{})})
+	if err != nil || stats != (mirrorStats{downloaded: 2}) {
+		t.Fatalf("got %+v, %v", stats, err)
+	}
+	if _, err := os.Stat(filepath.Join(opts.dir, "owner", "repo", "v1.1.0", "app_windows_amd64.zip")); !os.IsNotExist(err) {
+		t.Error("asset outside the mask mirrored")
+	}
+
+	if _, err := parseMirrorArgs([]string{"--mask", "[", "owner/repo"}); err == nil {
+		t.Error("invalid mask accepted")
+	}
+}
@@ -1,11 +1,16 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/Native-Robotics/asset-fetch/afetch"
 )
 
 // Model structure for bubbletea - simplified unified version
@@ -19,12 +24,25 @@ type model struct {
 	// Unified list view
 	listView UnifiedListView
 
+	// Terminal dimensions from the last tea.WindowSizeMsg
+	width  int
+	height int
+
 	// Download queue (always used, even for single downloads)
-	downloadQueue    DownloadQueue
-	downloading      bool
-	downloadFinished bool
-	downloadSuccess  bool
-	downloadResult   string
+	downloadQueue     DownloadQueue
+	downloading       bool
+	downloadFinished  bool
+	downloadSuccess   bool
+	downloadResult    string
+	hookFailures      []string // post-download hook errors of the current batch
+	progressEvents    chan downloadProgressMsg
+	downloadCancel    context.CancelCauseFunc // stops the running download
+	batchCancelled    bool                    // the running batch is being cancelled
+	pausing           bool                    // pause requested, effective once the running download stops
+	paused            bool                    // the batch is paused
+	overwriteConflict bool                    // waiting for the user's choice about an existing file
+	batchPolicy       OverwritePolicy         // choice made for all remaining existing files
+	overwritePolicy   OverwritePolicy         // configured OVERWRITE_POLICY, resolved at startup
 
 	// Helper components
 	assetFormatter    AssetFormatter
@@ -34,39 +52,130 @@ type model struct {
 	releases         []Release
 	fromReleasesView bool // true when user navigated from releases list
 
+	// Repository browser
+	browseOwner          string // user or organization to list; "" = authenticated user
+	repositories         []Repository
+	releaseChecks        <-chan repositoryReleasesMsg // results of checking repositories for releases
+	fromRepositoriesView bool                         // true when user navigated from repositories list
+
+	// Recently used and bookmarked repositories
+	history        []RepositoryHistory
+	fromRecentView bool // true when user navigated from recent repositories list
+
+	// GitHub Actions workflow runs and artifacts
+	runFilter        RunFilter
+	runs             []WorkflowRun
+	fromRunsView     bool // true when artifacts were opened from the workflow runs list
+	runsFromReleases bool // true when workflow runs were opened from the releases list
+
+	// GitHub repository search prompt
+	querying          bool
+	searchQuery       string
+	queryStatus       string
+	repositoriesTitle string // list title for search results; "" for browsing
+
 	// URL-based execution
 	repoOwner         string
 	repoName          string
 	tag               string
 	assetMask         *string
 	startWithReleases bool
+
+	// Artifact in an OCI registry, listed instead of GitHub releases
+	ociReference *ociReference
 }
 
 // Init bubbletea initialization
 func (m model) Init() tea.Cmd {
+	if m.state == StateRecent {
+		return nil
+	}
+	if m.state == StateRuns {
+		return fetchWorkflowRuns(m.repoOwner, m.repoName, m.runFilter)
+	}
+	if m.state == StateRepositories {
+		if m.querying {
+			return nil
+		}
+		if m.searchQuery != "" {
+			return searchRepositories(m.searchQuery)
+		}
+		return fetchRepositories(m.browseOwner)
+	}
+	if m.ociReference != nil {
+		return fetchOCIAssets(*m.ociReference)
+	}
 	return fetchReleases(m)
 }
 
 // Update bubbletea message processing - unified version
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.listView.SetSize(msg.Width, msg.Height)
+		return m, nil
+
 	case tea.KeyMsg:
-		switch msg.String() {
+		key := msg.String()
+		listState := m.isListState()
+		if key == "q" && (m.querying || (m.listView.InputActive() && listState)) {
+			// 'q' is typed text while a prompt is open
+			key = ""
+		}
+		switch key {
 		case "ctrl+c", "q":
 			if m.downloading {
-				// Cancel download
-				if downloadCancel != nil {
-					downloadCancel()
+				// Cancel the batch once the running download has stopped
+				m.batchCancelled = true
+				if m.paused || m.overwriteConflict {
+					m.removePartialDownload()
+					return m.cancelDownloads()
 				}
-				return m, func() tea.Msg {
-					return cancelDownloadMsg{}
+				if m.downloadCancel != nil {
+					m.downloadCancel(nil)
 				}
+				return m, nil
+			} else if m.state == StateAssets && m.fromRunsView {
+				// Go back to workflow runs list
+				m.listView.SetWorkflowRuns(m.runs)
+				m.listView.title = m.runsTitle()
+				m.state = StateRuns
+				m.fromRunsView = false
+				m.loading = false
+				m.errorMsg = ""
+				return m, nil
+			} else if m.state == StateRuns && m.runsFromReleases {
+				// Go back to releases list
+				m.listView.SetReleases(m.releases)
+				m.state = StateReleases
+				m.runsFromReleases = false
+				m.loading = false
+				m.errorMsg = ""
+				return m, nil
 			} else if m.state == StateAssets && m.fromReleasesView {
 				// Go back to releases list
 				m.listView.SetReleases(m.releases)
 				m.state = StateReleases
 				m.fromReleasesView = false
 				return m, nil
+			} else if m.state == StateReleases && m.fromRepositoriesView {
+				// Go back to repositories list
+				m.showRepositories()
+				m.state = StateRepositories
+				m.fromRepositoriesView = false
+				m.loading = false
+				m.errorMsg = ""
+				return m, nil
+			} else if m.state == StateReleases && m.fromRecentView {
+				// Go back to recent repositories list
+				m.listView.SetRecentRepositories(m.history)
+				m.state = StateRecent
+				m.fromRecentView = false
+				m.loading = false
+				m.errorMsg = ""
+				return m, nil
 			} else {
 				m.quitting = true
 				return m, tea.Quit
@@ -75,16 +184,32 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		// Handle state-specific navigation and actions
 		switch m.state {
+		case StateRecent:
+			return m.handleRecentInput(msg.String())
+		case StateRepositories:
+			if m.querying {
+				return m.handleQueryInput(msg.String())
+			}
+			return m.handleRepositoriesInput(msg.String())
 		case StateReleases:
 			return m.handleReleasesInput(msg.String())
+		case StateRuns:
+			return m.handleRunsInput(msg.String())
 		case StateAssets:
 			return m.handleAssetsInput(msg.String())
-		case StateDownloading, StateFinished:
-			// No input handling during download states
-			return m, nil
+		case StateDownloading:
+			return m.handleDownloadingInput(msg.String())
+		case StateFinished:
+			return m.handleFinishedInput(msg.String())
 		}
 
 	case releasesMsg:
+		if m.state != StateReleases {
+			// User went back to another list before releases arrived
+			return m, nil
+		}
+		m.repoOwner = msg.owner
+		m.repoName = msg.name
 		// If a specific tag was requested, go directly to assets
 		if m.tag != "" {
 			m.listView.SetAssets(msg.assets)
@@ -101,118 +226,237 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.releases = msg.releases
 			m.state = StateReleases
 			m.loading = false
+			if entry := findHistory(m.history, m.repoOwner, m.repoName); entry != nil && entry.LastTag != "" {
+				m.listView.SelectReleaseTag(entry.LastTag)
+			}
 		}
+		return m, rememberRepository(m.repoOwner, m.repoName, func(*RepositoryHistory) {})
 
-	case errorMsg:
-		m.errorMsg = string(msg)
+	case historyMsg:
+		m.history = msg
+		m.listView.UpdateRecentRepositories(m.history)
+
+	case repositoriesMsg:
+		m.repositories = msg
+		m.repositoriesTitle = ""
+		m.showRepositories()
 		m.loading = false
+		m.releaseChecks = checkRepositoryReleases(m.repositories)
+		return m, waitForRepositoryReleases(m.releaseChecks)
 
-	case startDownloadProgressMsg:
-		// Start download progress updates
-		m.downloading = true
-		m.state = StateDownloading
-		return m, tea.Tick(time.Second, func(tick time.Time) tea.Msg {
-			if !m.downloadQueue.IsEmpty() {
-				return updateDownloadProgressMsg{asset: msg.asset}
-			}
-			return nil
-		})
+	case searchResultsMsg:
+		m.loading = false
+		switch {
+		case msg.err != nil:
+			m.querying = true
+			m.queryStatus = msg.err.Error()
+		case len(msg.repos) == 0:
+			m.querying = true
+			m.queryStatus = fmt.Sprintf("No repositories match %q", msg.query)
+		default:
+			m.querying = false
+			m.queryStatus = ""
+			m.repositories = msg.repos
+			m.repositoriesTitle = fmt.Sprintf("Search results for %q (%d of %d):", msg.query, len(msg.repos), msg.total)
+			m.showRepositories()
+			m.releaseChecks = checkRepositoryReleases(m.repositories)
+			return m, waitForRepositoryReleases(m.releaseChecks)
+		}
+
+	case repositoryReleasesMsg:
+		// Results of an earlier listing are dropped
+		if msg.checks != m.releaseChecks {
+			return m, nil
+		}
+		applyRepositoryReleases(m.repositories, msg)
+		m.listView.UpdateRepositories(m.repositories)
+		return m, waitForRepositoryReleases(m.releaseChecks)
 
-	case updateDownloadProgressMsg:
-		// Update download progress
-		downloadProgressMutex.Lock()
-		progress := downloadProgress
-		downloadProgressMutex.Unlock()
+	case workflowRunsMsg:
+		m.runs = msg
+		m.listView.SetWorkflowRuns(m.runs)
+		m.listView.title = m.runsTitle()
+		m.state = StateRuns
+		m.loading = false
 
-		// Update download queue progress
-		m.downloadQueue.UpdateProgress(progress, msg.asset.Size)
+	case artifactsMsg:
+		if m.state != StateRuns {
+			return m, nil
+		}
+		m.listView.SetAssets(msg.assets)
+		m.listView.title = fmt.Sprintf("Select artifacts of %s to download (press space to select, enter to download):", msg.run.Label())
+		switch {
+		case len(msg.assets) == 0 && msg.expired > 0:
+			m.listView.status = fmt.Sprintf("All %d artifact(s) of this run have expired", msg.expired)
+		case len(msg.assets) == 0:
+			m.listView.status = "This run has no artifacts"
+		case msg.expired > 0:
+			m.listView.status = fmt.Sprintf("%d expired artifact(s) hidden", msg.expired)
+		}
+		m.state = StateAssets
+		m.fromRunsView = true
+		m.loading = false
 
-		return m, tea.Tick(time.Second, func(tick time.Time) tea.Msg {
-			if m.downloading {
-				return updateDownloadProgressMsg{asset: msg.asset}
-			}
-			return nil
-		})
+	case errorMsg:
+		m.loading = false
+		if m.isListState() && len(m.listView.items) > 0 {
+			// Keep the list usable and report the failure below it
+			m.listView.status = "Error: " + string(msg)
+		} else {
+			m.errorMsg = string(msg)
+		}
+
+	case clipboardMsg:
+		if msg.err != nil {
+			m.listView.status = fmt.Sprintf("Copy failed: %v", msg.err)
+		} else {
+			m.listView.status = fmt.Sprintf("Copied %s to clipboard", msg.what)
+		}
+
+	case downloadProgressMsg:
+		m.downloadQueue.UpdateProgress(msg.index, msg.downloaded, msg.total, time.Now())
+		return m, waitForProgress(m.progressEvents)
+
+	case progressTickMsg:
+		if m.state != StateDownloading {
+			return m, nil
+		}
+		return m, progressTick()
 
 	case downloadErrorMsg:
 		m.downloading = false
+		if m.batchCancelled {
+			return m.cancelDownloads()
+		}
 
-		// Move to next download in queue
-		if m.downloadQueue.NextDownload() {
-			// Start next download
-			asset := m.downloadQueue.GetCurrent()
-			return m, tea.Batch(
-				func() tea.Msg {
-					return startDownloadProgressMsg{asset: *asset}
-				},
-				downloadAsset(*asset),
-			)
-		} else {
-			// All downloads completed (with errors)
-			m.downloadFinished = true
-			m.downloadSuccess = false
-			m.downloadResult = "Downloads completed with errors"
-			m.state = StateFinished
-			// Exit after showing results
-			return m, tea.Quit
+		// Record why the row failed and move to next download in queue
+		m.downloadQueue.FailCurrent(string(msg))
+		return m.nextDownload()
+
+	case signatureFailedMsg:
+		// The file was removed; record why and continue like any failed download
+		m.downloadQueue.SetSignatureStatus(msg.signature)
+		return m.Update(downloadErrorMsg(msg.err))
+
+	case downloadCancelledMsg:
+		m.downloading = false
+		if m.batchCancelled {
+			return m.cancelDownloads()
 		}
 
-	case cancelDownloadMsg:
+		// The current item was skipped; carry on with the rest
+		return m.skipCurrentDownload()
+
+	case downloadUpToDateMsg:
 		m.downloading = false
-		m.errorMsg = "Download cancelled by user"
-		m.state = StateAssets
+		m.downloadQueue.MarkCurrentUpToDate()
+		if m.batchCancelled {
+			return m.cancelDownloads()
+		}
+		return m.nextDownload()
+
+	case downloadPausedMsg:
+		m.releaseDownload()
+		if m.batchCancelled {
+			m.removePartialDownload()
+			return m.cancelDownloads()
+		}
+		m.pausing = false
+		m.paused = true
+		m.downloadQueue.PauseCurrent(msg.downloaded)
 
 	case checksumVerifiedMsg:
 		m.downloading = false
 
-		// Get actual file size from filesystem for completed download
+		// Get actual file size from filesystem for completed download;
+		// extracted artifacts are directories and keep the reported size
 		var actualSize int64
-		if fileInfo, err := os.Stat(msg.filename); err == nil {
+		if fileInfo, err := os.Stat(msg.filename); err == nil && !fileInfo.IsDir() {
 			actualSize = fileInfo.Size()
 		}
 
 		// Mark current download as completed with actual file size
 		m.downloadQueue.CompleteCurrentDownload(actualSize)
+		m.downloadQueue.SetSignatureStatus(msg.signature)
+		if msg.hookErr != "" {
+			m.hookFailures = append(m.hookFailures, fmt.Sprintf("%s: %s", msg.filename, msg.hookErr))
+		}
+		if m.batchCancelled {
+			return m.cancelDownloads()
+		}
 
 		// Handle checksum verification result
-		if msg.success {
-			// Check if there are more downloads in the queue
-			if m.downloadQueue.NextDownload() {
-				// Start next download
-				asset := m.downloadQueue.GetCurrent()
-				return m, tea.Batch(
-					func() tea.Msg {
-						return startDownloadProgressMsg{asset: *asset}
-					},
-					downloadAsset(*asset),
-				)
-			} else {
-				// All downloads completed
-				m.downloadFinished = true
-				m.downloadSuccess = true
-				m.downloadResult = "All files downloaded and verified successfully"
-				m.state = StateFinished
-				// Exit after showing results
-				return m, tea.Quit
+		if !msg.success {
+			m.downloadQueue.FailCurrent(fmt.Sprintf("Checksum verification failed for %s: %s", msg.filename, msg.err))
+		}
+		return m.nextDownload()
+	}
+
+	return m, nil
+}
+
+// Handle input when in recent repositories state
+func (m model) handleRecentInput(key string) (tea.Model, tea.Cmd) {
+	if m.listView.searchActive {
+		switch key {
+		case "esc":
+			m.listView.searchActive = false
+			m.listView.SetFilter("")
+		case "enter":
+			m.listView.searchActive = false
+			if entry := m.listView.GetCurrentRecent(); entry != nil {
+				return m.selectRecent(entry)
+			}
+		case "backspace":
+			m.listView.BackspaceFilter()
+		default:
+			if !m.listView.HandleNavigationKey(key) && len(key) == 1 {
+				m.listView.AddToFilter(key)
 			}
-		} else {
-			// Checksum verification failed
-			m.downloadFinished = true
-			m.downloadSuccess = false
-			m.downloadResult = fmt.Sprintf("Checksum verification failed for %s: %s", msg.filename, msg.err)
-			m.state = StateFinished
-			// Exit after showing results
-			return m, tea.Quit
+		}
+		return m, nil
+	}
+
+	// Nav mode
+	if m.listView.HandleNavigationKey(key) || m.listView.HandleSortKey(key) {
+		return m, nil
+	}
+	switch key {
+	case "/":
+		m.listView.ActivateSearch()
+	case "esc":
+		if m.listView.filter != "" {
+			m.listView.SetFilter("")
+		}
+	case "b":
+		if entry := m.listView.GetCurrentRecent(); entry != nil {
+			return m, m.toggleBookmark(entry.Owner, entry.Name)
+		}
+	case "enter", " ":
+		if entry := m.listView.GetCurrentRecent(); entry != nil {
+			return m.selectRecent(entry)
 		}
 	}
 
 	return m, nil
 }
 
-// Handle input when in releases state
-func (m model) handleReleasesInput(key string) (tea.Model, tea.Cmd) {
-	maxItems := len(m.listView.filteredItems)
+// selectRecent switches to the releases of a remembered repository
+func (m model) selectRecent(entry *RepositoryHistory) (tea.Model, tea.Cmd) {
+	m.repoOwner = entry.Owner
+	m.repoName = entry.Name
+	m.tag = ""
+	m.startWithReleases = true
+	m.fromRecentView = true
+	m.listView.SetReleases(nil)
+	m.state = StateReleases
+	m.loading = true
+	m.errorMsg = ""
+	return m, fetchReleases(m)
+}
 
+// Handle input when in repositories state
+func (m model) handleRepositoriesInput(key string) (tea.Model, tea.Cmd) {
 	if m.listView.searchActive {
 		switch key {
 		case "esc":
@@ -220,21 +464,115 @@ func (m model) handleReleasesInput(key string) (tea.Model, tea.Cmd) {
 			m.listView.SetFilter("")
 		case "enter":
 			m.listView.searchActive = false
-			if selectedRelease := m.listView.GetCurrentRelease(); selectedRelease != nil {
-				m.selectRelease(selectedRelease)
+			if repo := m.listView.GetCurrentRepository(); repo != nil {
+				return m.selectRepository(repo)
 			}
-		case "up":
-			if m.listView.cursor > 0 {
-				m.listView.cursor--
+		case "backspace":
+			m.listView.BackspaceFilter()
+		default:
+			if !m.listView.HandleNavigationKey(key) && len(key) == 1 {
+				m.listView.AddToFilter(key)
 			}
-		case "down":
-			if m.listView.cursor < maxItems-1 {
-				m.listView.cursor++
+		}
+		return m, nil
+	}
+
+	// Nav mode
+	if m.listView.HandleNavigationKey(key) || m.listView.HandleSortKey(key) {
+		return m, nil
+	}
+	switch key {
+	case "/":
+		m.listView.ActivateSearch()
+	case "esc":
+		if m.listView.filter != "" {
+			m.listView.SetFilter("")
+		}
+	case "S":
+		m.querying = true
+		m.queryStatus = ""
+	case "b":
+		if repo := m.listView.GetCurrentRepository(); repo != nil {
+			return m, m.toggleBookmark(repo.Owner.Login, repo.Name)
+		}
+	case "enter", " ":
+		if repo := m.listView.GetCurrentRepository(); repo != nil {
+			return m.selectRepository(repo)
+		}
+	}
+
+	return m, nil
+}
+
+// Handle input while typing a GitHub repository search query
+func (m model) handleQueryInput(key string) (tea.Model, tea.Cmd) {
+	switch key {
+	case "esc":
+		if len(m.listView.items) == 0 {
+			m.quitting = true
+			return m, tea.Quit
+		}
+		m.querying = false
+		m.queryStatus = ""
+	case "enter":
+		if m.searchQuery == "" {
+			return m, nil
+		}
+		m.querying = false
+		m.queryStatus = ""
+		m.loading = true
+		return m, searchRepositories(m.searchQuery)
+	case "backspace":
+		if runes := []rune(m.searchQuery); len(runes) > 0 {
+			m.searchQuery = string(runes[:len(runes)-1])
+		}
+	default:
+		if len([]rune(key)) == 1 {
+			m.searchQuery += key
+		}
+	}
+	return m, nil
+}
+
+// showRepositories fills the list view with the current repositories
+func (m *model) showRepositories() {
+	m.listView.SetRepositories(m.repositories)
+	if m.repositoriesTitle != "" {
+		m.listView.title = m.repositoriesTitle
+	}
+}
+
+// selectRepository switches to the releases of repo
+func (m model) selectRepository(repo *Repository) (tea.Model, tea.Cmd) {
+	m.repoOwner = repo.Owner.Login
+	m.repoName = repo.Name
+	m.tag = ""
+	m.startWithReleases = true
+	m.fromRepositoriesView = true
+	m.listView.SetReleases(nil)
+	m.state = StateReleases
+	m.loading = true
+	m.errorMsg = ""
+	return m, fetchReleases(m)
+}
+
+// Handle input when in workflow runs state
+func (m model) handleRunsInput(key string) (tea.Model, tea.Cmd) {
+	if m.listView.searchActive {
+		switch key {
+		case "esc":
+			m.listView.searchActive = false
+			m.listView.SetFilter("")
+		case "enter":
+			m.listView.searchActive = false
+			if run := m.listView.GetCurrentRun(); run != nil {
+				m.loading = true
+				return m, fetchRunArtifacts(m.repoOwner, m.repoName, *run)
 			}
 		case "backspace":
 			m.listView.BackspaceFilter()
 		default:
-			if len(key) == 1 {
+			if !m.listView.HandleNavigationKey(key) && len(key) == 1 {
 				m.listView.AddToFilter(key)
 			}
 		}
@@ -242,46 +580,154 @@ func (m model) handleReleasesInput(key string) (tea.Model, tea.Cmd) {
 	}
 
 	// Nav mode
+	if m.listView.HandleNavigationKey(key) || m.listView.HandleSortKey(key) {
+		return m, nil
+	}
 	switch key {
 	case "/":
 		m.listView.ActivateSearch()
-	case "up", "k":
-		if m.listView.cursor > 0 {
-			m.listView.cursor--
+	case "esc":
+		if m.listView.filter != "" {
+			m.listView.SetFilter("")
+		}
+	case "enter", " ":
+		if run := m.listView.GetCurrentRun(); run != nil {
+			m.loading = true
+			return m, fetchRunArtifacts(m.repoOwner, m.repoName, *run)
 		}
-	case "down", "j":
-		if m.listView.cursor < maxItems-1 {
-			m.listView.cursor++
+	}
+
+	return m, nil
+}
+
+// runsTitle describes the listed workflow runs and active filters
+func (m model) runsTitle() string {
+	var filters []string
+	if m.runFilter.Workflow != "" {
+		filters = append(filters, "workflow "+m.runFilter.Workflow)
+	}
+	if m.runFilter.Branch != "" {
+		filters = append(filters, "branch "+m.runFilter.Branch)
+	}
+	if m.runFilter.Status != "" {
+		filters = append(filters, "status "+m.runFilter.Status)
+	}
+	title := fmt.Sprintf("Workflow runs of %s/%s", m.repoOwner, m.repoName)
+	if len(filters) > 0 {
+		title += " (" + strings.Join(filters, ", ") + ")"
+	}
+	return title + ":"
+}
+
+// isListState reports whether the current state shows the list view
+func (m model) isListState() bool {
+	switch m.state {
+	case StateRecent, StateRepositories, StateReleases, StateRuns, StateAssets:
+		return true
+	}
+	return false
+}
+
+// Handle input when in releases state
+func (m model) handleReleasesInput(key string) (tea.Model, tea.Cmd) {
+	if m.listView.searchActive {
+		switch key {
+		case "esc":
+			m.listView.searchActive = false
+			m.listView.SetFilter("")
+		case "enter":
+			m.listView.searchActive = false
+			if selectedRelease := m.listView.GetCurrentRelease(); selectedRelease != nil {
+				return m, m.selectRelease(selectedRelease)
+			}
+		case "backspace":
+			m.listView.BackspaceFilter()
+		default:
+			if !m.listView.HandleNavigationKey(key) && len(key) == 1 {
+				m.listView.AddToFilter(key)
+			}
 		}
+		return m, nil
+	}
+
+	// Nav mode
+	if m.listView.HandleNavigationKey(key) || m.listView.HandleSortKey(key) {
+		return m, nil
+	}
+	switch key {
+	case "/":
+		m.listView.ActivateSearch()
 	case "esc":
 		if m.listView.filter != "" {
 			m.listView.SetFilter("")
 		}
+	case "b":
+		return m, m.toggleBookmark(m.repoOwner, m.repoName)
+	case "A":
+		m.runsFromReleases = true
+		m.runFilter = RunFilter{}
+		m.listView.SetWorkflowRuns(nil)
+		m.state = StateRuns
+		m.loading = true
+		m.errorMsg = ""
+		return m, fetchWorkflowRuns(m.repoOwner, m.repoName, m.runFilter)
 	case "enter", " ":
 		if selectedRelease := m.listView.GetCurrentRelease(); selectedRelease != nil {
-			m.selectRelease(selectedRelease)
+			return m, m.selectRelease(selectedRelease)
 		}
 	}
 
 	return m, nil
 }
 
-func (m *model) selectRelease(selectedRelease *Release) {
+func (m *model) selectRelease(selectedRelease *Release) tea.Cmd {
 	var assets []AssetInfo
 	for _, asset := range selectedRelease.Assets {
 		assetInfo := m.assetFormatter.FormatAssetInfo(asset, *selectedRelease)
 		assetInfo.DisplayLine = m.assetFormatter.createDisplayLineWithoutTag(asset.Name, assetInfo.SizeStr, assetInfo.FormattedDate)
+		assetInfo.Repository = m.repoOwner + "/" + m.repoName
 		assets = append(assets, assetInfo)
 	}
+	assets = append(assets, m.assetFormatter.SourceArchiveAssets(*selectedRelease, m.repoName)...)
 	m.listView.SetAssets(assets)
 	m.state = StateAssets
 	m.fromReleasesView = true
+
+	// Coming back to a remembered repository, preselect the assets matching
+	// the mask used last time
+	if entry := findHistory(m.history, m.repoOwner, m.repoName); m.fromRecentView && entry != nil && entry.LastMask != "" {
+		if count, err := m.listView.SelectByGlob(entry.LastMask); err == nil && count > 0 {
+			m.listView.status = fmt.Sprintf("%d asset(s) preselected by last mask %q", count, entry.LastMask)
+		}
+	}
+
+	tag := selectedRelease.TagName
+	return rememberRepository(m.repoOwner, m.repoName, func(entry *RepositoryHistory) {
+		entry.LastTag = tag
+	})
+}
+
+// toggleBookmark bookmarks owner/name, or removes its bookmark
+func (m *model) toggleBookmark(owner, name string) tea.Cmd {
+	if owner == "" || name == "" {
+		return nil
+	}
+	bookmarked := false
+	if entry := findHistory(m.history, owner, name); entry != nil {
+		bookmarked = entry.Bookmarked
+	}
+	if bookmarked {
+		m.listView.status = fmt.Sprintf("Removed bookmark for %s/%s", owner, name)
+	} else {
+		m.listView.status = fmt.Sprintf("Bookmarked %s/%s", owner, name)
+	}
+	return rememberRepository(owner, name, func(entry *RepositoryHistory) {
+		entry.Bookmarked = !bookmarked
+	})
 }
 
 // Handle input when in assets state
 func (m model) handleAssetsInput(key string) (tea.Model, tea.Cmd) {
-	maxItems := len(m.listView.filteredItems)
-
 	if m.listView.searchActive {
 		switch key {
 		case "esc":
@@ -290,42 +736,49 @@ func (m model) handleAssetsInput(key string) (tea.Model, tea.Cmd) {
 		case "enter":
 			m.listView.searchActive = false
 			return m.startDownload()
-		case "up":
-			if m.listView.cursor > 0 {
-				m.listView.cursor--
-			}
-		case "down":
-			if m.listView.cursor < maxItems-1 {
-				m.listView.cursor++
-			}
 		case "backspace":
 			m.listView.BackspaceFilter()
 		default:
-			if len(key) == 1 {
+			if !m.listView.HandleNavigationKey(key) && len(key) == 1 {
 				m.listView.AddToFilter(key)
 			}
 		}
 		return m, nil
 	}
 
+	if m.listView.globActive {
+		if mask := m.listView.HandleGlobPromptKey(key); mask != "" {
+			return m, rememberRepository(m.repoOwner, m.repoName, func(entry *RepositoryHistory) {
+				entry.LastMask = mask
+			})
+		}
+		return m, nil
+	}
+
 	// Nav mode
+	if m.listView.HandleNavigationKey(key) || m.listView.HandleSelectionKey(key) || m.listView.HandleSortKey(key) {
+		return m, nil
+	}
 	switch key {
 	case "/":
+		m.listView.CancelVisualMode()
 		m.listView.ActivateSearch()
-	case "up", "k":
-		if m.listView.cursor > 0 {
-			m.listView.cursor--
-		}
-	case "down", "j":
-		if m.listView.cursor < maxItems-1 {
-			m.listView.cursor++
-		}
 	case "esc":
-		if m.listView.filter != "" {
+		if m.listView.visualActive {
+			m.listView.CancelVisualMode()
+		} else if m.listView.filter != "" {
 			m.listView.SetFilter("")
 		}
-	case " ":
-		m.listView.ToggleSelection()
+	case "d":
+		m.listView.ToggleDetails()
+	case "c":
+		if asset := m.listView.GetCurrentAsset(); asset != nil {
+			return m, copyToClipboard(asset.DownloadURL, "download URL")
+		}
+	case "C":
+		if asset := m.listView.GetCurrentAsset(); asset != nil {
+			return m, copyToClipboard(asset.Digest, "digest")
+		}
 	case "enter":
 		return m.startDownload()
 	}
@@ -340,37 +793,317 @@ func (m model) startDownload() (tea.Model, tea.Cmd) {
 			selectedAssets = []AssetInfo{*currentAsset}
 		}
 	}
-	if len(selectedAssets) > 0 {
+	return m.startBatch(selectedAssets)
+}
+
+// startBatch queues assets and starts downloading them
+func (m model) startBatch(assets []AssetInfo) (tea.Model, tea.Cmd) {
+	if len(assets) > 0 {
+		m.hookFailures = nil
+		m.pausing = false
+		m.paused = false
+		m.overwriteConflict = false
+		m.batchPolicy = ""
 		m.downloadQueue.Reset()
-		m.downloadQueue.AddMultiple(selectedAssets)
+		m.downloadFinished = false
+		m.downloadQueue.AddMultiple(assets)
 		if !m.downloadQueue.IsEmpty() {
-			asset := m.downloadQueue.GetCurrent()
-			return m, tea.Batch(
-				func() tea.Msg {
-					return startDownloadProgressMsg{asset: *asset}
-				},
-				downloadAsset(*asset),
-			)
+			m.progressEvents = make(chan downloadProgressMsg, progressEventBuffer)
+			return m, tea.Batch(m.downloadCurrent(), waitForProgress(m.progressEvents), progressTick())
 		}
 	}
 	return m, nil
 }
 
+// downloadCurrent starts the download at the head of the queue
+func (m *model) downloadCurrent() tea.Cmd {
+	asset := m.downloadQueue.GetCurrent()
+	if asset == nil {
+		return nil
+	}
+	m.downloading = true
+	m.state = StateDownloading
+	if m.pausing {
+		// Paused between two downloads; the next one waits for resume
+		m.pausing = false
+		m.paused = true
+		return nil
+	}
+
+	// With the "ask" policy, an existing file waits for the user's choice;
+	// a resumed download keeps the choice made when it started
+	progress := m.downloadQueue.progress[m.downloadQueue.currentIndex]
+	policy := progress.policy
+	if policy == "" {
+		policy = m.batchPolicy
+	}
+	if policy == "" && !progress.paused && m.overwritePolicy == PolicyAsk && targetExists(*asset) {
+		m.overwriteConflict = true
+		return nil
+	}
+	return m.startCurrent(policy)
+}
+
+// startCurrent starts the download at the head of the queue with policy
+// for an existing file
+func (m *model) startCurrent(policy OverwritePolicy) tea.Cmd {
+	asset := m.downloadQueue.GetCurrent()
+	m.overwriteConflict = false
+	m.downloadQueue.progress[m.downloadQueue.currentIndex].policy = policy
+	offset := m.downloadQueue.ResumeCurrent()
+	m.downloadQueue.StartCurrent(time.Now())
+
+	// Each download gets its own context, so skipping or cancelling one
+	// leaves later downloads unaffected
+	m.releaseDownload()
+	ctx, cancel := context.WithCancelCause(context.Background())
+	m.downloadCancel = cancel
+
+	index := m.downloadQueue.currentIndex
+	events := m.progressEvents
+	return downloadAsset(ctx, *asset, offset, policy, func(downloaded, total int64) {
+		// Drop events the TUI has not caught up with rather than slow the
+		// transfer; completion is reported separately
+		select {
+		case events <- downloadProgressMsg{index: index, downloaded: downloaded, total: total}:
+		default:
+		}
+	})
+}
+
+// finishDownloads ends the batch; its downloads have all returned, so no
+// more progress events will be sent
+func (m *model) finishDownloads() {
+	m.downloadFinished = true
+	m.state = StateFinished
+	m.downloadQueue.Finish(time.Now())
+	m.stopDownloads()
+}
+
+// stopDownloads releases the resources of the batch
+func (m *model) stopDownloads() {
+	m.releaseDownload()
+	if m.progressEvents != nil {
+		close(m.progressEvents)
+		m.progressEvents = nil
+	}
+}
+
+// releaseDownload releases the context of the last download
+func (m *model) releaseDownload() {
+	if m.downloadCancel != nil {
+		m.downloadCancel(nil)
+		m.downloadCancel = nil
+	}
+}
+
+// removePartialDownload removes the data kept of a paused download
+func (m *model) removePartialDownload() {
+	asset := m.downloadQueue.GetCurrent()
+	if asset == nil || !m.downloadQueue.progress[m.downloadQueue.currentIndex].paused {
+		return
+	}
+	if removeErr := afetch.RemovePartial(partialFileName(*asset)); removeErr != nil {
+		// The partial file may not have been created yet
+	}
+}
+
+// skipCurrentDownload marks the current download as skipped and starts the
+// next one
+func (m model) skipCurrentDownload() (tea.Model, tea.Cmd) {
+	m.downloadQueue.SkipCurrent()
+	return m.nextDownload()
+}
+
+// nextDownload starts the next download in the queue, or shows the results
+// once the queue is done
+func (m model) nextDownload() (tea.Model, tea.Cmd) {
+	if m.downloadQueue.NextDownload() {
+		return m, m.downloadCurrent()
+	}
+
+	m.finishDownloads()
+	failed := len(m.downloadQueue.FailedAssets())
+	skipped := m.downloadQueue.SkippedCount()
+	m.downloadSuccess = failed == 0 && skipped == 0 && len(m.hookFailures) == 0
+	switch {
+	case failed > 0:
+		m.downloadResult = fmt.Sprintf("%d of %d downloads failed", failed, len(m.downloadQueue.assets))
+	case skipped > 0:
+		m.downloadResult = "Downloads completed with skipped files"
+	case len(m.hookFailures) > 0:
+		m.downloadResult = "All files downloaded and verified, but post-download hooks failed"
+	default:
+		m.downloadResult = "All files downloaded and verified successfully"
+	}
+	return m, nil
+}
+
+// Handle input on the download results screen
+func (m model) handleFinishedInput(key string) (tea.Model, tea.Cmd) {
+	switch key {
+	case "r":
+		// Re-queue only the downloads that failed
+		if failed := m.downloadQueue.FailedAssets(); len(failed) > 0 {
+			return m.startBatch(failed)
+		}
+	case "enter", "esc":
+		m.quitting = true
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+// cancelDownloads ends a cancelled batch and returns to the asset list, where
+// new downloads can be started
+func (m model) cancelDownloads() (tea.Model, tea.Cmd) {
+	completed := 0
+	for _, progress := range m.downloadQueue.progress {
+		if progress.completed {
+			completed++
+		}
+	}
+	m.stopDownloads()
+	m.downloading = false
+	m.batchCancelled = false
+	m.pausing = false
+	m.paused = false
+	m.overwriteConflict = false
+	m.state = StateAssets
+	m.listView.status = fmt.Sprintf("Download cancelled: %d of %d files downloaded", completed, len(m.downloadQueue.assets))
+	return m, nil
+}
+
+// Handle input while downloads are running
+func (m model) handleDownloadingInput(key string) (tea.Model, tea.Cmd) {
+	if !m.downloading || m.batchCancelled {
+		return m, nil
+	}
+	if m.overwriteConflict {
+		switch key {
+		case "o":
+			return m, m.startCurrent(PolicyOverwrite)
+		case "r":
+			return m, m.startCurrent(PolicyRename)
+		case "O":
+			m.batchPolicy = PolicyOverwrite
+			return m, m.startCurrent(PolicyOverwrite)
+		case "R":
+			m.batchPolicy = PolicyRename
+			return m, m.startCurrent(PolicyRename)
+		case "s":
+			m.overwriteConflict = false
+			return m.skipCurrentDownload()
+		}
+		return m, nil
+	}
+	switch key {
+	case "s":
+		if m.paused {
+			m.removePartialDownload()
+			m.paused = false
+			return m.skipCurrentDownload()
+		}
+		// Stop the current download; the next one starts once it has returned
+		if !m.pausing && m.downloadCancel != nil {
+			m.downloadCancel(nil)
+		}
+	case "p":
+		if m.paused {
+			m.paused = false
+			return m, m.downloadCurrent()
+		}
+		// Stop reading from the network, keeping the partial file
+		if !m.pausing && m.downloadCancel != nil {
+			m.pausing = true
+			m.downloadCancel(errDownloadPaused)
+		}
+	}
+	return m, nil
+}
+
+// waitForProgress delivers the next progress event of the batch
+func waitForProgress(events <-chan downloadProgressMsg) tea.Cmd {
+	if events == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		msg, ok := <-events
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
+// progressTick schedules a redraw so the elapsed time keeps running
+func progressTick() tea.Cmd {
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
+		return progressTickMsg(t)
+	})
+}
+
+// failureSummary lists the downloads and post-download hooks that failed
+func (m model) failureSummary() string {
+	var s string
+	if failed := m.downloadQueue.FailedAssets(); len(failed) > 0 {
+		s += "\nFailed downloads:\n"
+		for i, progress := range m.downloadQueue.progress {
+			if progress.failed {
+				s += fmt.Sprintf("  %s: %s\n", m.downloadQueue.assets[i].Name, progress.err)
+			}
+		}
+	}
+	if len(m.hookFailures) > 0 {
+		s += "\nPost-download hooks failed:\n  " + strings.Join(m.hookFailures, "\n  ") + "\n"
+	}
+	return s
+}
+
 // View interface display - unified version
 func (m model) View() string {
 	switch m.state {
-	case StateReleases:
-		return m.listView.Render()
-	case StateAssets:
-		return m.listView.Render()
+	case StateRecent, StateRepositories, StateReleases, StateRuns, StateAssets:
+		if m.state == StateRepositories && m.querying {
+			return m.renderQueryPrompt()
+		}
+		// Lists are replaced by the loading or error message until they have content
+		if !m.loading && (m.errorMsg == "" || len(m.listView.items) > 0) {
+			return m.listView.Render()
+		}
 	case StateDownloading:
+		infoStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
 		s := "Download progress:\n\n"
-		s += m.progressFormatter.RenderProgressTable(m.downloadQueue.assets, m.downloadQueue.progress)
+		s += m.progressFormatter.RenderProgressTable(m.downloadQueue, time.Now())
+		switch {
+		case m.batchCancelled:
+			s += "\n" + infoStyle.Render("Cancelling...") + "\n"
+		case m.overwriteConflict:
+			warningStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+			s += "\n" + warningStyle.Render(m.downloadQueue.GetCurrent().TargetPath()+" already exists") + "\n"
+			s += infoStyle.Render("'o' to overwrite · 'r' to keep both · 's' to skip · 'O'/'R' for all remaining · 'q' to cancel all") + "\n"
+		case m.pausing:
+			s += "\n" + infoStyle.Render("Pausing...") + "\n"
+		case m.paused:
+			s += "\n" + infoStyle.Render("Paused · 'p' to resume · 's' to skip the current file · 'q' to cancel all") + "\n"
+		default:
+			s += "\n" + infoStyle.Render("'p' to pause · 's' to skip the current file · 'q' to cancel all") + "\n"
+		}
 		return s
 	case StateFinished:
 		s := "Download results:\n\n"
-		s += m.progressFormatter.RenderProgressTable(m.downloadQueue.assets, m.downloadQueue.progress)
+		s += m.progressFormatter.RenderProgressTable(m.downloadQueue, time.Now())
 		s += "\n" + m.downloadResult + "\n"
+		s += m.failureSummary()
+		// The hint is dropped from the output left behind on exit
+		if !m.quitting {
+			infoStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+			if len(m.downloadQueue.FailedAssets()) > 0 {
+				s += "\n" + infoStyle.Render("'r' to retry failed downloads · 'q' to quit") + "\n"
+			} else {
+				s += "\n" + infoStyle.Render("'q' to quit") + "\n"
+			}
+		}
 		return s
 	}
 
@@ -378,6 +1111,14 @@ func (m model) View() string {
 	switch {
 	case m.quitting:
 		return "Goodbye!\n"
+	case m.loading && m.state == StateRepositories && m.searchQuery != "":
+		return fmt.Sprintf("Searching GitHub for %q...\n", m.searchQuery)
+	case m.loading && m.state == StateRepositories:
+		return "Loading repositories...\n"
+	case m.loading && m.state == StateRuns && len(m.listView.items) > 0:
+		return "Loading artifacts...\n"
+	case m.loading && m.state == StateRuns:
+		return "Loading workflow runs...\n"
 	case m.loading:
 		return "Searching for available artifacts...\n"
 	case m.errorMsg != "":
@@ -386,3 +1127,17 @@ func (m model) View() string {
 		return "No artifacts found\n"
 	}
 }
+
+// renderQueryPrompt renders the GitHub repository search prompt
+func (m model) renderQueryPrompt() string {
+	searchStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+	infoStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	s := "Search GitHub repositories:\n\n"
+	s += searchStyle.Render("> "+m.searchQuery+"█") + "\n"
+	if m.queryStatus != "" {
+		s += "\n" + infoStyle.Render(m.queryStatus) + "\n"
+	}
+	s += "\n" + "Type a name or GitHub search qualifiers (e.g. 'afetch language:go'), 'enter' to search, 'esc' to cancel\n"
+	return s
+}
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/Native-Robotics/asset-fetch/internal/githubtest"
+)
+
+// testProgram runs a model like the bubbletea runtime does, without a
+// terminal: commands run in goroutines and their messages are fed back
+// through Update
+type testProgram struct {
+	t        *testing.T
+	model    model
+	messages chan tea.Msg
+	quit     bool
+}
+
+func newTestProgram(t *testing.T, m model) *testProgram {
+	p := &testProgram{t: t, messages: make(chan tea.Msg, 64)}
+	p.model = m
+	p.send(tea.WindowSizeMsg{Width: 160, Height: 40})
+	p.run(m.Init())
+	return p
+}
+
+func (p *testProgram) run(cmd tea.Cmd) {
+	if cmd != nil {
+		go func() { p.messages <- cmd() }()
+	}
+}
+
+func (p *testProgram) send(msg tea.Msg) {
+	switch msg := msg.(type) {
+	case nil:
+		return
+	case tea.BatchMsg:
+		for _, cmd := range msg {
+			p.run(cmd)
+		}
+		return
+	case tea.QuitMsg:
+		p.quit = true
+		return
+	}
+	next, cmd := p.model.Update(msg)
+	p.model = next.(model)
+	p.run(cmd)
+}
+
+// press sends key presses; "enter", "esc" and "space" are named keys
+func (p *testProgram) press(keys ...string) {
+	for _, key := range keys {
+		switch key {
+		case "enter":
+			p.send(tea.KeyMsg{Type: tea.KeyEnter})
+		case "esc":
+			p.send(tea.KeyMsg{Type: tea.KeyEsc})
+		case "space":
+			p.send(tea.KeyMsg{Type: tea.KeySpace})
+		default:
+			p.send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		}
+	}
+}
+
+// typeText types text into the open prompt one key at a time
+func (p *testProgram) typeText(text string) {
+	for _, r := range text {
+		p.press(string(r))
+	}
+}
+
+// waitFor processes messages until the model satisfies done
+func (p *testProgram) waitFor(what string, done func(model) bool) {
+	p.t.Helper()
+	timeout := time.After(5 * time.Second)
+	for !done(p.model) {
+		select {
+		case msg := <-p.messages:
+			p.send(msg)
+		case <-timeout:
+			p.t.Fatalf("timed out waiting for %s; view:\n%s", what, p.model.View())
+		}
+	}
+}
+
+// waitForView processes messages until the view contains text
+func (p *testProgram) waitForView(text string) {
+	p.t.Helper()
+	p.waitFor(strings.TrimSpace(text), func(m model) bool { return strings.Contains(m.View(), text) })
+}
+
+func newReleasesModel() model {
+	return model{
+		loading:           true,
+		state:             StateReleases,
+		repoOwner:         "owner",
+		repoName:          "repo",
+		startWithReleases: true,
+	}
+}
+
+func TestModelDownloadsAssetOfSelectedRelease(t *testing.T) {
+	server := setupFakeGitHub(t)
+	addTestReleases(server)
+
+	p := newTestProgram(t, newReleasesModel())
+	p.waitForView("v1.0.0")
+	if view := p.model.View(); !strings.Contains(view, "v1.1.0") {
+		t.Fatalf("release list lacks v1.1.0:\n%s", view)
+	}
+
+	// The newest release is highlighted; open it and pick the Linux build
+	p.press("enter")
+	if p.model.state != StateAssets {
+		t.Fatalf("state = %v after selecting a release", p.model.state)
+	}
+	p.press("/")
+	p.typeText("linux")
+	p.press("enter")
+
+	p.waitForView("All files downloaded and verified successfully")
+	if content, _ := os.ReadFile("app_linux_amd64.tar.gz"); string(content) != "linux build 1.1.0" {
+		t.Errorf("downloaded content = %q", content)
+	}
+	if _, err := os.Stat("app_windows_amd64.zip"); !os.IsNotExist(err) {
+		t.Error("unselected asset was downloaded")
+	}
+
+	p.press("enter")
+	p.waitFor("the program to quit", func(model) bool { return p.quit })
+}
+
+func TestModelRetriesFailedDownloads(t *testing.T) {
+	server := setupFakeGitHub(t)
+	server.AddRelease("owner/repo", githubtest.Release{Tag: "v2", Assets: []githubtest.Asset{
+		{Name: "good.bin", Content: []byte("good")},
+		{Name: "flaky.bin", Content: []byte("flaky")},
+	}})
+	flaky := strings.TrimPrefix(server.AssetURL("owner/repo", "v2", "flaky.bin"), server.URL)
+	server.Fail(flaky, 503)
+
+	p := newTestProgram(t, model{loading: true, state: StateReleases, repoOwner: "owner", repoName: "repo", tag: "v2"})
+	p.waitFor("the asset list", func(m model) bool { return m.state == StateAssets })
+	p.press("a", "enter")
+
+	p.waitForView("1 of 2 downloads failed")
+	view := p.model.View()
+	if !strings.Contains(view, "Failed downloads:") || !strings.Contains(view, "503") {
+		t.Errorf("results lack the failure reason:\n%s", view)
+	}
+	if !strings.Contains(view, "'r' to retry failed downloads") {
+		t.Errorf("results do not offer a retry:\n%s", view)
+	}
+
+	server.Fail(flaky, 0)
+	p.press("r")
+	p.waitForView("All files downloaded and verified successfully")
+	if len(p.model.downloadQueue.assets) != 1 {
+		t.Errorf("retry queued %d downloads, want only the failed one", len(p.model.downloadQueue.assets))
+	}
+	for name, want := range map[string]string{"good.bin": "good", "flaky.bin": "flaky"} {
+		if content, _ := os.ReadFile(name); string(content) != want {
+			t.Errorf("%s = %q, want %q", name, content, want)
+		}
+	}
+}
+
+func TestModelShowsMaskedAssets(t *testing.T) {
+	server := setupFakeGitHub(t)
+	addTestReleases(server)
+	writeConfig(t, `ASSET_MASK="*linux*"`)
+
+	p := newTestProgram(t, model{loading: true, state: StateReleases, repoOwner: "owner", repoName: "repo"})
+	p.waitFor("the asset list", func(m model) bool { return m.state == StateAssets && !m.loading })
+	view := p.model.View()
+	if strings.Count(view, "app_linux_amd64.tar.gz") != 2 || strings.Contains(view, "windows") {
+		t.Errorf("asset list does not show exactly the Linux builds of both releases:\n%s", view)
+	}
+}
+
+func TestModelShowsRateLimitError(t *testing.T) {
+	server := setupFakeGitHub(t)
+	addTestReleases(server)
+	server.SetRateLimited(true)
+
+	p := newTestProgram(t, newReleasesModel())
+	p.waitForView("Error: GitHub API error: 403 (rate limit exceeded)")
+}
+
+func TestModelSkipsUpToDateFiles(t *testing.T) {
+	server := setupFakeGitHub(t)
+	addTestReleases(server)
+	if err := os.WriteFile("app_linux_amd64.tar.gz", []byte("linux build 1.1.0"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := newTestProgram(t, model{loading: true, state: StateReleases, repoOwner: "owner", repoName: "repo", tag: "v1.1.0"})
+	p.waitFor("the asset list", func(m model) bool { return m.state == StateAssets })
+	p.press("/")
+	p.typeText("linux")
+	p.press("enter")
+
+	p.waitForView("already up to date")
+	for _, request := range server.Requests() {
+		if strings.HasPrefix(request.Path, "/storage/") {
+			t.Errorf("up-to-date file was downloaded again: %s", request.Path)
+		}
+	}
+}
+
+// stallingTransport holds requests for the stalled paths until their
+// context is cancelled, so a download can be interrupted while it runs
+type stallingTransport struct {
+	mu      sync.Mutex
+	paths   map[string]bool
+	waiting int
+}
+
+func (s *stallingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.mu.Lock()
+	stalled := s.paths[req.URL.Path]
+	if stalled {
+		s.waiting++
+	}
+	s.mu.Unlock()
+	if !stalled {
+		return http.DefaultTransport.RoundTrip(req)
+	}
+	<-req.Context().Done()
+	s.mu.Lock()
+	s.waiting--
+	s.mu.Unlock()
+	return nil, req.Context().Err()
+}
+
+// stall makes requests to path wait until they are cancelled; unstall
+// lets later requests through
+func (s *stallingTransport) stall(path string, stalled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paths[path] = stalled
+}
+
+// stalled reports whether a request is waiting
+func (s *stallingTransport) stalled() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.waiting > 0
+}
+
+// setupStalledDownload serves slow.bin and next.bin as release v2, where
+// downloads of slow.bin hang until they are cancelled
+func setupStalledDownload(t *testing.T) (*stallingTransport, string) {
+	server := setupFakeGitHub(t)
+	server.AddRelease("owner/repo", githubtest.Release{Tag: "v2", Assets: []githubtest.Asset{
+		{Name: "slow.bin", Content: []byte("slow")},
+		{Name: "next.bin", Content: []byte("next")},
+	}})
+	transport := &stallingTransport{paths: make(map[string]bool)}
+	previous := httpClient
+	httpClient = &http.Client{Transport: transport}
+	t.Cleanup(func() { httpClient = previous })
+
+	slow := strings.TrimPrefix(server.AssetURL("owner/repo", "v2", "slow.bin"), server.URL)
+	transport.stall(slow, true)
+	return transport, slow
+}
+
+func TestModelSkipStopsOnlyCurrentDownload(t *testing.T) {
+	transport, _ := setupStalledDownload(t)
+
+	p := newTestProgram(t, model{loading: true, state: StateReleases, repoOwner: "owner", repoName: "repo", tag: "v2"})
+	p.waitFor("the asset list", func(m model) bool { return m.state == StateAssets })
+	p.press("a", "enter")
+	p.waitFor("slow.bin to stall", func(model) bool { return transport.stalled() })
+
+	p.press("s")
+	p.waitForView("Downloads completed with skipped files")
+	progress := p.model.downloadQueue.progress
+	if !progress[0].skipped || progress[0].failed || !progress[1].completed || progress[1].failed {
+		t.Errorf("progress = %+v, want slow.bin skipped and next.bin downloaded", progress)
+	}
+	if content, _ := os.ReadFile("next.bin"); string(content) != "next" {
+		t.Errorf("next.bin = %q", content)
+	}
+	if _, err := os.Stat("slow.bin"); !os.IsNotExist(err) {
+		t.Error("skipped download left slow.bin behind")
+	}
+}
+
+func TestModelDownloadsAfterCancelledBatch(t *testing.T) {
+	transport, slow := setupStalledDownload(t)
+
+	p := newTestProgram(t, model{loading: true, state: StateReleases, repoOwner: "owner", repoName: "repo", tag: "v2"})
+	p.waitFor("the asset list", func(m model) bool { return m.state == StateAssets })
+	p.press("a", "enter")
+	p.waitFor("slow.bin to stall", func(model) bool { return transport.stalled() })
+
+	p.press("q")
+	p.waitForView("Download cancelled: 0 of 2 files downloaded")
+	if p.model.state != StateAssets || p.model.downloading {
+		t.Fatalf("state %v, downloading %v after cancelling", p.model.state, p.model.downloading)
+	}
+
+	// The cancelled batch does not cancel the next one
+	transport.stall(slow, false)
+	p.press("enter")
+	p.waitForView("All files downloaded and verified successfully")
+	for name, want := range map[string]string{"slow.bin": "slow", "next.bin": "next"} {
+		if content, _ := os.ReadFile(name); string(content) != want {
+			t.Errorf("%s = %q, want %q", name, content, want)
+		}
+	}
+}
+
+func TestModelAsksAboutExistingFiles(t *testing.T) {
+	server := setupFakeGitHub(t)
+	addTestReleases(server)
+	if err := os.WriteFile("app_linux_amd64.tar.gz", []byte("local build"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The policy resolved at startup applies; there is no configuration file
+	p := newTestProgram(t, model{loading: true, state: StateReleases, repoOwner: "owner", repoName: "repo", tag: "v1.1.0", overwritePolicy: PolicyAsk})
+	p.waitFor("the asset list", func(m model) bool { return m.state == StateAssets })
+	p.press("/")
+	p.typeText("linux")
+	p.press("enter")
+	p.waitForView("app_linux_amd64.tar.gz already exists")
+
+	p.press("r")
+	p.waitForView("All files downloaded and verified successfully")
+	for name, want := range map[string]string{"app_linux_amd64.tar.gz": "local build", "app_linux_amd64 (1).tar.gz": "linux build 1.1.0"} {
+		if content, _ := os.ReadFile(name); string(content) != want {
+			t.Errorf("%s = %q, want %q", name, content, want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+)
+
+// httpClient sends all HTTP requests, so connections are reused across API
+// calls and downloads; configureHTTPClient applies the network settings
+var httpClient = &http.Client{}
+
+// configureHTTPClient replaces httpClient with one using the proxy and TLS
+// settings of config
+func configureHTTPClient(config *Config) error {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if config.ProxyURL != "" {
+		proxyURL, err := url.Parse(config.ProxyURL)
+		if err != nil || proxyURL.Host == "" {
+			return fmt.Errorf("invalid PROXY_URL %q", config.ProxyURL)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig, err := clientTLSConfig(config)
+	if err != nil {
+		return err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	httpClient = &http.Client{Transport: transport}
+	if len(config.InsecureHosts) > 0 {
+		insecure := transport.Clone()
+		insecure.TLSClientConfig.InsecureSkipVerify = true
+		httpClient.Transport = &insecureHostsTransport{verified: transport, insecure: insecure, hosts: config.InsecureHosts}
+	}
+	return nil
+}
+
+// insecureHostsTransport sends requests to hosts whose certificates are not
+// verified through their own transport, so their connections are never
+// reused for other hosts
+type insecureHostsTransport struct {
+	verified, insecure http.RoundTripper
+	hosts              []string
+}
+
+func (t *insecureHostsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if slices.Contains(t.hosts, strings.ToLower(req.URL.Hostname())) {
+		return t.insecure.RoundTrip(req)
+	}
+	return t.verified.RoundTrip(req)
+}
+
+// clientTLSConfig returns the TLS settings of config: extra trusted CA
+// certificates and a client certificate
+func clientTLSConfig(config *Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+
+	if len(config.CACertFiles) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		for _, file := range config.CACertFiles {
+			pem, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("reading CA certificates: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no PEM certificates in %s", file)
+			}
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if config.ClientCert != "" || config.ClientKey != "" {
+		// The key may be part of the certificate file
+		keyFile := config.ClientKey
+		if keyFile == "" {
+			keyFile = config.ClientCert
+		}
+		cert, err := tls.LoadX509KeyPair(config.ClientCert, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// warnInsecureHosts prints a warning when certificate verification is
+// disabled for some hosts
+func warnInsecureHosts(config *Config) {
+	if len(config.InsecureHosts) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: TLS certificates of %s are not verified (INSECURE_SKIP_VERIFY)\n", strings.Join(config.InsecureHosts, ", "))
+	}
+}
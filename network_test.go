@@ -0,0 +1,155 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useHTTPClient configures httpClient with config for the rest of the test
+func useHTTPClient(t *testing.T, config *Config) error {
+	t.Helper()
+	previous := httpClient
+	t.Cleanup(func() { httpClient = previous })
+	return configureHTTPClient(config)
+}
+
+// get requests url with httpClient and reports the error, if any
+func get(url string) error {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// writeCertificate writes cert as a PEM file and returns its path
+func writeCertificate(t *testing.T, name string, cert *x509.Certificate) string {
+	t.Helper()
+	return writeTestFile(t, name, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+}
+
+func TestConfigureHTTPClientCACertFiles(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	if err := useHTTPClient(t, &Config{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := get(server.URL); err == nil {
+		t.Error("untrusted server certificate accepted")
+	}
+
+	config := &Config{CACertFiles: []string{writeCertificate(t, "ca.pem", server.Certificate())}}
+	if err := useHTTPClient(t, config); err != nil {
+		t.Fatal(err)
+	}
+	if err := get(server.URL); err != nil {
+		t.Errorf("with the CA bundle: %v", err)
+	}
+
+	config = &Config{CACertFiles: []string{writeTestFile(t, "empty.pem", []byte("no certificates"))}}
+	if err := useHTTPClient(t, config); err == nil || !strings.Contains(err.Error(), "no PEM certificates") {
+		t.Errorf("bundle without certificates: got %v", err)
+	}
+}
+
+func TestConfigureHTTPClientCertificate(t *testing.T) {
+	key := newTestECDSAKey(t)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(cert)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+	ca := writeCertificate(t, "ca.pem", server.Certificate())
+
+	if err := useHTTPClient(t, &Config{CACertFiles: []string{ca}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := get(server.URL); err == nil {
+		t.Error("request without a client certificate accepted")
+	}
+
+	// The key may be in the certificate file
+	certFile := writeCertificate(t, "client.pem", cert)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	keyFile := writeTestFile(t, "client.key", keyPEM)
+	combined := writeTestFile(t, "combined.pem", append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), keyPEM...))
+	for _, config := range []*Config{
+		{CACertFiles: []string{ca}, ClientCert: certFile, ClientKey: keyFile},
+		{CACertFiles: []string{ca}, ClientCert: combined},
+	} {
+		if err := useHTTPClient(t, config); err != nil {
+			t.Fatal(err)
+		}
+		if err := get(server.URL); err != nil {
+			t.Errorf("with %s: %v", filepath.Base(config.ClientCert), err)
+		}
+	}
+}
+
+func TestConfigureHTTPClientInsecureHosts(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	for _, test := range []struct {
+		hosts   []string
+		trusted bool
+	}{
+		{[]string{"127.0.0.1"}, true},
+		{[]string{"test.example.com", "127.0.0.1"}, true},
+		{[]string{"test.example.com"}, false},
+	} {
+		if err := useHTTPClient(t, &Config{InsecureHosts: test.hosts}); err != nil {
+			t.Fatal(err)
+		}
+		if err := get(server.URL); (err == nil) != test.trusted {
+			t.Errorf("INSECURE_SKIP_VERIFY=%s: got %v", strings.Join(test.hosts, ","), err)
+		}
+	}
+}
+
+func TestLoadConfigInsecureHosts(t *testing.T) {
+	setupFakeGitHub(t)
+	writeConfig(t, "INSECURE_SKIP_VERIFY=Test.Example.com, localhost")
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(config.InsecureHosts, ",") != "test.example.com,localhost" {
+		t.Errorf("hosts = %v", config.InsecureHosts)
+	}
+
+	writeConfig(t, "INSECURE_SKIP_VERIFY=true")
+	if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), "lists the hosts") {
+		t.Errorf("INSECURE_SKIP_VERIFY=true: got %v", err)
+	}
+}
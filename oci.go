@@ -0,0 +1,425 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"runtime"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Annotation holding the file name of an ORAS-style artifact layer
+const ociTitleAnnotation = "org.opencontainers.image.title"
+
+// Annotation holding the creation time of an image or artifact
+const ociCreatedAnnotation = "org.opencontainers.image.created"
+
+// Manifest media types accepted when resolving a reference
+var ociManifestMediaTypes = []string{
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+}
+
+// ociReference identifies an artifact in an OCI registry,
+// e.g. oci://ghcr.io/org/tool:1.2
+type ociReference struct {
+	Scheme     string // "https", or "http" for local registries
+	Registry   string // host[:port]
+	Repository string // e.g. "org/tool"
+	Reference  string // tag or digest
+}
+
+// parseOCIReference parses "oci://registry/repository[:tag|@digest]". The
+// "oci+http://" scheme, or a localhost registry, uses plain HTTP.
+func parseOCIReference(raw string) (ociReference, error) {
+	var ref ociReference
+	input := raw
+	switch {
+	case strings.HasPrefix(raw, "oci://"):
+		ref.Scheme = "https"
+		raw = strings.TrimPrefix(raw, "oci://")
+	case strings.HasPrefix(raw, "oci+http://"):
+		ref.Scheme = "http"
+		raw = strings.TrimPrefix(raw, "oci+http://")
+	default:
+		return ref, fmt.Errorf("invalid OCI reference %q, expected oci://registry/repository:tag", input)
+	}
+
+	registry, repository, found := strings.Cut(raw, "/")
+	if !found || registry == "" || repository == "" {
+		return ref, fmt.Errorf("invalid OCI reference %q, expected oci://registry/repository:tag", input)
+	}
+	ref.Registry = registry
+	if isLocalRegistry(registry) {
+		ref.Scheme = "http"
+	}
+
+	if name, digest, ok := strings.Cut(repository, "@"); ok {
+		repository, ref.Reference = name, digest
+	} else if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		repository, ref.Reference = repository[:i], repository[i+1:]
+	}
+	if ref.Reference == "" {
+		ref.Reference = "latest"
+	}
+	ref.Repository = strings.Trim(repository, "/")
+	if ref.Repository == "" {
+		return ref, fmt.Errorf("invalid OCI reference %q: missing repository", input)
+	}
+	return ref, nil
+}
+
+// isLocalRegistry reports whether registry (host[:port]) is on the loopback interface
+func isLocalRegistry(registry string) bool {
+	host := registry
+	if h, _, err := net.SplitHostPort(registry); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
+
+// String returns the reference in oci://registry/repository:tag form
+func (r ociReference) String() string {
+	scheme := "oci://"
+	if r.Scheme == "http" && !isLocalRegistry(r.Registry) {
+		scheme = "oci+http://"
+	}
+	separator := ":"
+	if strings.Contains(r.Reference, ":") {
+		separator = "@"
+	}
+	return scheme + r.Registry + "/" + r.Repository + separator + r.Reference
+}
+
+// manifestURL returns the registry API URL of the manifest for reference
+func (r ociReference) manifestURL(reference string) string {
+	return fmt.Sprintf("%s://%s/v2/%s/manifests/%s", r.Scheme, r.Registry, r.Repository, reference)
+}
+
+// blobURL returns the registry API URL of the blob with digest
+func (r ociReference) blobURL(digest string) string {
+	return fmt.Sprintf("%s://%s/v2/%s/blobs/%s", r.Scheme, r.Registry, r.Repository, digest)
+}
+
+// ociDescriptor describes a manifest, config or layer blob
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations"`
+	Platform    *struct {
+		OS           string `json:"os"`
+		Architecture string `json:"architecture"`
+	} `json:"platform"`
+}
+
+// ociManifest is an image manifest or, when Manifests is set, an index
+type ociManifest struct {
+	MediaType   string            `json:"mediaType"`
+	Layers      []ociDescriptor   `json:"layers"`
+	Manifests   []ociDescriptor   `json:"manifests"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// registryClient talks to one repository of an OCI registry, requesting a
+// bearer token when the registry challenges for one
+type registryClient struct {
+	client   *http.Client
+	ref      ociReference
+	username string
+	password string
+	token    string
+	offset   int64 // first byte requested from blobs
+}
+
+// newRegistryClient returns a client for ref using the credentials in config
+func newRegistryClient(ref ociReference, config *Config) *registryClient {
+	rc := &registryClient{client: httpClient, ref: ref}
+	if config != nil {
+		rc.username, rc.password = registryCredentials(config, ref.Registry)
+	}
+	return rc
+}
+
+// registryCredentials returns the credentials configured for registry. The
+// GitHub token is used for ghcr.io unless other credentials are configured.
+func registryCredentials(config *Config, registry string) (string, string) {
+	if config.RegistryHost == registry && (config.RegistryUsername != "" || config.RegistryPassword != "") {
+		return config.RegistryUsername, config.RegistryPassword
+	}
+	if registry == "ghcr.io" && config.GitHubToken != "" {
+		return "afetch", config.GitHubToken
+	}
+	return "", ""
+}
+
+// get requests apiURL, authenticating and retrying once on a 401 challenge
+func (rc *registryClient) get(ctx context.Context, apiURL string, accept ...string) (*http.Response, error) {
+	resp, err := rc.do(ctx, apiURL, accept)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	challenge := resp.Header.Get("WWW-Authenticate")
+	_ = resp.Body.Close()
+	if err := rc.authenticate(ctx, challenge); err != nil {
+		return nil, err
+	}
+	return rc.do(ctx, apiURL, accept)
+}
+
+// do sends a single GET request with the current credentials
+func (rc *registryClient) do(ctx context.Context, apiURL string, accept []string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(accept) > 0 {
+		req.Header.Set("Accept", strings.Join(accept, ", "))
+	}
+	if rc.offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", rc.offset))
+	}
+	if rc.token != "" {
+		req.Header.Set("Authorization", "Bearer "+rc.token)
+	} else if rc.username != "" || rc.password != "" {
+		req.SetBasicAuth(rc.username, rc.password)
+	}
+	return rc.client.Do(req)
+}
+
+// authenticate obtains a pull token as described by a WWW-Authenticate
+// challenge. Configured credentials are sent up front, so a Basic challenge
+// means they are missing or were rejected.
+func (rc *registryClient) authenticate(ctx context.Context, challenge string) error {
+	scheme, params := parseAuthChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if rc.username == "" && rc.password == "" {
+			return fmt.Errorf("registry %s requires credentials", rc.ref.Registry)
+		}
+		return fmt.Errorf("registry %s rejected the configured credentials", rc.ref.Registry)
+	case "bearer":
+	default:
+		return fmt.Errorf("registry %s: unsupported authentication challenge %q", rc.ref.Registry, challenge)
+	}
+
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Host == "" {
+		return fmt.Errorf("registry %s: invalid token realm %q", rc.ref.Registry, params["realm"])
+	}
+	query := realm.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = "repository:" + rc.ref.Repository + ":pull"
+	}
+	query.Set("scope", scope)
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	if rc.username != "" || rc.password != "" {
+		req.SetBasicAuth(rc.username, rc.password)
+	}
+	resp, err := rc.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("registry %s: token request failed: %d", rc.ref.Registry, resp.StatusCode)
+	}
+
+	var result struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	rc.token = result.Token
+	if rc.token == "" {
+		rc.token = result.AccessToken
+	}
+	if rc.token == "" {
+		return fmt.Errorf("registry %s: empty token", rc.ref.Registry)
+	}
+	return nil
+}
+
+// parseAuthChallenge splits a WWW-Authenticate header such as
+// `Bearer realm="https://ghcr.io/token",service="ghcr.io"` into its scheme
+// and parameters
+func parseAuthChallenge(header string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params := make(map[string]string)
+	for rest != "" {
+		var key string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		if key = strings.ToLower(strings.TrimSpace(key)); key != "" {
+			params[key] = value
+		}
+	}
+	return scheme, params
+}
+
+// fetchManifest fetches the manifest of reference, verifying it against the
+// reference when that is a digest
+func (rc *registryClient) fetchManifest(ctx context.Context, reference string) (*ociManifest, error) {
+	resp, err := rc.get(ctx, rc.ref.manifestURL(reference), ociManifestMediaTypes...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%s/%s:%s not found", rc.ref.Registry, rc.ref.Repository, reference)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("registry error: %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(reference, "sha256:") {
+		sum := sha256.Sum256(body)
+		if actual := "sha256:" + hex.EncodeToString(sum[:]); actual != reference {
+			return nil, fmt.Errorf("manifest digest mismatch: expected %s, got %s", reference, actual)
+		}
+	}
+
+	var manifest ociManifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// selectPlatformManifest picks the manifest of an index matching the current
+// platform, falling back to the first one without a platform
+func selectPlatformManifest(index *ociManifest) (ociDescriptor, error) {
+	var fallback *ociDescriptor
+	for i, desc := range index.Manifests {
+		if desc.Platform == nil {
+			if fallback == nil {
+				fallback = &index.Manifests[i]
+			}
+			continue
+		}
+		if desc.Platform.OS == runtime.GOOS && desc.Platform.Architecture == runtime.GOARCH {
+			return desc, nil
+		}
+	}
+	if fallback != nil {
+		return *fallback, nil
+	}
+	if len(index.Manifests) == 1 {
+		return index.Manifests[0], nil
+	}
+	return ociDescriptor{}, fmt.Errorf("no manifest for %s/%s in index", runtime.GOOS, runtime.GOARCH)
+}
+
+// fetchOCIAssets resolves ref and lists the titled layers of its manifest as
+// downloadable assets
+func fetchOCIAssets(ref ociReference) tea.Cmd {
+	return func() tea.Msg {
+		// Public registries can be used without a configuration file
+		config, err := loadConfig()
+		if errors.Is(err, errConfigNotFound) {
+			config = &Config{}
+		} else if err != nil {
+			return errorMsg(err.Error())
+		}
+
+		ctx := context.Background()
+		rc := newRegistryClient(ref, config)
+		manifest, err := rc.fetchManifest(ctx, ref.Reference)
+		if err != nil {
+			return errorMsg(err.Error())
+		}
+		if len(manifest.Manifests) > 0 {
+			desc, err := selectPlatformManifest(manifest)
+			if err != nil {
+				return errorMsg(err.Error())
+			}
+			if manifest, err = rc.fetchManifest(ctx, desc.Digest); err != nil {
+				return errorMsg(err.Error())
+			}
+		}
+
+		formatter := AssetFormatter{}
+		var assets []AssetInfo
+		for _, layer := range manifest.Layers {
+			if ociLayerFileName(layer.Annotations[ociTitleAnnotation]) == "" {
+				continue
+			}
+			assets = append(assets, formatter.FormatOCILayerInfo(layer, ref, manifest.Annotations[ociCreatedAnnotation]))
+		}
+		if len(assets) == 0 {
+			return errorMsg(fmt.Sprintf("no files in %s (layers need an %s annotation)", ref, ociTitleAnnotation))
+		}
+		return releasesMsg{assets: assets}
+	}
+}
+
+// fetchOCIBlob starts the download of the blob behind asset, which must
+// have been listed by fetchOCIAssets
+func fetchOCIBlob(ctx context.Context, asset AssetInfo, config *Config, offset int64) (*http.Response, error) {
+	blobURL, err := url.Parse(asset.URL)
+	if err != nil {
+		return nil, err
+	}
+	repository, _, found := strings.Cut(strings.TrimPrefix(blobURL.Path, "/v2/"), "/blobs/")
+	if !found {
+		return nil, fmt.Errorf("invalid blob URL %s", asset.URL)
+	}
+	ref := ociReference{Scheme: blobURL.Scheme, Registry: blobURL.Host, Repository: repository}
+	rc := newRegistryClient(ref, config)
+	rc.offset = offset
+	return rc.get(ctx, asset.URL)
+}
+
+// ociLayerFileName returns the local file name of a layer titled title,
+// dropping any directory components
+func ociLayerFileName(title string) string {
+	if title == "" {
+		return ""
+	}
+	name := path.Base(strings.ReplaceAll(title, "\\", "/"))
+	if name == "." || name == "/" || name == ".." {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testRegistry is an OCI registry serving the repository org/tool behind a
+// bearer token challenge
+type testRegistry struct {
+	*httptest.Server
+	mu        sync.Mutex
+	manifests map[string][]byte // by tag or digest
+	blobs     map[string][]byte // by digest
+	scopes    []string          // scopes of token requests
+}
+
+const testRegistryToken = "pull-token"
+
+func newTestRegistry(t *testing.T) *testRegistry {
+	t.Helper()
+	r := &testRegistry{manifests: make(map[string][]byte), blobs: make(map[string][]byte)}
+	r.Server = httptest.NewServer(http.HandlerFunc(r.serve))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *testRegistry) serve(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if req.URL.Path == "/token" {
+		r.scopes = append(r.scopes, req.URL.Query().Get("scope"))
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": testRegistryToken})
+		return
+	}
+	if req.Header.Get("Authorization") != "Bearer "+testRegistryToken {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test-registry"`, r.URL))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if reference, ok := strings.CutPrefix(req.URL.Path, "/v2/org/tool/manifests/"); ok && r.manifests[reference] != nil {
+		w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+		_, _ = w.Write(r.manifests[reference])
+		return
+	}
+	if digest, ok := strings.CutPrefix(req.URL.Path, "/v2/org/tool/blobs/"); ok && r.blobs[digest] != nil {
+		http.ServeContent(w, req, "", time.Time{}, bytes.NewReader(r.blobs[digest]))
+		return
+	}
+	http.NotFound(w, req)
+}
+
+// ociDigest returns the sha256 digest of content in OCI notation
+func ociDigest(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// addManifest stores manifest under tag and its digest, returning the digest
+func (r *testRegistry) addManifest(t *testing.T, tag string, manifest any) string {
+	t.Helper()
+	content, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := ociDigest(content)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.manifests[digest] = content
+	if tag != "" {
+		r.manifests[tag] = content
+	}
+	return digest
+}
+
+// addLayer stores content as a blob and returns its layer descriptor; a
+// non-empty digest overrides the real one
+func (r *testRegistry) addLayer(title string, content []byte, digest string) map[string]any {
+	if digest == "" {
+		digest = ociDigest(content)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.blobs[digest] = content
+	return map[string]any{
+		"mediaType":   "application/octet-stream",
+		"digest":      digest,
+		"size":        len(content),
+		"annotations": map[string]string{ociTitleAnnotation: title},
+	}
+}
+
+// reference returns the OCI reference of tag in the registry
+func (r *testRegistry) reference(t *testing.T, tag string) ociReference {
+	t.Helper()
+	ref, err := parseOCIReference("oci://" + strings.TrimPrefix(r.URL, "http://") + "/org/tool:" + tag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ref
+}
+
+func imageManifest(layers ...map[string]any) map[string]any {
+	return map[string]any{"schemaVersion": 2, "mediaType": "application/vnd.oci.image.manifest.v1+json", "layers": layers}
+}
+
+func platformDescriptor(digest, os, arch string) map[string]any {
+	return map[string]any{
+		"mediaType": "application/vnd.oci.image.manifest.v1+json",
+		"digest":    digest,
+		"platform":  map[string]string{"os": os, "architecture": arch},
+	}
+}
+
+func TestFetchOCIAssetsSelectsPlatform(t *testing.T) {
+	setupFakeGitHub(t)
+	registry := newTestRegistry(t)
+	native := registry.addManifest(t, "", imageManifest(registry.addLayer("dist/tool", []byte("native build"), "")))
+	other := registry.addManifest(t, "", imageManifest(registry.addLayer("tool.exe", []byte("other build"), "")))
+	registry.addManifest(t, "1.0", map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     "application/vnd.oci.image.index.v1+json",
+		"manifests": []any{
+			platformDescriptor(other, "plan9", "mips"),
+			platformDescriptor(native, runtime.GOOS, runtime.GOARCH),
+		},
+	})
+	ref := registry.reference(t, "1.0")
+
+	msg := fetchOCIAssets(ref)()
+	releases, ok := msg.(releasesMsg)
+	if !ok || len(releases.assets) != 1 {
+		t.Fatalf("got %#v, want the native layer", msg)
+	}
+	asset := releases.assets[0]
+	if asset.Name != "dist/tool" || asset.FileName != "tool" || !asset.OCIBlob {
+		t.Errorf("asset = %+v", asset)
+	}
+	if len(registry.scopes) == 0 || registry.scopes[0] != "repository:org/tool:pull" {
+		t.Errorf("token scopes = %v", registry.scopes)
+	}
+
+	msg = downloadAsset(context.Background(), asset, 0, "", nil)()
+	if verified, ok := msg.(checksumVerifiedMsg); !ok || verified.filename != "tool" {
+		t.Fatalf("download: got %#v", msg)
+	}
+	if content, _ := os.ReadFile("tool"); string(content) != "native build" {
+		t.Errorf("content = %q", content)
+	}
+}
+
+func TestFetchOCIAssetsVerifiesDigests(t *testing.T) {
+	setupFakeGitHub(t)
+	registry := newTestRegistry(t)
+	registry.addManifest(t, "1.0", imageManifest(
+		registry.addLayer("tampered.bin", []byte("tampered"), ociDigest([]byte("original"))),
+	))
+
+	msg := fetchOCIAssets(registry.reference(t, "1.0"))()
+	releases, ok := msg.(releasesMsg)
+	if !ok || len(releases.assets) != 1 {
+		t.Fatalf("got %#v", msg)
+	}
+	msg = downloadAsset(context.Background(), releases.assets[0], 0, "", nil)()
+	if failed, ok := msg.(downloadErrorMsg); !ok || !strings.HasPrefix(string(failed), "Checksum verification failed for tampered.bin") {
+		t.Errorf("tampered blob: got %#v", msg)
+	}
+	if _, err := os.Stat("tampered.bin"); !os.IsNotExist(err) {
+		t.Error("tampered blob kept")
+	}
+
+	// A manifest fetched by digest must have that digest
+	forged := ociDigest([]byte("another manifest"))
+	registry.mu.Lock()
+	registry.manifests[forged] = registry.manifests["1.0"]
+	registry.mu.Unlock()
+	ref := registry.reference(t, "1.0")
+	ref.Reference = forged
+	if msg, ok := fetchOCIAssets(ref)().(errorMsg); !ok || !strings.Contains(string(msg), "manifest digest mismatch") {
+		t.Errorf("forged manifest: got %#v", msg)
+	}
+}
+
+func TestSelectPlatformManifest(t *testing.T) {
+	var index ociManifest
+	if err := json.Unmarshal([]byte(`{"manifests": [
+		{"digest": "sha256:a", "platform": {"os": "plan9", "architecture": "mips"}},
+		{"digest": "sha256:b", "platform": {"os": "plan9", "architecture": "arm"}}
+	]}`), &index); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := selectPlatformManifest(&index); err == nil {
+		t.Error("manifest of another platform selected")
+	}
+	index.Manifests = append(index.Manifests, ociDescriptor{Digest: "sha256:c"})
+	if desc, err := selectPlatformManifest(&index); err != nil || desc.Digest != "sha256:c" {
+		t.Errorf("got %v, %v, want the manifest without a platform", desc.Digest, err)
+	}
+}
+
+func TestRegistryBasicChallenge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+	ref, err := parseOCIReference("oci://" + strings.TrimPrefix(server.URL, "http://") + "/org/tool:1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := newRegistryClient(ref, &Config{}).fetchManifest(context.Background(), "1.0"); err == nil || !strings.Contains(err.Error(), "requires credentials") {
+		t.Errorf("without credentials: got %v", err)
+	}
+	config := &Config{RegistryHost: ref.Registry, RegistryUsername: "user", RegistryPassword: "wrong"}
+	if _, err := newRegistryClient(ref, config).fetchManifest(context.Background(), "1.0"); err == nil || !strings.Contains(err.Error(), "rejected the configured credentials") {
+		t.Errorf("wrong credentials: got %v", err)
+	}
+}
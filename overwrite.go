@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Native-Robotics/asset-fetch/afetch"
+)
+
+// OverwritePolicy decides what happens when a download's file already exists
+type OverwritePolicy string
+
+const (
+	// Keep an existing file whose digest matches the asset, else overwrite it
+	PolicySkipSameDigest OverwritePolicy = "skip-if-same-digest"
+	PolicyOverwrite      OverwritePolicy = "overwrite"
+	// Save the download as "name (1).ext", "name (2).ext", ...
+	PolicyRename OverwritePolicy = "rename"
+	// Ask in the TUI; non-interactive downloads fail instead
+	PolicyAsk OverwritePolicy = "ask"
+)
+
+// parseOverwritePolicy parses an OVERWRITE_POLICY value
+func parseOverwritePolicy(value string) (OverwritePolicy, error) {
+	switch policy := OverwritePolicy(strings.ToLower(value)); policy {
+	case PolicySkipSameDigest, PolicyOverwrite, PolicyRename, PolicyAsk:
+		return policy, nil
+	}
+	return "", fmt.Errorf("invalid OVERWRITE_POLICY %q, expected %s, %s, %s or %s", value, PolicySkipSameDigest, PolicyOverwrite, PolicyRename, PolicyAsk)
+}
+
+// overwritePolicy returns the configured policy, skip-if-same-digest by default
+func (c *Config) overwritePolicy() OverwritePolicy {
+	if c.OverwritePolicy == "" {
+		return PolicySkipSameDigest
+	}
+	return c.OverwritePolicy
+}
+
+// TargetPath returns the path the asset ends up at: the file, or the
+// directory a workflow artifact is extracted into
+func (a AssetInfo) TargetPath() string {
+	if a.Artifact {
+		return artifactDirName(a.Name)
+	}
+	return a.LocalFileName()
+}
+
+// partialFileName returns the temporary file an asset is downloaded to,
+// next to its target, before it is verified and renamed
+func partialFileName(asset AssetInfo) string {
+	return afetch.PartialPath(asset.LocalFileName())
+}
+
+// targetExists reports whether the target of asset is already present
+func targetExists(asset AssetInfo) bool {
+	_, err := os.Stat(asset.TargetPath())
+	return err == nil
+}
+
+// resolveTarget applies policy to the target of asset. It returns the path
+// to save the download at, or upToDate when the existing file already has
+// the asset's digest.
+func resolveTarget(asset AssetInfo, policy OverwritePolicy) (target string, upToDate bool, err error) {
+	target = asset.TargetPath()
+	info, statErr := os.Stat(target)
+	if statErr != nil {
+		return target, false, nil
+	}
+
+	switch policy {
+	case PolicySkipSameDigest:
+		// Directories of extracted artifacts have no single digest
+		if asset.Digest != "" && !info.IsDir() && afetch.VerifyDigest(target, asset.Digest) == nil {
+			return target, true, nil
+		}
+		return target, false, nil
+	case PolicyRename:
+		return uniqueFileName(target), false, nil
+	case PolicyAsk:
+		return "", false, fmt.Errorf("%s already exists", target)
+	default:
+		return target, false, nil
+	}
+}
+
+// uniqueFileName returns the first of "name (1).ext", "name (2).ext", ...
+// that does not exist; the extension starts at the first dot of the base name
+func uniqueFileName(target string) string {
+	dir, base := filepath.Split(target)
+	stem, ext := base, ""
+	if i := strings.Index(base[1:], "."); i >= 0 {
+		stem, ext = base[:i+1], base[i+1:]
+	}
+	for n := 1; ; n++ {
+		candidate := filepath.Join(dir, fmt.Sprintf("%s (%d)%s", stem, n, ext))
+		if _, err := os.Stat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/Native-Robotics/asset-fetch/afetch"
+)
+
+// Upper bounds for repository browsing requests
+const (
+	maxRepositoryPages         = 10
+	repositoryReleaseCheckJobs = 8
+
+	// Repositories checked for releases without a token, out of the 60
+	// unauthenticated requests allowed per hour
+	unauthenticatedReleaseChecks = 10
+)
+
+// repositoryReleases is the result of checking one repository for releases
+type repositoryReleases struct {
+	latest  string // tag of the most recent release; "" if there are none
+	failed  bool
+	skipped bool // not checked, as no token is configured
+}
+
+// fetchRepositories lists the repositories of owner (a user or an
+// organization), or those accessible to the authenticated user if owner is empty
+func fetchRepositories(owner string) tea.Cmd {
+	return func() tea.Msg {
+		var token string
+		if config, err := loadConfig(); err == nil {
+			token = config.GitHubToken
+		}
+
+		var apiURLs []string
+		if owner == "" {
+			if token == "" {
+				return errorMsg("set REPO_OWNER, pass a GitHub user or organization URL, or configure GITHUB_TOKEN to browse repositories")
+			}
+			apiURLs = []string{githubAPIURL + "/user/repos?per_page=100&sort=pushed"}
+		} else {
+			// The organization listing includes private repositories visible to
+			// the token; personal accounts are only served by the user listing
+			apiURLs = []string{
+				fmt.Sprintf("%s/orgs/%s/repos?per_page=100&type=all&sort=pushed", githubAPIURL, url.PathEscape(owner)),
+				fmt.Sprintf("%s/users/%s/repos?per_page=100&sort=pushed", githubAPIURL, url.PathEscape(owner)),
+			}
+		}
+
+		for _, apiURL := range apiURLs {
+			repos, status, err := fetchRepositoryPages(httpClient, apiURL, token)
+			if status == http.StatusNotFound {
+				continue
+			}
+			if err != nil {
+				return errorMsg(err.Error())
+			}
+			if len(repos) == 0 {
+				return errorMsg(fmt.Sprintf("no repositories found for %s", owner))
+			}
+			return repositoriesMsg(repos)
+		}
+		return errorMsg(fmt.Sprintf("user or organization %s not found", owner))
+	}
+}
+
+// fetchRepositoryPages follows pagination of a repository listing and
+// returns the collected repositories with the last HTTP status code
+func fetchRepositoryPages(client *http.Client, apiURL, token string) ([]Repository, int, error) {
+	var repos []Repository
+	for page := 0; apiURL != "" && page < maxRepositoryPages; page++ {
+		req, err := afetch.NewRequest(context.Background(), apiURL, token)
+		if err != nil {
+			return nil, 0, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			_ = resp.Body.Close()
+			return nil, resp.StatusCode, fmt.Errorf("GitHub API error: %d", resp.StatusCode)
+		}
+		var pageRepos []Repository
+		err = json.NewDecoder(resp.Body).Decode(&pageRepos)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, resp.StatusCode, err
+		}
+		repos = append(repos, pageRepos...)
+		apiURL = afetch.NextPageURL(resp)
+	}
+	return repos, http.StatusOK, nil
+}
+
+// checkRepositoryReleases starts looking up the latest release of each
+// repository and returns the channel the results are delivered on, one
+// message per repository. Without a token, only the first repositories are
+// checked to stay within the unauthenticated rate limit.
+func checkRepositoryReleases(repos []Repository) <-chan repositoryReleasesMsg {
+	names := make([]string, len(repos))
+	for i, repo := range repos {
+		names[i] = repo.FullName
+	}
+	// Buffered for all results, so an abandoned check never blocks
+	checks := make(chan repositoryReleasesMsg, len(names))
+
+	go func() {
+		defer close(checks)
+		var token string
+		if config, err := loadConfig(); err == nil {
+			token = config.GitHubToken
+		}
+
+		var wg sync.WaitGroup
+		jobs := make(chan string)
+		for i := 0; i < repositoryReleaseCheckJobs; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for fullName := range jobs {
+					latest, err := fetchLatestReleaseTag(httpClient, fullName, token)
+					checks <- repositoryReleasesMsg{checks: checks, fullName: fullName, result: repositoryReleases{latest: latest, failed: err != nil}}
+				}
+			}()
+		}
+		for i, fullName := range names {
+			if token == "" && i >= unauthenticatedReleaseChecks {
+				checks <- repositoryReleasesMsg{checks: checks, fullName: fullName, result: repositoryReleases{skipped: true}}
+				continue
+			}
+			jobs <- fullName
+		}
+		close(jobs)
+		wg.Wait()
+	}()
+	return checks
+}
+
+// waitForRepositoryReleases delivers the next release check result
+func waitForRepositoryReleases(checks <-chan repositoryReleasesMsg) tea.Cmd {
+	if checks == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		msg, ok := <-checks
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
+// fetchLatestReleaseTag returns the tag of the most recent release of the
+// repository, or "" if it has none
+func fetchLatestReleaseTag(client *http.Client, fullName, token string) (string, error) {
+	apiURL := fmt.Sprintf("%s/repos/%s/releases?per_page=1", githubAPIURL, fullName)
+	req, err := afetch.NewRequest(context.Background(), apiURL, token)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GitHub API error: %d", resp.StatusCode)
+	}
+	var releases []Release
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return "", err
+	}
+	if len(releases) == 0 {
+		return "", nil
+	}
+	return releases[0].TagName, nil
+}
+
+// applyRepositoryReleases records a release check result on repos
+func applyRepositoryReleases(repos []Repository, msg repositoryReleasesMsg) {
+	for i := range repos {
+		if repos[i].FullName == msg.fullName {
+			repos[i].ReleasesChecked = !msg.result.failed && !msg.result.skipped
+			repos[i].ReleaseCheckFailed = msg.result.failed
+			repos[i].ReleaseCheckSkipped = msg.result.skipped
+			repos[i].LatestRelease = msg.result.latest
+		}
+	}
+}
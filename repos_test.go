@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Native-Robotics/asset-fetch/internal/githubtest"
+)
+
+// collectReleaseChecks waits for all release check results of a batch
+func collectReleaseChecks(checks <-chan repositoryReleasesMsg) map[string]repositoryReleases {
+	results := make(map[string]repositoryReleases)
+	for msg := range checks {
+		if msg.checks != checks {
+			panic("result delivered with another batch")
+		}
+		results[msg.fullName] = msg.result
+	}
+	return results
+}
+
+func TestCheckRepositoryReleases(t *testing.T) {
+	server := setupFakeGitHub(t)
+	var repos []Repository
+	for i := 0; i < unauthenticatedReleaseChecks+2; i++ {
+		name := fmt.Sprintf("owner/repo%d", i)
+		server.AddRelease(name, githubtest.Release{Tag: fmt.Sprintf("v%d", i)})
+		repos = append(repos, Repository{FullName: name})
+	}
+	repos = append(repos, Repository{FullName: "owner/missing"})
+
+	// Without a token, the checks are capped to spare the rate limit
+	results := collectReleaseChecks(checkRepositoryReleases(repos))
+	if len(results) != len(repos) {
+		t.Fatalf("%d results for %d repositories", len(results), len(repos))
+	}
+	for i, repo := range repos {
+		if skipped := results[repo.FullName].skipped; skipped != (i >= unauthenticatedReleaseChecks) {
+			t.Errorf("%s: skipped = %v", repo.FullName, skipped)
+		}
+	}
+
+	server.Token = "secret"
+	writeConfig(t, `GITHUB_TOKEN="secret"`)
+	results = collectReleaseChecks(checkRepositoryReleases(repos))
+	for i, repo := range repos[:len(repos)-1] {
+		if result := results[repo.FullName]; result != (repositoryReleases{latest: fmt.Sprintf("v%d", i)}) {
+			t.Errorf("%s: %+v", repo.FullName, result)
+		}
+	}
+	if !results["owner/missing"].failed {
+		t.Errorf("missing repository: %+v", results["owner/missing"])
+	}
+
+	applyRepositoryReleases(repos, repositoryReleasesMsg{fullName: "owner/repo0", result: repositoryReleases{latest: "v0"}})
+	if !repos[0].ReleasesChecked || repos[0].LatestRelease != "v0" || repos[1].ReleasesChecked {
+		t.Errorf("applied to %+v", repos[:2])
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/Native-Robotics/asset-fetch/afetch"
+)
+
+// Number of repository search results requested; each result costs one more
+// request to look up its latest release
+const searchResultsPerPage = 30
+
+// repositorySearchResponse is the body of the GitHub repository search API
+type repositorySearchResponse struct {
+	TotalCount int          `json:"total_count"`
+	Items      []Repository `json:"items"`
+}
+
+// searchResultsMsg carries the outcome of a repository search
+type searchResultsMsg struct {
+	query string
+	total int
+	repos []Repository
+	err   error
+}
+
+// searchRepositories queries the GitHub repository search API
+func searchRepositories(query string) tea.Cmd {
+	return func() tea.Msg {
+		var token string
+		if config, err := loadConfig(); err == nil {
+			token = config.GitHubToken
+		}
+
+		apiURL := fmt.Sprintf("%s/search/repositories?q=%s&per_page=%d", githubAPIURL, url.QueryEscape(query), searchResultsPerPage)
+		req, err := afetch.NewRequest(context.Background(), apiURL, token)
+		if err != nil {
+			return searchResultsMsg{query: query, err: err}
+		}
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return searchResultsMsg{query: query, err: err}
+		}
+		defer func() {
+			_ = resp.Body.Close()
+		}()
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+		case http.StatusForbidden, http.StatusTooManyRequests:
+			return searchResultsMsg{query: query, err: fmt.Errorf("GitHub search rate limit exceeded, try again later or configure GITHUB_TOKEN")}
+		case http.StatusUnprocessableEntity:
+			return searchResultsMsg{query: query, err: fmt.Errorf("invalid search query %q", query)}
+		default:
+			return searchResultsMsg{query: query, err: fmt.Errorf("GitHub API error: %d", resp.StatusCode)}
+		}
+
+		var result repositorySearchResponse
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return searchResultsMsg{query: query, err: err}
+		}
+		return searchResultsMsg{query: query, total: result.TotalCount, repos: result.Items}
+	}
+}
@@ -0,0 +1,621 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Native-Robotics/asset-fetch/afetch"
+)
+
+// Paths served from the asset store: an asset by ID, as requested by afetch
+// with "Accept: application/octet-stream", and a browser download URL
+var (
+	assetPathPattern    = regexp.MustCompile(`^/repos/([^/]+)/([^/]+)/releases/assets/(\d+)$`)
+	downloadPathPattern = regexp.MustCompile(`^/([^/]+)/([^/]+)/releases/download/([^/]+)/([^/]+)$`)
+)
+
+// downloadURLPattern matches browser download URLs in API responses, which
+// point at the GitHub website rather than the API
+var downloadURLPattern = regexp.MustCompile(`("browser_download_url":\s*")[^"]*?(/[^/"]+/[^/"]+/releases/download/[^"]*")`)
+
+// maxAssetIdentities caps the credentials remembered for a stored asset;
+// the oldest ones are dropped and have to be checked upstream again
+const maxAssetIdentities = 100
+
+// apiPathPatterns are the API paths forwarded upstream: releases, their
+// assets, tags, source archives and the attestations of asset digests.
+// Everything else, such as /user or repository contents, is refused.
+var apiPathPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^/repos/[^/]+/[^/]+/releases$`),
+	regexp.MustCompile(`^/repos/[^/]+/[^/]+/releases/(latest|\d+|tags/.+)$`),
+	assetPathPattern,
+	regexp.MustCompile(`^/repos/[^/]+/[^/]+/tags$`),
+	regexp.MustCompile(`^/repos/[^/]+/[^/]+/(tarball|zipball)/.+$`),
+	regexp.MustCompile(`^/repos/[^/]+/[^/]+/attestations/sha256:[0-9a-f]{64}$`),
+}
+
+// proxyServer answers GitHub API requests from upstream, keeping release
+// assets in a digest-keyed store and API responses as a fallback for when
+// upstream is unreachable or rate limited
+type proxyServer struct {
+	upstream string
+	store    string
+	client   *http.Client
+
+	locksMu sync.Mutex
+	locks   map[string]*assetLock // owner/repo/asset ID -> lock of its download
+
+	// token is sent upstream for callers presenting clientToken; other
+	// callers are forwarded with their own credentials
+	token       string
+	clientToken string
+}
+
+// assetLock serializes the requests for one asset; it is removed from
+// proxyServer.locks once no request holds or waits for it
+type assetLock struct {
+	sync.Mutex
+	users int
+}
+
+// credential is how a request is authorized upstream
+type credential struct {
+	authorization string
+	// identity keys stored responses and assets, so they are only served
+	// to callers with the same credential; "" is anonymous
+	identity string
+}
+
+// parseServeArgs parses "serve [--listen ADDR] [--store DIR] [--upstream URL]"
+func parseServeArgs(args []string) (string, *proxyServer, error) {
+	server := &proxyServer{client: httpClient, locks: make(map[string]*assetLock)}
+	var listen string
+	store := "afetch-store"
+	if cacheDir, err := os.UserCacheDir(); err == nil {
+		store = filepath.Join(cacheDir, "afetch", "store")
+	}
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags.StringVar(&listen, "listen", "127.0.0.1:8080", "address to listen on")
+	flags.StringVar(&server.store, "store", store, "directory of cached assets and API responses")
+	flags.StringVar(&server.upstream, "upstream", afetch.DefaultAPIURL, "GitHub API to fetch from")
+	if err := flags.Parse(args); err != nil {
+		return "", nil, err
+	}
+	if flags.NArg() != 0 {
+		return "", nil, fmt.Errorf("unexpected argument %q", flags.Arg(0))
+	}
+	server.upstream = strings.TrimRight(server.upstream, "/")
+	return listen, server, nil
+}
+
+// runServe runs the caching proxy until interrupted. It returns the process
+// exit status.
+func runServe(args []string, config *Config) int {
+	listen, server, err := parseServeArgs(args)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		fmt.Println("usage: afetch serve [--listen ADDR] [--store DIR] [--upstream URL]")
+		return 2
+	}
+	// Callers presenting the client token are served with the proxy's own
+	// token
+	server.token = config.GitHubToken
+	server.clientToken = config.ServeClientToken
+	if err := server.initStore(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	httpServer := &http.Server{Addr: listen, Handler: server}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = httpServer.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("Serving %s on %s, store %s", server.upstream, listen, server.store)
+	if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Printf("Error: %v", err)
+		return 1
+	}
+	return 0
+}
+
+// initStore creates the directories of the store
+func (s *proxyServer) initStore() error {
+	for _, dir := range []string{"blobs", "assets", "api", "tmp"} {
+		if err := os.MkdirAll(filepath.Join(s.store, dir), 0755); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *proxyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	// Paths with "." or ".." segments could reach other endpoints upstream
+	if path.Clean(r.URL.Path) != r.URL.Path {
+		http.NotFound(w, r)
+		return
+	}
+	cred := s.credential(r)
+
+	if match := assetPathPattern.FindStringSubmatch(r.URL.Path); match != nil && r.Header.Get("Accept") == "application/octet-stream" {
+		s.serveAsset(w, r, match[1], match[2], match[3], cred)
+		return
+	}
+	if match := downloadPathPattern.FindStringSubmatch(r.URL.Path); match != nil {
+		s.serveDownload(w, r, match[1], match[2], match[3], match[4], cred)
+		return
+	}
+	for _, pattern := range apiPathPatterns {
+		if pattern.MatchString(r.URL.Path) {
+			s.serveAPI(w, r, cred)
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+// credential returns how r is authorized upstream: with the proxy's token
+// if it presents the client token, otherwise with its own Authorization
+// header, if any
+func (s *proxyServer) credential(r *http.Request) credential {
+	header := r.Header.Get("Authorization")
+	switch {
+	case header == "":
+		return credential{}
+	case s.clientToken != "" && subtle.ConstantTimeCompare([]byte(header), []byte("Bearer "+s.clientToken)) == 1:
+		cred := credential{identity: "client"}
+		if s.token != "" {
+			cred.authorization = "Bearer " + s.token
+		}
+		return cred
+	}
+	return credential{authorization: header, identity: cacheKey(header)}
+}
+
+// newUpstreamRequest creates a request for requestURI upstream, authorized
+// by cred
+func (s *proxyServer) newUpstreamRequest(ctx context.Context, requestURI string, cred credential) (*http.Request, error) {
+	req, err := afetch.NewRequest(ctx, s.upstream+requestURI, "")
+	if err != nil {
+		return nil, err
+	}
+	if cred.authorization != "" {
+		req.Header.Set("Authorization", cred.authorization)
+	}
+	return req, nil
+}
+
+// serveAPI forwards an API request upstream and rewrites upstream URLs in
+// the response to point at the proxy. Other content, such as source
+// archives, is streamed through unchanged.
+func (s *proxyServer) serveAPI(w http.ResponseWriter, r *http.Request, cred credential) {
+	resp, body, err := s.fetchAPI(r.Context(), r.URL.RequestURI(), r.Header.Get("Accept"), cred)
+	if err != nil {
+		log.Printf("%s %s: %v", r.Method, r.URL.RequestURI(), err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	if body == nil {
+		defer resp.Body.Close()
+	}
+
+	base := proxyBaseURL(r)
+	for _, key := range []string{"Content-Type", "Content-Length", "Content-Disposition", "Link", "Location", "ETag", "Last-Modified", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"} {
+		if value := resp.Header.Get(key); value != "" && (body == nil || key != "Content-Length") {
+			w.Header().Set(key, strings.ReplaceAll(value, s.upstream, base))
+		}
+	}
+	if body != nil {
+		body = bytes.ReplaceAll(body, []byte(s.upstream), []byte(base))
+		body = downloadURLPattern.ReplaceAll(body, []byte("${1}"+base+"${2}"))
+	}
+	w.WriteHeader(resp.StatusCode)
+	if r.Method != http.MethodGet {
+		return
+	}
+	if body != nil {
+		_, _ = w.Write(body)
+	} else {
+		_, _ = io.Copy(w, resp.Body)
+	}
+}
+
+// fetchAPI fetches requestURI from upstream. JSON responses are returned
+// read into body; successful ones are stored and served instead when
+// upstream fails or is rate limited, to callers with the same credential.
+// For other responses body is nil and the caller closes resp.Body.
+func (s *proxyServer) fetchAPI(ctx context.Context, requestURI, accept string, cred credential) (*http.Response, []byte, error) {
+	cacheFile := filepath.Join(s.store, "api", cacheKey(cred.identity+"\n"+requestURI+"\n"+accept)+".json")
+
+	req, err := s.newUpstreamRequest(ctx, requestURI, cred)
+	if err != nil {
+		return nil, nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	resp, err := s.client.Do(req)
+	if err == nil && !upstreamUnavailable(resp) {
+		if !isJSONResponse(resp) {
+			return resp, nil, nil
+		}
+		body, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+		if resp.StatusCode == http.StatusOK {
+			if writeErr := writeFileAtomic(cacheFile, body, filepath.Join(s.store, "tmp")); writeErr != nil {
+				log.Printf("Caching %s: %v", requestURI, writeErr)
+			}
+		}
+		return resp, body, nil
+	}
+
+	// Fall back to the last response stored for this request
+	var body []byte
+	if err == nil {
+		body, err = io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+	}
+	cached, cacheErr := os.ReadFile(cacheFile)
+	if cacheErr != nil {
+		if err != nil {
+			return nil, nil, err
+		}
+		return resp, body, nil
+	}
+	log.Printf("Serving %s from cache: upstream unavailable", requestURI)
+	cachedResp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}
+	cachedResp.Header.Set("Content-Type", "application/json; charset=utf-8")
+	return cachedResp, cached, nil
+}
+
+// fetchJSON fetches a JSON API document through fetchAPI. An unexpected
+// upstream status is returned as an *afetch.APIError.
+func (s *proxyServer) fetchJSON(ctx context.Context, requestURI string, cred credential) ([]byte, error) {
+	resp, body, err := s.fetchAPI(ctx, requestURI, "", cred)
+	if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("unexpected content type %q", resp.Header.Get("Content-Type"))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &afetch.APIError{StatusCode: resp.StatusCode, URL: requestURI}
+	}
+	return body, nil
+}
+
+// errorStatus returns the status to answer a failed upstream request with:
+// upstream's own status for API errors, such as 404 for assets the caller
+// cannot read, and 502 otherwise
+func errorStatus(err error) int {
+	var apiErr *afetch.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return http.StatusBadGateway
+}
+
+// serveDownload serves a browser download URL by looking up the asset in
+// the release of tag
+func (s *proxyServer) serveDownload(w http.ResponseWriter, r *http.Request, owner, repo, tag, name string, cred credential) {
+	body, err := s.fetchJSON(r.Context(), fmt.Sprintf("/repos/%s/%s/releases/tags/%s", owner, repo, tag), cred)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("release %s: %v", tag, err), errorStatus(err))
+		return
+	}
+	var release Release
+	if err := json.Unmarshal(body, &release); err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	for _, asset := range release.Assets {
+		if asset.Name == name {
+			s.serveAsset(w, r, owner, repo, fmt.Sprint(asset.ID), cred)
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+// serveAsset serves a release asset from the store, fetching it from
+// upstream first on a miss. Range requests are answered from the store.
+func (s *proxyServer) serveAsset(w http.ResponseWriter, r *http.Request, owner, repo, id string, cred credential) {
+	// Concurrent requests for one asset download it once
+	unlock := s.lockAsset(strings.ToLower(owner + "/" + repo + "/" + id))
+	blob, asset, err := s.storedAsset(r.Context(), owner, repo, id, cred)
+	unlock()
+	if err != nil {
+		log.Printf("Asset %s/%s %s: %v", owner, repo, id, err)
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	file, err := os.Open(blob)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if asset.Name != "" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", asset.Name))
+	}
+	http.ServeContent(w, r, "", info.ModTime(), file)
+}
+
+// lockAsset locks the asset with key and returns the function unlocking it
+func (s *proxyServer) lockAsset(key string) func() {
+	s.locksMu.Lock()
+	lock, ok := s.locks[key]
+	if !ok {
+		lock = &assetLock{}
+		s.locks[key] = lock
+	}
+	lock.users++
+	s.locksMu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		s.locksMu.Lock()
+		lock.users--
+		if lock.users == 0 {
+			delete(s.locks, key)
+		}
+		s.locksMu.Unlock()
+	}
+}
+
+// storedAsset returns the blob of asset id of owner/repo and its metadata,
+// downloading it into the store when it is not there yet
+func (s *proxyServer) storedAsset(ctx context.Context, owner, repo, id string, cred credential) (string, Asset, error) {
+	indexFile := filepath.Join(s.store, "assets", strings.ToLower(owner), strings.ToLower(repo), id+".json")
+
+	// Assets already fetched anonymously or with the caller's credential
+	// are served without asking upstream
+	entry, stored := s.readAssetEntry(indexFile, owner, repo)
+	if stored && (slices.Contains(entry.Identities, "") || slices.Contains(entry.Identities, cred.identity)) {
+		return s.blobPath(entry.Digest), entry.Asset, nil
+	}
+
+	// Otherwise upstream decides whether the caller may read the asset
+	var asset Asset
+	assetPath := fmt.Sprintf("/repos/%s/%s/releases/assets/%s", owner, repo, id)
+	body, err := s.fetchJSON(ctx, assetPath, cred)
+	if err != nil {
+		return "", asset, err
+	}
+	if err := json.Unmarshal(body, &asset); err != nil {
+		return "", asset, err
+	}
+	previous := entry
+	entry = storedAssetEntry{
+		Owner:      owner,
+		Repo:       repo,
+		Asset:      asset,
+		Digest:     asset.Digest,
+		Identities: addIdentity(previous.Identities, cred.identity),
+	}
+	if stored && entry.Digest == "" {
+		// Asset IDs are not reused, so the content is the one stored
+		entry.Digest = previous.Digest
+	}
+
+	// Another asset, or another release, may have the same content
+	if blob := s.blobPath(entry.Digest); blob != "" {
+		if _, err := os.Stat(blob); err == nil {
+			return blob, asset, s.indexAsset(indexFile, entry)
+		}
+	}
+
+	entry.Digest, err = s.downloadBlob(ctx, assetPath, asset, cred)
+	if err != nil {
+		return "", asset, err
+	}
+	log.Printf("Stored %s/%s %s as %s", owner, repo, asset.Name, entry.Digest)
+	return s.blobPath(entry.Digest), asset, s.indexAsset(indexFile, entry)
+}
+
+// storedAssetEntry maps an asset of a repository to the digest of its
+// stored blob
+type storedAssetEntry struct {
+	Owner  string `json:"owner"`
+	Repo   string `json:"repo"`
+	Asset  Asset  `json:"asset"`
+	Digest string `json:"digest"`
+	// Identities of the credentials the asset was fetched with; "" means
+	// it is readable anonymously
+	Identities []string `json:"identities"`
+}
+
+// readAssetEntry reads the index entry of an asset of owner/repo, reporting
+// whether it exists, belongs to owner/repo and its blob is in the store
+func (s *proxyServer) readAssetEntry(indexFile, owner, repo string) (storedAssetEntry, bool) {
+	var entry storedAssetEntry
+	content, err := os.ReadFile(indexFile)
+	if err != nil || json.Unmarshal(content, &entry) != nil {
+		return storedAssetEntry{}, false
+	}
+	if !strings.EqualFold(entry.Owner, owner) || !strings.EqualFold(entry.Repo, repo) {
+		return storedAssetEntry{}, false
+	}
+	blob := s.blobPath(entry.Digest)
+	if blob == "" {
+		return storedAssetEntry{}, false
+	}
+	if _, err := os.Stat(blob); err != nil {
+		return storedAssetEntry{}, false
+	}
+	return entry, true
+}
+
+// addIdentity adds identity to the identities of an asset entry, keeping the
+// most recent maxAssetIdentities. Anonymous access ("") makes the others
+// unnecessary.
+func addIdentity(identities []string, identity string) []string {
+	switch {
+	case identity == "" || slices.Contains(identities, ""):
+		return []string{""}
+	case slices.Contains(identities, identity):
+		return identities
+	}
+	identities = append(identities, identity)
+	if len(identities) > maxAssetIdentities {
+		identities = identities[len(identities)-maxAssetIdentities:]
+	}
+	return identities
+}
+
+func (s *proxyServer) indexAsset(indexFile string, entry storedAssetEntry) error {
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(indexFile), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(indexFile, content, filepath.Join(s.store, "tmp"))
+}
+
+// downloadBlob downloads an asset from upstream into the store and returns
+// its sha256 digest, checked against the digest reported by GitHub
+func (s *proxyServer) downloadBlob(ctx context.Context, assetPath string, asset Asset, cred credential) (string, error) {
+	req, err := s.newUpstreamRequest(ctx, assetPath, cred)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/octet-stream")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("HTTP error: %s", resp.Status)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Join(s.store, "tmp"), "blob-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	hasher := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, hasher), resp.Body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	digest := "sha256:" + hex.EncodeToString(hasher.Sum(nil))
+	if asset.Digest != "" {
+		if err := afetch.VerifyDigest(tmp.Name(), asset.Digest); err != nil {
+			return "", err
+		}
+	}
+	blob := s.blobPath(digest)
+	if err := os.MkdirAll(filepath.Dir(blob), 0755); err != nil {
+		return "", err
+	}
+	return digest, os.Rename(tmp.Name(), blob)
+}
+
+// blobPath returns the store path of a "sha256:<hex>" digest, or "" for
+// other digests
+func (s *proxyServer) blobPath(digest string) string {
+	hexDigest, ok := strings.CutPrefix(digest, "sha256:")
+	if !ok || len(hexDigest) != sha256.Size*2 {
+		return ""
+	}
+	if _, err := hex.DecodeString(hexDigest); err != nil {
+		return ""
+	}
+	return filepath.Join(s.store, "blobs", "sha256", hexDigest)
+}
+
+// proxyBaseURL returns the URL clients reached the proxy at
+func proxyBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// upstreamUnavailable reports whether a response is a server error or a
+// rate limit, for which a stored response is preferred
+func upstreamUnavailable(resp *http.Response) bool {
+	switch {
+	case resp.StatusCode >= 500, resp.StatusCode == http.StatusTooManyRequests:
+		return true
+	case resp.StatusCode == http.StatusForbidden:
+		return resp.Header.Get("X-RateLimit-Remaining") == "0"
+	}
+	return false
+}
+
+func isJSONResponse(resp *http.Response) bool {
+	return strings.Contains(resp.Header.Get("Content-Type"), "json")
+}
+
+// cacheKey returns a file name for an arbitrary request key
+func cacheKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// writeFileAtomic writes content to filename through a temporary file in
+// tmpDir, so readers never see a partial file
+func writeFileAtomic(filename string, content []byte, tmpDir string) error {
+	tmp, err := os.CreateTemp(tmpDir, "write-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
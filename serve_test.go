@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Native-Robotics/asset-fetch/internal/githubtest"
+)
+
+// newTestProxy starts "afetch serve" in front of a fake GitHub API
+func newTestProxy(t *testing.T) (*githubtest.Server, *proxyServer, *httptest.Server) {
+	t.Helper()
+	upstream := githubtest.NewServer(t)
+	upstream.AddRelease("owner/repo", githubtest.Release{Tag: "v1", Assets: []githubtest.Asset{
+		{Name: "app.tar.gz", Content: []byte("0123456789")},
+		{Name: "tampered.bin", Content: []byte("tampered"), Digest: githubtest.Digest([]byte("original"))},
+	}})
+	_, server, err := parseServeArgs([]string{"--store", t.TempDir(), "--upstream", upstream.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.initStore(); err != nil {
+		t.Fatal(err)
+	}
+	proxy := httptest.NewServer(server)
+	t.Cleanup(proxy.Close)
+	return upstream, server, proxy
+}
+
+// proxyGet requests path from the proxy with the given header pairs and
+// returns the status and body
+func proxyGet(t *testing.T, proxy *httptest.Server, path string, header ...string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest("GET", proxy.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+// storageRequests counts the downloads of asset content from upstream
+func storageRequests(upstream *githubtest.Server) int {
+	count := 0
+	for _, request := range upstream.Requests() {
+		if strings.HasPrefix(request.Path, "/storage/") {
+			count++
+		}
+	}
+	return count
+}
+
+func TestServeAssetFromStore(t *testing.T) {
+	upstream, _, proxy := newTestProxy(t)
+	assetPath := strings.TrimPrefix(upstream.AssetURL("owner/repo", "v1", "app.tar.gz"), upstream.URL)
+
+	status, body := proxyGet(t, proxy, assetPath, "Accept", "application/octet-stream")
+	if status != http.StatusOK || body != "0123456789" {
+		t.Fatalf("first request: %d %q", status, body)
+	}
+
+	// Later requests, including ranges, are answered from the store even
+	// when upstream fails
+	upstream.Fail(assetPath, http.StatusInternalServerError)
+	status, body = proxyGet(t, proxy, assetPath, "Accept", "application/octet-stream", "Range", "bytes=4-")
+	if status != http.StatusPartialContent || body != "456789" {
+		t.Errorf("range request: %d %q", status, body)
+	}
+	status, body = proxyGet(t, proxy, "/owner/repo/releases/download/v1/app.tar.gz")
+	if status != http.StatusOK || body != "0123456789" {
+		t.Errorf("download URL: %d %q", status, body)
+	}
+	if n := storageRequests(upstream); n != 1 {
+		t.Errorf("asset downloaded %d times from upstream, want once", n)
+	}
+}
+
+func TestServeRejectsDigestMismatch(t *testing.T) {
+	upstream, server, proxy := newTestProxy(t)
+	assetPath := strings.TrimPrefix(upstream.AssetURL("owner/repo", "v1", "tampered.bin"), upstream.URL)
+
+	if status, _ := proxyGet(t, proxy, assetPath, "Accept", "application/octet-stream"); status != http.StatusBadGateway {
+		t.Errorf("status = %d, want 502", status)
+	}
+	blobs, _ := os.ReadDir(filepath.Join(server.store, "blobs", "sha256"))
+	if len(blobs) != 0 {
+		t.Errorf("mismatching blob stored: %v", blobs)
+	}
+}
+
+func TestServeAPIFallsBackToStore(t *testing.T) {
+	upstream, _, proxy := newTestProxy(t)
+
+	status, body := proxyGet(t, proxy, "/repos/owner/repo/releases")
+	if status != http.StatusOK || !strings.Contains(body, proxy.URL+"/repos/owner/repo/releases/assets/") {
+		t.Fatalf("release list: %d, asset URLs not rewritten to the proxy: %s", status, body)
+	}
+	if strings.Contains(body, upstream.URL) {
+		t.Errorf("upstream URL left in %s", body)
+	}
+
+	upstream.Fail("/repos/owner/repo/releases", http.StatusServiceUnavailable)
+	if status, cached := proxyGet(t, proxy, "/repos/owner/repo/releases"); status != http.StatusOK || cached != body {
+		t.Errorf("upstream failing: %d %s, want the stored response", status, cached)
+	}
+
+	// Responses are stored per credential
+	if status, _ := proxyGet(t, proxy, "/repos/owner/repo/releases", "Authorization", "Bearer other"); status != http.StatusServiceUnavailable {
+		t.Errorf("other credential: status = %d, want upstream's 503", status)
+	}
+}
+
+func TestServeRefusesOtherRoutes(t *testing.T) {
+	upstream, _, proxy := newTestProxy(t)
+
+	for _, path := range []string{
+		"/user",
+		"/user/repos",
+		"/repos/owner/repo/contents/secret.txt",
+		"/repos/owner/repo/releases/tags/../../../../user",
+		"/repos/owner/repo/releases/tags/%2e%2e/%2e%2e/%2e%2e/%2e%2e/user",
+	} {
+		if status, _ := proxyGet(t, proxy, path); status != http.StatusNotFound {
+			t.Errorf("%s: status = %d, want 404", path, status)
+		}
+	}
+	if requests := upstream.Requests(); len(requests) != 0 {
+		t.Errorf("refused routes reached upstream: %v", requests)
+	}
+
+	for _, path := range []string{"/repos/owner/repo/releases/latest", "/repos/owner/repo/releases/tags/v1"} {
+		if status, body := proxyGet(t, proxy, path); status != http.StatusOK {
+			t.Errorf("%s: %d %s", path, status, body)
+		}
+	}
+}
+
+func TestServeCredentials(t *testing.T) {
+	upstream, server, proxy := newTestProxy(t)
+	upstream.Token = "github-token"
+	server.token = "github-token"
+	server.clientToken = "client-token"
+	assetPath := strings.TrimPrefix(upstream.AssetURL("owner/repo", "v1", "app.tar.gz"), upstream.URL)
+
+	// Anonymous callers are not served with the proxy's token
+	if status, _ := proxyGet(t, proxy, "/repos/owner/repo/releases"); status != http.StatusUnauthorized {
+		t.Errorf("anonymous: status = %d, want 401", status)
+	}
+	// Callers' own tokens are forwarded
+	if status, _ := proxyGet(t, proxy, "/repos/owner/repo/releases", "Authorization", "Bearer github-token"); status != http.StatusOK {
+		t.Errorf("caller's token: status = %d", status)
+	}
+
+	status, body := proxyGet(t, proxy, assetPath, "Accept", "application/octet-stream", "Authorization", "Bearer client-token")
+	if status != http.StatusOK || body != "0123456789" {
+		t.Fatalf("client token: %d %q", status, body)
+	}
+	// The stored asset is only served to callers upstream allows to read it
+	if status, _ := proxyGet(t, proxy, assetPath, "Accept", "application/octet-stream"); status != http.StatusUnauthorized {
+		t.Errorf("stored asset, anonymous: status = %d, want 401", status)
+	}
+	otherRepoPath := strings.Replace(assetPath, "/owner/repo/", "/owner/other/", 1)
+	if status, _ := proxyGet(t, proxy, otherRepoPath, "Accept", "application/octet-stream", "Authorization", "Bearer client-token"); status != http.StatusNotFound {
+		t.Errorf("stored asset through another repository: status = %d, want 404", status)
+	}
+	if n := storageRequests(upstream); n != 1 {
+		t.Errorf("asset downloaded %d times from upstream, want once", n)
+	}
+}
+
+func TestServeRewritesDownloadURLs(t *testing.T) {
+	upstream, server, proxy := newTestProxy(t)
+	// Reach upstream under another host name, so its browser download URLs
+	// point elsewhere like GitHub's website does
+	server.upstream = strings.Replace(upstream.URL, "127.0.0.1", "localhost", 1)
+
+	status, body := proxyGet(t, proxy, "/repos/owner/repo/releases/tags/v1")
+	want := `"browser_download_url":"` + proxy.URL + "/owner/repo/releases/download/v1/app.tar.gz"
+	if status != http.StatusOK || !strings.Contains(body, want) {
+		t.Fatalf("release: %d, download URL not rewritten to the proxy: %s", status, body)
+	}
+	if n := strings.Count(body, `"browser_download_url":"`+proxy.URL+"/"); n != 2 {
+		t.Errorf("%d of 2 download URLs rewritten: %s", n, body)
+	}
+	if status, body := proxyGet(t, proxy, "/owner/repo/releases/download/v1/app.tar.gz"); status != http.StatusOK || body != "0123456789" {
+		t.Errorf("rewritten download URL: %d %q", status, body)
+	}
+}
+
+func TestServeReleasesAssetLocks(t *testing.T) {
+	upstream, server, proxy := newTestProxy(t)
+	assetPath := strings.TrimPrefix(upstream.AssetURL("owner/repo", "v1", "app.tar.gz"), upstream.URL)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest("GET", proxy.URL+assetPath, nil)
+			req.Header.Set("Accept", "application/octet-stream")
+			if resp, err := http.DefaultClient.Do(req); err == nil {
+				_, _ = io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	if n := storageRequests(upstream); n != 1 {
+		t.Errorf("asset downloaded %d times from upstream, want once", n)
+	}
+	server.locksMu.Lock()
+	defer server.locksMu.Unlock()
+	if len(server.locks) != 0 {
+		t.Errorf("locks left after the requests: %v", server.locks)
+	}
+}
+
+func TestAddIdentity(t *testing.T) {
+	identities := addIdentity(nil, "a")
+	identities = addIdentity(identities, "b")
+	if identities = addIdentity(identities, "a"); !slices.Equal(identities, []string{"a", "b"}) {
+		t.Errorf("identities = %q, want each once", identities)
+	}
+	if got := addIdentity(identities, ""); !slices.Equal(got, []string{""}) {
+		t.Errorf("after anonymous access: %q", got)
+	}
+	if got := addIdentity([]string{""}, "c"); !slices.Equal(got, []string{""}) {
+		t.Errorf("anonymous asset: %q", got)
+	}
+
+	// Only the most recent identities are kept
+	identities = nil
+	for i := 0; i < maxAssetIdentities+10; i++ {
+		identities = addIdentity(identities, fmt.Sprint(i))
+	}
+	if len(identities) != maxAssetIdentities || identities[0] != "10" || identities[len(identities)-1] != fmt.Sprint(maxAssetIdentities+9) {
+		t.Errorf("%d identities from %s to %s", len(identities), identities[0], identities[len(identities)-1])
+	}
+}
@@ -0,0 +1,669 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	pgperrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+	"golang.org/x/crypto/blake2b"
+)
+
+// Signature files larger than this are rejected
+const maxSignatureSize = 1 << 20
+
+// Detached signature kinds, also used as labels in the progress table
+const (
+	signatureMinisign = "minisign"
+	signatureGPG      = "gpg"
+	signatureCosign   = "cosign"
+)
+
+// signatureSuffixes maps file name suffixes of detached signatures to their
+// kind; ".sig" may hold either a binary GPG or a cosign signature
+var signatureSuffixes = []struct {
+	suffix string
+	kind   string
+}{
+	{".minisig", signatureMinisign},
+	{".asc", signatureGPG},
+	{".sig", ""},
+	{".sigstore.json", signatureCosign},
+	{".sigstore", signatureCosign},
+	{".bundle", signatureCosign},
+}
+
+// errUnverifiable reports a signature that no configured key applies to
+var errUnverifiable = errors.New("no key configured for this signature")
+
+// signatureFile is a detached signature published next to an asset
+type signatureFile struct {
+	Name string
+	URL  string
+	Kind string // "" until the content of a ".sig" file is known
+}
+
+// isSignatureName reports whether name looks like a detached signature
+func isSignatureName(name string) bool {
+	for _, s := range signatureSuffixes {
+		if strings.HasSuffix(name, s.suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// findSignatures returns the detached signatures of the asset called name
+// among the assets of its release
+func findSignatures(name string, assets []Asset) []signatureFile {
+	var signatures []signatureFile
+	for _, s := range signatureSuffixes {
+		for _, asset := range assets {
+			if asset.Name == name+s.suffix {
+				signatures = append(signatures, signatureFile{Name: asset.Name, URL: asset.URL, Kind: s.kind})
+			}
+		}
+	}
+	return signatures
+}
+
+// signatureResult is the outcome of checking the signatures of a download
+type signatureResult struct {
+	status string // shown in the progress table
+	err    error  // set when the file must not be kept
+}
+
+// verifyAssetSignatures checks the detached signatures of the downloaded
+// asset in filename against the keys pinned in config. Any signature that
+// fails makes the download fail; with REQUIRE_SIGNATURE, so does a file
+// without a signature that could be verified.
+func verifyAssetSignatures(ctx context.Context, filename string, asset AssetInfo, config *Config) signatureResult {
+	keys, err := loadSignatureKeys(config)
+	if err != nil {
+		return signatureResult{status: "✗ config", err: err}
+	}
+
+	var verified, unverified []string
+	for _, sig := range asset.Signatures {
+		content, err := fetchSignature(ctx, sig, config)
+		if err != nil {
+			return signatureResult{status: "✗ " + sig.Name, err: fmt.Errorf("fetching %s: %v", sig.Name, err)}
+		}
+		kind, err := keys.verify(filename, sig, content)
+		switch {
+		case errors.Is(err, errUnverifiable):
+			unverified = appendUnique(unverified, kind)
+		case err != nil:
+			return signatureResult{status: "✗ " + kind, err: fmt.Errorf("%s: %v", sig.Name, err)}
+		default:
+			verified = appendUnique(verified, kind)
+		}
+	}
+
+	required := keys.required && !isSignatureName(asset.Name)
+	switch {
+	case len(verified) > 0:
+		return signatureResult{status: "✓ " + strings.Join(verified, "+")}
+	case required && len(unverified) > 0:
+		return signatureResult{status: "✗ unverified", err: fmt.Errorf("no %s signature could be verified with the configured keys", strings.Join(unverified, "/"))}
+	case required:
+		return signatureResult{status: "✗ unsigned", err: errors.New("no signature published and REQUIRE_SIGNATURE is set")}
+	case len(unverified) > 0:
+		return signatureResult{status: "? " + strings.Join(unverified, "+")}
+	case isSignatureName(asset.Name):
+		return signatureResult{status: "-"}
+	default:
+		return signatureResult{status: "unsigned"}
+	}
+}
+
+// appendUnique appends value to values unless already present
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// fetchSignature downloads the content of a detached signature
+func fetchSignature(ctx context.Context, sig signatureFile, config *Config) ([]byte, error) {
+	resp, err := fetchGitHubAsset(ctx, AssetInfo{URL: sig.URL}, config, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP error: %d", resp.StatusCode)
+	}
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxSignatureSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > maxSignatureSize {
+		return nil, errors.New("signature file too large")
+	}
+	return content, nil
+}
+
+// signatureKeys holds the keys pinned in the configuration
+type signatureKeys struct {
+	minisign []minisignPublicKey
+	gpg      openpgp.EntityList
+	cosign   crypto.PublicKey
+	required bool
+
+	// Identities and OIDC issuer keyless cosign certificates must be
+	// issued to, and the trusted root they are checked against
+	cosignIdentities     []string
+	cosignIdentityRegexp *regexp.Regexp
+	cosignIssuer         string
+	trustedRoot          string
+}
+
+// loadSignatureKeys parses the signing keys configured in config
+func loadSignatureKeys(config *Config) (*signatureKeys, error) {
+	keys := &signatureKeys{required: config.RequireSignature}
+	for _, value := range strings.Split(config.MinisignPublicKey, ",") {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		key, err := parseMinisignPublicKey(value)
+		if err != nil {
+			return nil, fmt.Errorf("MINISIGN_PUBKEY: %v", err)
+		}
+		keys.minisign = append(keys.minisign, key)
+	}
+	if config.GPGKeyring != "" {
+		keyring, err := readGPGKeyring(config.GPGKeyring)
+		if err != nil {
+			return nil, fmt.Errorf("GPG_KEYRING: %v", err)
+		}
+		keys.gpg = keyring
+	}
+	if config.CosignPublicKey != "" {
+		key, err := readPEMPublicKey(config.CosignPublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("COSIGN_PUBKEY: %v", err)
+		}
+		keys.cosign = key
+	}
+	for _, value := range strings.Split(config.CosignIdentity, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			keys.cosignIdentities = append(keys.cosignIdentities, value)
+		}
+	}
+	if config.CosignIdentityRegexp != "" {
+		// Anchored like cosign's --certificate-identity-regexp
+		re, err := regexp.Compile("^(?:" + config.CosignIdentityRegexp + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("COSIGN_IDENTITY_REGEXP: %v", err)
+		}
+		keys.cosignIdentityRegexp = re
+	}
+	if keys.keyless() && config.CosignIssuer == "" {
+		return nil, errors.New("COSIGN_IDENTITY and COSIGN_IDENTITY_REGEXP need COSIGN_ISSUER")
+	}
+	keys.cosignIssuer = config.CosignIssuer
+	keys.trustedRoot = config.SigstoreTrustedRoot
+	return keys, nil
+}
+
+// verify checks one detached signature of filename and returns its kind
+func (k *signatureKeys) verify(filename string, sig signatureFile, content []byte) (string, error) {
+	kind := sig.Kind
+	if kind == "" {
+		kind = signatureCosign
+		if isOpenPGPSignature(content) {
+			kind = signatureGPG
+		}
+	}
+	switch kind {
+	case signatureMinisign:
+		return kind, k.verifyMinisign(filename, content)
+	case signatureGPG:
+		return kind, k.verifyGPG(filename, content)
+	default:
+		if sig.Kind == "" {
+			return kind, k.verifyCosignSignature(filename, content)
+		}
+		return kind, k.verifyCosignBundle(filename, content)
+	}
+}
+
+// minisignPublicKey is a minisign Ed25519 public key and its key ID
+type minisignPublicKey struct {
+	keyID [8]byte
+	key   ed25519.PublicKey
+}
+
+// parseMinisignPublicKey parses the base64 key line of a minisign .pub file
+func parseMinisignPublicKey(value string) (minisignPublicKey, error) {
+	var key minisignPublicKey
+	raw, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(raw) != 2+8+ed25519.PublicKeySize || string(raw[:2]) != "Ed" {
+		return key, fmt.Errorf("invalid minisign public key %q", value)
+	}
+	copy(key.keyID[:], raw[2:10])
+	key.key = ed25519.PublicKey(raw[10:])
+	return key, nil
+}
+
+// verifyMinisign checks a minisign signature, including its trusted comment
+func (k *signatureKeys) verifyMinisign(filename string, content []byte) error {
+	if len(k.minisign) == 0 {
+		return errUnverifiable
+	}
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	if len(lines) < 4 || !strings.HasPrefix(lines[2], "trusted comment: ") {
+		return errors.New("malformed minisign signature")
+	}
+	sig, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil || len(sig) != 2+8+ed25519.SignatureSize {
+		return errors.New("malformed minisign signature")
+	}
+	globalSig, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil || len(globalSig) != ed25519.SignatureSize {
+		return errors.New("malformed minisign signature")
+	}
+
+	var key *minisignPublicKey
+	for i := range k.minisign {
+		if bytes.Equal(k.minisign[i].keyID[:], sig[2:10]) {
+			key = &k.minisign[i]
+		}
+	}
+	if key == nil {
+		return fmt.Errorf("signed by unknown minisign key %X", sig[2:10])
+	}
+
+	var message []byte
+	switch string(sig[:2]) {
+	case "Ed":
+		message, err = os.ReadFile(filename)
+	case "ED":
+		message, err = blake2bFile(filename)
+	default:
+		return fmt.Errorf("unsupported minisign algorithm %q", sig[:2])
+	}
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(key.key, message, sig[10:]) {
+		return errors.New("minisign signature does not match")
+	}
+	trustedComment := strings.TrimPrefix(lines[2], "trusted comment: ")
+	signed := append(append([]byte(nil), sig[10:]...), trustedComment...)
+	if !ed25519.Verify(key.key, signed, globalSig) {
+		return errors.New("minisign trusted comment signature does not match")
+	}
+	return nil
+}
+
+// blake2bFile returns the BLAKE2b-512 hash of a file
+func blake2bFile(filename string) ([]byte, error) {
+	hash, err := blake2b.New512(nil)
+	if err != nil {
+		return nil, err
+	}
+	sum, err := calculateFileHash(filename, hash)
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(sum)
+}
+
+// readGPGKeyring reads an armored or binary OpenPGP public keyring
+func readGPGKeyring(keyringPath string) (openpgp.EntityList, error) {
+	content, err := os.ReadFile(keyringPath)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Contains(content, []byte("-----BEGIN PGP")) {
+		return openpgp.ReadArmoredKeyRing(bytes.NewReader(content))
+	}
+	return openpgp.ReadKeyRing(bytes.NewReader(content))
+}
+
+// isOpenPGPSignature reports whether content is an armored or binary
+// OpenPGP signature rather than a base64 cosign signature
+func isOpenPGPSignature(content []byte) bool {
+	if bytes.HasPrefix(bytes.TrimSpace(content), []byte("-----BEGIN PGP")) {
+		return true
+	}
+	return len(content) > 0 && content[0]&0x80 != 0
+}
+
+// verifyGPG checks an armored or binary detached OpenPGP signature
+func (k *signatureKeys) verifyGPG(filename string, content []byte) error {
+	if len(k.gpg) == 0 {
+		return errUnverifiable
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	signature := io.Reader(bytes.NewReader(content))
+	if bytes.HasPrefix(bytes.TrimSpace(content), []byte("-----BEGIN PGP")) {
+		block, err := armor.Decode(bytes.NewReader(bytes.TrimSpace(content)))
+		if err != nil {
+			return fmt.Errorf("malformed GPG signature: %v", err)
+		}
+		signature = block.Body
+	}
+	if _, err := openpgp.CheckDetachedSignature(k.gpg, file, signature, nil); err != nil {
+		if errors.Is(err, pgperrors.ErrUnknownIssuer) {
+			return errors.New("signed by a key not in GPG_KEYRING")
+		}
+		return fmt.Errorf("GPG signature does not match: %v", err)
+	}
+	return nil
+}
+
+// readPEMPublicKey reads a PEM-encoded public key such as cosign.pub
+func readPEMPublicKey(keyPath string) (crypto.PublicKey, error) {
+	content, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errors.New("no PEM public key found")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// verifyCosignSignature checks a base64 signature made by "cosign sign-blob"
+func (k *signatureKeys) verifyCosignSignature(filename string, content []byte) error {
+	if k.cosign == nil {
+		return errUnverifiable
+	}
+	sig, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(content)))
+	if err != nil {
+		return errors.New("malformed cosign signature")
+	}
+	return verifyBlobSignature(k.cosign, filename, nil, sig)
+}
+
+// cosignBundle covers both "cosign sign-blob --bundle" output and Sigstore
+// bundles (.sigstore.json) holding a message signature
+type cosignBundle struct {
+	Base64Signature string `json:"base64Signature"`
+	Cert            string `json:"cert"` // base64 of a PEM certificate
+	RekorBundle     *struct {
+		SignedEntryTimestamp string `json:"SignedEntryTimestamp"`
+		Payload              struct {
+			Body           string `json:"body"`
+			IntegratedTime int64  `json:"integratedTime"`
+			LogIndex       int64  `json:"logIndex"`
+			LogID          string `json:"logID"` // hex
+		} `json:"Payload"`
+	} `json:"rekorBundle"`
+
+	VerificationMaterial verificationMaterial `json:"verificationMaterial"`
+	MessageSignature     *struct {
+		MessageDigest struct {
+			Algorithm string `json:"algorithm"`
+			Digest    string `json:"digest"`
+		} `json:"messageDigest"`
+		Signature string `json:"signature"`
+	} `json:"messageSignature"`
+}
+
+// certificate returns the short-lived signing certificate of a keyless
+// bundle, or nil if it was signed with a key
+func (b cosignBundle) certificate() (*x509.Certificate, error) {
+	if b.Cert == "" {
+		return b.VerificationMaterial.certificate()
+	}
+	content, err := base64.StdEncoding.DecodeString(b.Cert)
+	if err != nil {
+		return nil, errors.New("malformed signing certificate")
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errors.New("malformed signing certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("malformed signing certificate: %v", err)
+	}
+	return cert, nil
+}
+
+// tlogEntries returns the transparency log entries of the bundle, converting
+// the Rekor bundle of the cosign format
+func (b cosignBundle) tlogEntries() ([]tlogEntry, error) {
+	if b.RekorBundle == nil {
+		return b.VerificationMaterial.TlogEntries, nil
+	}
+	payload := b.RekorBundle.Payload
+	logID, err := hex.DecodeString(payload.LogID)
+	if err != nil {
+		return nil, errors.New("malformed transparency log ID")
+	}
+	entry := tlogEntry{
+		LogIndex:          strconv.FormatInt(payload.LogIndex, 10),
+		IntegratedTime:    strconv.FormatInt(payload.IntegratedTime, 10),
+		InclusionPromise:  &inclusionPromise{SignedEntryTimestamp: b.RekorBundle.SignedEntryTimestamp},
+		CanonicalizedBody: payload.Body,
+	}
+	entry.LogID.KeyID = base64.StdEncoding.EncodeToString(logID)
+	return []tlogEntry{entry}, nil
+}
+
+// verifyCosignBundle checks a cosign or Sigstore bundle, signed with the
+// configured key or, keyless, with a certificate for a configured identity
+func (k *signatureKeys) verifyCosignBundle(filename string, content []byte) error {
+	var bundle cosignBundle
+	if err := json.Unmarshal(content, &bundle); err != nil {
+		return fmt.Errorf("malformed cosign bundle: %v", err)
+	}
+
+	encoded := bundle.Base64Signature
+	var digest []byte
+	if ms := bundle.MessageSignature; ms != nil {
+		encoded = ms.Signature
+		if ms.MessageDigest.Algorithm != "" && ms.MessageDigest.Algorithm != "SHA2_256" {
+			return fmt.Errorf("unsupported digest algorithm %s", ms.MessageDigest.Algorithm)
+		}
+		var err error
+		if digest, err = base64.StdEncoding.DecodeString(ms.MessageDigest.Digest); err != nil {
+			return errors.New("malformed cosign bundle digest")
+		}
+	}
+	if encoded == "" {
+		return errUnverifiable
+	}
+	sig, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return errors.New("malformed cosign signature")
+	}
+
+	cert, err := bundle.certificate()
+	if err != nil {
+		return err
+	}
+	if cert == nil {
+		if k.cosign == nil {
+			return errUnverifiable
+		}
+		return verifyBlobSignature(k.cosign, filename, digest, sig)
+	}
+	if !k.keyless() {
+		return errUnverifiable
+	}
+	entries, err := bundle.tlogEntries()
+	if err != nil {
+		return err
+	}
+	return k.verifyKeyless(filename, cert, entries, digest, sig)
+}
+
+// verifyKeyless checks a keyless signature: it matches the certificate,
+// the transparency log recorded it for this file while the certificate was
+// valid, the certificate chains to a Fulcio CA, and it was issued to a
+// configured identity
+func (k *signatureKeys) verifyKeyless(filename string, cert *x509.Certificate, entries []tlogEntry, digest, sig []byte) error {
+	if err := verifyBlobSignature(cert.PublicKey, filename, digest, sig); err != nil {
+		return err
+	}
+	root, err := loadTrustedRoot(k.trustedRoot)
+	if err != nil {
+		return fmt.Errorf("SIGSTORE_TRUSTED_ROOT: %v", err)
+	}
+	signedAt, err := root.verifyLogEntry(entries)
+	if err != nil {
+		return err
+	}
+	sum, err := calculateSHA256(filename)
+	if err != nil {
+		return err
+	}
+	if err := checkHashedRekordBody(entries[0].CanonicalizedBody, sum, sig, cert); err != nil {
+		return err
+	}
+	if err := root.verifyCertificate(cert, signedAt); err != nil {
+		return err
+	}
+	return k.checkCosignIdentity(cert)
+}
+
+// checkHashedRekordBody checks that a logged hashedrekord entry records sig
+// by cert over a file with sha256 digest sum
+func checkHashedRekordBody(encoded, sum string, sig []byte, cert *x509.Certificate) error {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return errors.New("malformed transparency log entry")
+	}
+	var body struct {
+		Kind string `json:"kind"`
+		Spec struct {
+			Data struct {
+				Hash struct {
+					Algorithm string `json:"algorithm"`
+					Value     string `json:"value"`
+				} `json:"hash"`
+			} `json:"data"`
+			Signature struct {
+				Content   string `json:"content"`
+				PublicKey struct {
+					Content string `json:"content"`
+				} `json:"publicKey"`
+			} `json:"signature"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return errors.New("malformed transparency log entry")
+	}
+	if body.Kind != "hashedrekord" {
+		return fmt.Errorf("unsupported transparency log entry kind %q", body.Kind)
+	}
+	hash := body.Spec.Data.Hash
+	if hash.Algorithm != "sha256" || !strings.EqualFold(hash.Value, sum) {
+		return errors.New("transparency log entry is for a different file")
+	}
+	logged, err := base64.StdEncoding.DecodeString(body.Spec.Signature.Content)
+	if err != nil || !bytes.Equal(logged, sig) {
+		return errors.New("transparency log entry is for a different signature")
+	}
+	certPEM, err := base64.StdEncoding.DecodeString(body.Spec.Signature.PublicKey.Content)
+	if err != nil {
+		return errors.New("malformed transparency log entry")
+	}
+	if block, _ := pem.Decode(certPEM); block == nil || !bytes.Equal(block.Bytes, cert.Raw) {
+		return errors.New("transparency log entry is for a different certificate")
+	}
+	return nil
+}
+
+// checkCosignIdentity checks that cert was issued by the configured OIDC
+// issuer to an identity matching one of the configured patterns
+func (k *signatureKeys) checkCosignIdentity(cert *x509.Certificate) error {
+	identity, err := certificateIdentity(cert)
+	if err != nil {
+		return err
+	}
+	if identity.issuer != k.cosignIssuer {
+		return fmt.Errorf("certificate was issued by %q, expected %q", identity.issuer, k.cosignIssuer)
+	}
+	names := append([]string(nil), cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		names = append(names, uri.String())
+	}
+	for _, name := range names {
+		if slices.Contains(k.cosignIdentities, name) || (k.cosignIdentityRegexp != nil && k.cosignIdentityRegexp.MatchString(name)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("certificate identity %s does not match COSIGN_IDENTITY or COSIGN_IDENTITY_REGEXP", strings.Join(names, ", "))
+}
+
+// keyless reports whether identities of keyless cosign signatures are
+// configured
+func (k *signatureKeys) keyless() bool {
+	return len(k.cosignIdentities) > 0 || k.cosignIdentityRegexp != nil
+}
+
+// verifyBlobSignature checks sig over the SHA-256 digest of filename, or over
+// its content for Ed25519 keys. A digest recorded in a bundle must match.
+func verifyBlobSignature(key crypto.PublicKey, filename string, digest, sig []byte) error {
+	sum, err := calculateSHA256(filename)
+	if err != nil {
+		return err
+	}
+	actual, err := hex.DecodeString(sum)
+	if err != nil {
+		return err
+	}
+	if digest != nil && !bytes.Equal(digest, actual) {
+		return errors.New("signed digest does not match the downloaded file")
+	}
+
+	valid := false
+	switch key := key.(type) {
+	case *ecdsa.PublicKey:
+		valid = ecdsa.VerifyASN1(key, actual, sig)
+	case *rsa.PublicKey:
+		valid = rsa.VerifyPKCS1v15(key, crypto.SHA256, actual, sig) == nil ||
+			rsa.VerifyPSS(key, crypto.SHA256, actual, sig, nil) == nil
+	case ed25519.PublicKey:
+		message, err := os.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		valid = ed25519.Verify(key, message, sig)
+	default:
+		return fmt.Errorf("unsupported public key type %T", key)
+	}
+	if !valid {
+		return errors.New("cosign signature does not match")
+	}
+	return nil
+}
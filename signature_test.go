@@ -0,0 +1,518 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"golang.org/x/crypto/blake2b"
+
+	"github.com/Native-Robotics/asset-fetch/internal/githubtest"
+)
+
+// writeTestFile writes content to name in a temporary directory
+func writeTestFile(t *testing.T, name string, content []byte) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filename, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+// testMinisignKey is a minisign key pair
+type testMinisignKey struct {
+	keyID [8]byte
+	key   ed25519.PrivateKey
+}
+
+func newTestMinisignKey(t *testing.T) testMinisignKey {
+	t.Helper()
+	var k testMinisignKey
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.key = key
+	_, _ = rand.Read(k.keyID[:])
+	return k
+}
+
+// publicKey returns the key line of the minisign .pub file
+func (k testMinisignKey) publicKey() string {
+	raw := append(append([]byte("Ed"), k.keyID[:]...), k.key.Public().(ed25519.PublicKey)...)
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+// sign returns a .minisig file for message, prehashed as "minisign -S" does
+func (k testMinisignKey) sign(message []byte) []byte {
+	hash := blake2b.Sum512(message)
+	sig := ed25519.Sign(k.key, hash[:])
+	raw := append(append([]byte("ED"), k.keyID[:]...), sig...)
+	trustedComment := "timestamp:1700000000\tfile:app.bin\thashed"
+	globalSig := ed25519.Sign(k.key, append(append([]byte(nil), sig...), trustedComment...))
+	return []byte("untrusted comment: signature from minisign secret key\n" +
+		base64.StdEncoding.EncodeToString(raw) + "\n" +
+		"trusted comment: " + trustedComment + "\n" +
+		base64.StdEncoding.EncodeToString(globalSig) + "\n")
+}
+
+func TestVerifyMinisign(t *testing.T) {
+	key, other := newTestMinisignKey(t), newTestMinisignKey(t)
+	keys, err := loadSignatureKeys(&Config{MinisignPublicKey: key.publicKey()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := writeTestFile(t, "app.bin", []byte("release build"))
+	sig := signatureFile{Name: "app.bin.minisig", Kind: signatureMinisign}
+
+	if _, err := keys.verify(filename, sig, key.sign([]byte("release build"))); err != nil {
+		t.Errorf("good signature: %v", err)
+	}
+	if _, err := keys.verify(filename, sig, key.sign([]byte("tampered build"))); err == nil {
+		t.Error("signature of other content accepted")
+	}
+	if _, err := keys.verify(filename, sig, other.sign([]byte("release build"))); err == nil || !strings.Contains(err.Error(), "unknown minisign key") {
+		t.Errorf("wrong key: got %v", err)
+	}
+	tampered := bytes.Replace(key.sign([]byte("release build")), []byte("file:app.bin"), []byte("file:other"), 1)
+	if _, err := keys.verify(filename, sig, tampered); err == nil {
+		t.Error("tampered trusted comment accepted")
+	}
+	if _, err := (&signatureKeys{}).verify(filename, sig, key.sign([]byte("release build"))); !errors.Is(err, errUnverifiable) {
+		t.Errorf("no key configured: got %v, want errUnverifiable", err)
+	}
+}
+
+// writeGPGKeyring writes the public key of entity to an armored keyring
+func writeGPGKeyring(t *testing.T, entity *openpgp.Entity) string {
+	t.Helper()
+	var keyring bytes.Buffer
+	w, err := armor.Encode(&keyring, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	_ = w.Close()
+	return writeTestFile(t, "keyring.asc", keyring.Bytes())
+}
+
+func TestVerifyGPG(t *testing.T) {
+	entity, err := openpgp.NewEntity("Release", "", "release@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := openpgp.NewEntity("Other", "", "other@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := loadSignatureKeys(&Config{GPGKeyring: writeGPGKeyring(t, entity)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := writeTestFile(t, "app.bin", []byte("release build"))
+
+	sign := func(signer *openpgp.Entity, message string, armored bool) []byte {
+		var sig bytes.Buffer
+		detachSign := openpgp.DetachSign
+		if armored {
+			detachSign = openpgp.ArmoredDetachSign
+		}
+		if err := detachSign(&sig, signer, strings.NewReader(message), nil); err != nil {
+			t.Fatal(err)
+		}
+		return sig.Bytes()
+	}
+
+	// Binary signatures in ".sig" files are told apart from cosign ones
+	for _, sig := range []struct {
+		file    signatureFile
+		armored bool
+	}{
+		{signatureFile{Name: "app.bin.asc", Kind: signatureGPG}, true},
+		{signatureFile{Name: "app.bin.sig"}, false},
+	} {
+		kind, err := keys.verify(filename, sig.file, sign(entity, "release build", sig.armored))
+		if err != nil || kind != signatureGPG {
+			t.Errorf("%s: good signature: %s, %v", sig.file.Name, kind, err)
+		}
+		if _, err := keys.verify(filename, sig.file, sign(entity, "tampered build", sig.armored)); err == nil {
+			t.Errorf("%s: signature of other content accepted", sig.file.Name)
+		}
+		if _, err := keys.verify(filename, sig.file, sign(other, "release build", sig.armored)); err == nil || !strings.Contains(err.Error(), "not in GPG_KEYRING") {
+			t.Errorf("%s: wrong key: got %v", sig.file.Name, err)
+		}
+	}
+}
+
+// writePEMPublicKey writes the public half of key to a cosign.pub file
+func writePEMPublicKey(t *testing.T, key *ecdsa.PrivateKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writeTestFile(t, "cosign.pub", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func newTestECDSAKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// signBlob signs the SHA-256 digest of message as "cosign sign-blob" does
+func signBlob(t *testing.T, key *ecdsa.PrivateKey, message string) []byte {
+	t.Helper()
+	digest := sha256.Sum256([]byte(message))
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sig
+}
+
+func TestVerifyCosignWithKey(t *testing.T) {
+	key, other := newTestECDSAKey(t), newTestECDSAKey(t)
+	keys, err := loadSignatureKeys(&Config{CosignPublicKey: writePEMPublicKey(t, key)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := writeTestFile(t, "app.bin", []byte("release build"))
+
+	signature := func(signer *ecdsa.PrivateKey, message string) []byte {
+		return []byte(base64.StdEncoding.EncodeToString(signBlob(t, signer, message)))
+	}
+	bundle := func(signer *ecdsa.PrivateKey, message string) []byte {
+		content, _ := json.Marshal(map[string]string{"base64Signature": string(signature(signer, message))})
+		return content
+	}
+	sig := signatureFile{Name: "app.bin.sig"}
+	bundleSig := signatureFile{Name: "app.bin.bundle", Kind: signatureCosign}
+
+	if kind, err := keys.verify(filename, sig, signature(key, "release build")); err != nil || kind != signatureCosign {
+		t.Errorf("good signature: %s, %v", kind, err)
+	}
+	if _, err := keys.verify(filename, bundleSig, bundle(key, "release build")); err != nil {
+		t.Errorf("good bundle: %v", err)
+	}
+	for name, content := range map[string][]byte{
+		"tampered file":        signature(key, "tampered build"),
+		"wrong key":            signature(other, "release build"),
+		"bundle of wrong file": bundle(key, "tampered build"),
+		"bundle of wrong key":  bundle(other, "release build"),
+	} {
+		s := sig
+		if strings.HasPrefix(name, "bundle") {
+			s = bundleSig
+		}
+		if _, err := keys.verify(filename, s, content); err == nil || errors.Is(err, errUnverifiable) {
+			t.Errorf("%s: got %v, want a failure", name, err)
+		}
+	}
+}
+
+// testSigstore is a Fulcio CA and a Rekor log for signing test bundles,
+// written out as a trusted root
+type testSigstore struct {
+	caKey    *ecdsa.PrivateKey
+	caCert   *x509.Certificate
+	logKey   *ecdsa.PrivateKey
+	logID    []byte
+	rootPath string
+}
+
+func newTestSigstore(t *testing.T) *testSigstore {
+	t.Helper()
+	s := &testSigstore{caKey: newTestECDSAKey(t), logKey: newTestECDSAKey(t)}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test fulcio"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, s.caKey.Public(), s.caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.caCert, err = x509.ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	logKey, err := x509.MarshalPKIXPublicKey(s.logKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	logID := sha256.Sum256(logKey)
+	s.logID = logID[:]
+
+	start := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	root, err := json.Marshal(map[string]any{
+		"tlogs": []any{map[string]any{
+			"publicKey": map[string]any{"rawBytes": logKey, "validFor": map[string]string{"start": start}},
+			"logId":     map[string]any{"keyId": s.logID},
+		}},
+		"certificateAuthorities": []any{map[string]any{
+			"certChain": map[string]any{"certificates": []any{map[string]any{"rawBytes": der}}},
+			"validFor":  map[string]string{"start": start},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.rootPath = writeTestFile(t, "trusted_root.json", root)
+	return s
+}
+
+// fulcioExtension encodes a Fulcio certificate extension
+func fulcioExtension(t *testing.T, oid asn1.ObjectIdentifier, value string) pkix.Extension {
+	t.Helper()
+	raw, err := asn1.MarshalWithParams(value, "utf8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pkix.Extension{Id: oid, Value: raw}
+}
+
+// issue returns a short-lived code signing certificate for identity
+func (s *testSigstore) issue(t *testing.T, identity string, extensions ...pkix.Extension) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key := newTestECDSAKey(t)
+	uri, err := url.Parse(identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:    big.NewInt(time.Now().UnixNano()),
+		NotBefore:       time.Now().Add(-time.Minute),
+		NotAfter:        time.Now().Add(10 * time.Minute),
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		URIs:            []*url.URL{uri},
+		ExtraExtensions: extensions,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, s.caCert, key.Public(), s.caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+// logEntry records body in the log at integratedTime and returns the entry
+// with its signed entry timestamp
+func (s *testSigstore) logEntry(t *testing.T, body any, integratedAt time.Time) tlogEntry {
+	t.Helper()
+	raw, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	integratedTime := integratedAt.Unix()
+	entry := tlogEntry{
+		LogIndex:          "42",
+		IntegratedTime:    strconv.FormatInt(integratedTime, 10),
+		CanonicalizedBody: base64.StdEncoding.EncodeToString(raw),
+	}
+	entry.LogID.KeyID = base64.StdEncoding.EncodeToString(s.logID)
+	payload, _ := json.Marshal(struct {
+		Body           string `json:"body"`
+		IntegratedTime int64  `json:"integratedTime"`
+		LogID          string `json:"logID"`
+		LogIndex       int64  `json:"logIndex"`
+	}{entry.CanonicalizedBody, integratedTime, hex.EncodeToString(s.logID), 42})
+	digest := sha256.Sum256(payload)
+	set, err := ecdsa.SignASN1(rand.Reader, s.logKey, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry.InclusionPromise = &inclusionPromise{SignedEntryTimestamp: base64.StdEncoding.EncodeToString(set)}
+	return entry
+}
+
+// keylessBundle signs message with a certificate for cert and returns the
+// "cosign sign-blob --bundle" output
+func (s *testSigstore) keylessBundle(t *testing.T, cert *x509.Certificate, key *ecdsa.PrivateKey, message string) []byte {
+	t.Helper()
+	sig := signBlob(t, key, message)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	digest := sha256.Sum256([]byte(message))
+	entry := s.logEntry(t, map[string]any{
+		"apiVersion": "0.0.1",
+		"kind":       "hashedrekord",
+		"spec": map[string]any{
+			"data": map[string]any{"hash": map[string]string{"algorithm": "sha256", "value": hex.EncodeToString(digest[:])}},
+			"signature": map[string]any{
+				"content":   sig,
+				"publicKey": map[string]any{"content": certPEM},
+			},
+		},
+	}, time.Now())
+	bundle := map[string]any{
+		"base64Signature": sig,
+		"cert":            certPEM,
+		"rekorBundle": map[string]any{
+			"SignedEntryTimestamp": entry.InclusionPromise.SignedEntryTimestamp,
+			"Payload": map[string]any{
+				"body":           entry.CanonicalizedBody,
+				"integratedTime": entry.IntegratedTime,
+				"logIndex":       42,
+				"logID":          hex.EncodeToString(s.logID),
+			},
+		},
+	}
+	content, err := json.Marshal(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// integratedTime is a number in the cosign format
+	return bytes.Replace(content, []byte(`"integratedTime":"`+entry.IntegratedTime+`"`), []byte(`"integratedTime":`+entry.IntegratedTime), 1)
+}
+
+func TestVerifyCosignKeyless(t *testing.T) {
+	sigstore := newTestSigstore(t)
+	const identity = "https://github.com/owner/repo/.github/workflows/release.yml@refs/tags/v1"
+	cert, key := sigstore.issue(t, identity, fulcioExtension(t, oidFulcioIssuer, githubActionsIssuer))
+	filename := writeTestFile(t, "app.bin", []byte("release build"))
+	sig := signatureFile{Name: "app.bin.bundle", Kind: signatureCosign}
+	good := sigstore.keylessBundle(t, cert, key, "release build")
+
+	load := func(config Config) *signatureKeys {
+		t.Helper()
+		config.SigstoreTrustedRoot = sigstore.rootPath
+		keys, err := loadSignatureKeys(&config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return keys
+	}
+	keys := load(Config{CosignIdentity: "someone@example.com, " + identity, CosignIssuer: githubActionsIssuer})
+
+	if _, err := keys.verify(filename, sig, good); err != nil {
+		t.Fatalf("good bundle: %v", err)
+	}
+	// Regexps are anchored and may match across slashes
+	for _, re := range []string{`https://github\.com/owner/.*`, `https://github\.com/owner/repo/\.github/workflows/release\.yml@refs/tags/v\d+`} {
+		if _, err := load(Config{CosignIdentityRegexp: re, CosignIssuer: githubActionsIssuer}).verify(filename, sig, good); err != nil {
+			t.Errorf("COSIGN_IDENTITY_REGEXP=%s: %v", re, err)
+		}
+	}
+	if _, err := load(Config{}).verify(filename, sig, good); !errors.Is(err, errUnverifiable) {
+		t.Errorf("no identity configured: got %v, want errUnverifiable", err)
+	}
+	if _, err := keys.verify(filename, sig, sigstore.keylessBundle(t, cert, key, "tampered build")); err == nil {
+		t.Error("bundle of other content accepted")
+	}
+
+	other := newTestSigstore(t)
+	foreignCert, foreignKey := other.issue(t, identity, fulcioExtension(t, oidFulcioIssuer, githubActionsIssuer))
+	for name, test := range map[string]struct {
+		keys   *signatureKeys
+		bundle []byte
+		want   string
+	}{
+		"wrong identity": {
+			keys:   load(Config{CosignIdentity: "https://github.com/owner/repo/.github/workflows/release.yml@refs/heads/main", CosignIssuer: githubActionsIssuer}),
+			bundle: good,
+			want:   "does not match COSIGN_IDENTITY",
+		},
+		"unanchored regexp": {
+			keys:   load(Config{CosignIdentityRegexp: `github\.com/owner/`, CosignIssuer: githubActionsIssuer}),
+			bundle: good,
+			want:   "does not match COSIGN_IDENTITY",
+		},
+		"identity prefix": {
+			keys:   load(Config{CosignIdentity: "https://github.com/owner/", CosignIssuer: githubActionsIssuer}),
+			bundle: good,
+			want:   "does not match COSIGN_IDENTITY",
+		},
+		"wrong issuer": {
+			keys:   load(Config{CosignIdentity: identity, CosignIssuer: "https://accounts.google.com"}),
+			bundle: good,
+			want:   "issued by",
+		},
+		"foreign CA and log": {
+			keys:   keys,
+			bundle: other.keylessBundle(t, foreignCert, foreignKey, "release build"),
+			want:   "not in the trusted root",
+		},
+		"foreign CA": {
+			keys: keys,
+			bundle: func() []byte {
+				// Logged by the trusted log, but not issued by its CA
+				return sigstore.keylessBundle(t, foreignCert, foreignKey, "release build")
+			}(),
+			want: "not issued by a trusted Fulcio CA",
+		},
+	} {
+		if _, err := test.keys.verify(filename, sig, test.bundle); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: got %v, want %q", name, err, test.want)
+		}
+	}
+
+	if _, err := loadSignatureKeys(&Config{CosignIdentity: identity}); err == nil {
+		t.Error("COSIGN_IDENTITY accepted without COSIGN_ISSUER")
+	}
+	if _, err := loadSignatureKeys(&Config{CosignIdentityRegexp: "(", CosignIssuer: githubActionsIssuer}); err == nil {
+		t.Error("invalid COSIGN_IDENTITY_REGEXP accepted")
+	}
+}
+
+func TestDownloadAssetSignatures(t *testing.T) {
+	server := setupFakeGitHub(t)
+	key := newTestMinisignKey(t)
+	server.AddRelease("owner/repo", githubtest.Release{Tag: "v1", Assets: []githubtest.Asset{
+		{Name: "signed.bin", Content: []byte("signed build")},
+		{Name: "signed.bin.minisig", Content: key.sign([]byte("signed build"))},
+		{Name: "forged.bin", Content: []byte("forged build")},
+		{Name: "forged.bin.minisig", Content: key.sign([]byte("original build"))},
+		{Name: "unsigned.bin", Content: []byte("unsigned build")},
+	}})
+	writeConfig(t, `MINISIGN_PUBKEY="`+key.publicKey()+`"`, "REQUIRE_SIGNATURE=true")
+
+	msg := downloadAsset(context.Background(), releaseAsset(t, "v1", "signed.bin"), 0, "", nil)()
+	if verified, ok := msg.(checksumVerifiedMsg); !ok || verified.signature != "✓ minisign" {
+		t.Errorf("signed asset: got %#v", msg)
+	}
+
+	for _, name := range []string{"forged.bin", "unsigned.bin"} {
+		msg := downloadAsset(context.Background(), releaseAsset(t, "v1", name), 0, "", nil)()
+		if failed, ok := msg.(signatureFailedMsg); !ok || !strings.HasPrefix(failed.err, "Signature verification failed for "+name) {
+			t.Errorf("%s: got %#v, want signatureFailedMsg", name, msg)
+		}
+		if _, err := os.Stat(name); !os.IsNotExist(err) {
+			t.Errorf("%s kept after failing verification", name)
+		}
+	}
+}
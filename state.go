@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Maximum number of repositories kept in the history besides bookmarks
+const maxRecentRepositories = 20
+
+// RepositoryHistory records what was last used in a repository
+type RepositoryHistory struct {
+	Owner      string    `json:"owner"`
+	Name       string    `json:"name"`
+	LastTag    string    `json:"last_tag,omitempty"`
+	LastMask   string    `json:"last_mask,omitempty"`
+	LastUsed   time.Time `json:"last_used"`
+	Bookmarked bool      `json:"bookmarked,omitempty"`
+}
+
+// FullName returns the repository name in owner/name form
+func (rh RepositoryHistory) FullName() string {
+	return rh.Owner + "/" + rh.Name
+}
+
+// AppState is persisted between sessions in the state file
+type AppState struct {
+	Repositories []RepositoryHistory `json:"repositories"`
+}
+
+// stateMutex serializes read-modify-write cycles of the state file
+var stateMutex sync.Mutex
+
+// historyMsg carries the repository history after it has been updated
+type historyMsg []RepositoryHistory
+
+// stateFilePath returns the location of the state file in the user config dir
+func stateFilePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "afetch", "state.json"), nil
+}
+
+// loadState reads the state file; a missing file yields an empty state
+func loadState() (*AppState, error) {
+	statePath, err := stateFilePath()
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(statePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return &AppState{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	state := &AppState{}
+	if err := json.Unmarshal(content, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// save writes the state file, replacing it atomically
+func (s *AppState) save() error {
+	statePath, err := stateFilePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(statePath), 0o755); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpPath := statePath + ".tmp"
+	if err := os.WriteFile(tmpPath, content, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, statePath)
+}
+
+// find returns the history entry of owner/name, or nil
+func (s *AppState) find(owner, name string) *RepositoryHistory {
+	for i := range s.Repositories {
+		if s.Repositories[i].Owner == owner && s.Repositories[i].Name == name {
+			return &s.Repositories[i]
+		}
+	}
+	return nil
+}
+
+// sortedRepositories returns bookmarks first, then the most recently used
+func (s *AppState) sortedRepositories() []RepositoryHistory {
+	repos := append([]RepositoryHistory(nil), s.Repositories...)
+	sort.SliceStable(repos, func(a, b int) bool {
+		if repos[a].Bookmarked != repos[b].Bookmarked {
+			return repos[a].Bookmarked
+		}
+		return repos[a].LastUsed.After(repos[b].LastUsed)
+	})
+	return repos
+}
+
+// prune drops the oldest entries that are not bookmarked
+func (s *AppState) prune() {
+	var kept []RepositoryHistory
+	recent := 0
+	for _, repo := range s.sortedRepositories() {
+		if !repo.Bookmarked {
+			if recent >= maxRecentRepositories {
+				continue
+			}
+			recent++
+		}
+		kept = append(kept, repo)
+	}
+	s.Repositories = kept
+}
+
+// updateRepositoryHistory applies update to the history entry of owner/name,
+// creating it if needed, and saves the state file
+func updateRepositoryHistory(owner, name string, update func(*RepositoryHistory)) ([]RepositoryHistory, error) {
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+
+	state, err := loadState()
+	if err != nil {
+		return nil, err
+	}
+	entry := state.find(owner, name)
+	if entry == nil {
+		state.Repositories = append(state.Repositories, RepositoryHistory{Owner: owner, Name: name})
+		entry = &state.Repositories[len(state.Repositories)-1]
+	}
+	entry.LastUsed = time.Now().UTC()
+	update(entry)
+	state.prune()
+
+	if err := state.save(); err != nil {
+		return nil, err
+	}
+	return state.sortedRepositories(), nil
+}
+
+// rememberRepository updates the history of owner/name in the background.
+// History is best effort, so failures to save it are ignored.
+func rememberRepository(owner, name string, update func(*RepositoryHistory)) tea.Cmd {
+	if owner == "" || name == "" {
+		return nil
+	}
+	return func() tea.Msg {
+		history, err := updateRepositoryHistory(owner, name, update)
+		if err != nil {
+			return nil
+		}
+		return historyMsg(history)
+	}
+}
+
+// findHistory returns the history entry of owner/name in history, or nil
+func findHistory(history []RepositoryHistory, owner, name string) *RepositoryHistory {
+	for i := range history {
+		if history[i].Owner == owner && history[i].Name == name {
+			return &history[i]
+		}
+	}
+	return nil
+}
@@ -1,43 +1,88 @@
 package main
 
 import (
-	"context"
-	"io"
-	"sync"
-)
-
-// Global context and cancel function for download cancellation
-var downloadContext context.Context
-var downloadCancel context.CancelFunc
+	"time"
 
-// Global variable for download progress
-var downloadProgress int64
-var downloadProgressMutex sync.Mutex
+	"github.com/Native-Robotics/asset-fetch/afetch"
+)
 
 // Config structure for storing configuration
 type Config struct {
-	GitHubToken string
-	RepoOwner   string
-	RepoName    string
-	AssetMask   string
-}
+	GitHubToken  string
+	GitHubAPIURL string
+	RepoOwner    string
+	RepoName     string
+	AssetMask    string
+
+	// Keys that detached signatures of assets are verified against
+	MinisignPublicKey string
+	GPGKeyring        string
+	CosignPublicKey   string
+	RequireSignature  bool
+
+	// Identities (exact, or an anchored regexp) and OIDC issuer of keyless
+	// cosign signatures
+	CosignIdentity       string
+	CosignIdentityRegexp string
+	CosignIssuer         string
+
+	// Build provenance required per repository ("" = all repositories) and
+	// the Sigstore trusted root its attestations are verified against
+	AttestationPolicies map[string]AttestationPolicy
+	SigstoreTrustedRoot string
+
+	// Commands run after downloads, in config order
+	Hooks []Hook
+
+	// What to do when a download's file already exists
+	OverwritePolicy OverwritePolicy
+
+	// Credentials for pulling from the OCI registry RegistryHost
+	RegistryHost     string
+	RegistryUsername string
+	RegistryPassword string
+
+	// Token callers of "afetch serve" present to be served with GitHubToken
+	ServeClientToken string
 
-// Asset structure for storing artifact information
-type Asset struct {
-	ID                 int    `json:"id"`
-	Name               string `json:"name"`
-	URL                string `json:"url"`
-	BrowserDownloadURL string `json:"browser_download_url"`
-	Size               int64  `json:"size"`
-	CreatedAt          string `json:"created_at"`
-	Digest             string `json:"digest"`
+	// Network settings of all HTTP requests: a proxy used instead of the
+	// environment's, CA certificate files trusted in addition to the system
+	// pool, a client certificate for mutual TLS and disabled verification
+	ProxyURL      string
+	CACertFiles   []string
+	ClientCert    string
+	ClientKey     string
+	InsecureHosts []string // hosts whose certificates are not verified
+
+	// Problems that do not stop afetch, such as unknown settings, to be
+	// printed at startup
+	Warnings []string
 }
 
-// Release structure for storing release information
-type Release struct {
-	TagName string  `json:"tag_name"`
-	Name    string  `json:"name"`
-	Assets  []Asset `json:"assets"`
+// GitHub accounts, release assets and releases as returned by the API
+type (
+	User    = afetch.User
+	Asset   = afetch.Asset
+	Release = afetch.Release
+)
+
+// Repository structure for storing repository information
+type Repository struct {
+	Name            string `json:"name"`
+	FullName        string `json:"full_name"`
+	Owner           User   `json:"owner"`
+	Description     string `json:"description"`
+	Private         bool   `json:"private"`
+	Fork            bool   `json:"fork"`
+	Archived        bool   `json:"archived"`
+	StargazersCount int    `json:"stargazers_count"`
+	PushedAt        string `json:"pushed_at"`
+
+	// Filled in after the repository has been checked for releases
+	ReleasesChecked     bool   `json:"-"`
+	ReleaseCheckFailed  bool   `json:"-"`
+	ReleaseCheckSkipped bool   `json:"-"`
+	LatestRelease       string `json:"-"`
 }
 
 // AssetInfo structure for storing artifact information
@@ -48,12 +93,39 @@ type AssetInfo struct {
 	DownloadURL   string
 	Size          int64
 	CreatedAt     string
+	UpdatedAt     string
 	Digest        string
+	ContentType   string
+	State         string
+	Uploader      string
 	ReleaseTag    string
 	ReleaseName   string
+	DownloadCount int
 	FormattedDate string
 	SizeStr       string
 	DisplayLine   string
+
+	// Source code archives are pseudo-assets generated by GitHub for a tag,
+	// workflow artifacts are zip archives extracted after download,
+	// OCI blobs are layers of an artifact in a container registry;
+	// FileName is the local filename when it differs from Name
+	SourceArchive bool
+	Artifact      bool
+	OCIBlob       bool
+	FileName      string
+
+	// Detached signatures published next to the asset, and the owner/repo
+	// the asset was listed from
+	Signatures []signatureFile
+	Repository string
+}
+
+// LocalFileName returns the filename the asset is saved as
+func (a AssetInfo) LocalFileName() string {
+	if a.FileName != "" {
+		return a.FileName
+	}
+	return a.Name
 }
 
 // DownloadProgress structure for tracking download progress
@@ -61,27 +133,19 @@ type DownloadProgress struct {
 	downloadedBytes int64
 	totalBytes      int64
 	completed       bool
-}
+	skipped         bool
+	paused          bool
+	failed          bool
+	err             string // why the download failed
+	upToDate        bool   // an existing file already had the asset's digest
+	policy          OverwritePolicy
+	signature       string // signature status, once verified
 
-// ProgressReader structure for tracking download progress
-type ProgressReader struct {
-	reader     io.Reader
-	total      int64
-	downloaded int64
-	onProgress func(downloaded, total int64)
-}
-
-// Read implements io.Reader interface
-func (pr *ProgressReader) Read(p []byte) (int, error) {
-	n, err := pr.reader.Read(p)
-	pr.downloaded += int64(n)
-
-	// Call onProgress callback if provided
-	if pr.onProgress != nil {
-		pr.onProgress(pr.downloaded, pr.total)
-	}
-
-	return n, err
+	// Transfer timing; rate is a smoothed bytes-per-second estimate
+	started  time.Time
+	updated  time.Time
+	finished time.Time
+	rate     float64
 }
 
 // DownloadQueue manages the download queue and progress
@@ -89,6 +153,10 @@ type DownloadQueue struct {
 	assets       []AssetInfo
 	progress     []DownloadProgress
 	currentIndex int
+
+	// Batch timing for the overall progress line
+	started  time.Time
+	finished time.Time
 }
 
 func (dq *DownloadQueue) Add(asset AssetInfo) {
@@ -109,16 +177,43 @@ func (dq *DownloadQueue) GetCurrent() *AssetInfo {
 	return nil
 }
 
-func (dq *DownloadQueue) UpdateProgress(downloaded, total int64) {
+// StartCurrent records the start of the current download, and of the batch
+func (dq *DownloadQueue) StartCurrent(now time.Time) {
+	if dq.started.IsZero() {
+		dq.started = now
+	}
 	if dq.currentIndex >= 0 && dq.currentIndex < len(dq.progress) {
-		dq.progress[dq.currentIndex] = DownloadProgress{
-			downloadedBytes: downloaded,
-			totalBytes:      total,
-			completed:       downloaded >= total && total > 0,
+		// A resumed download keeps its original start time
+		if dq.progress[dq.currentIndex].started.IsZero() {
+			dq.progress[dq.currentIndex].started = now
 		}
+		dq.progress[dq.currentIndex].updated = now
 	}
 }
 
+// UpdateProgress records a progress event of the download at index
+func (dq *DownloadQueue) UpdateProgress(index int, downloaded, total int64, now time.Time) {
+	if index < 0 || index >= len(dq.progress) {
+		return
+	}
+	// Events still buffered when a download stopped are stale
+	if p := dq.progress[index]; p.completed || p.paused || p.skipped || p.failed {
+		return
+	}
+	progress := &dq.progress[index]
+	if elapsed := now.Sub(progress.updated).Seconds(); elapsed > 0 && downloaded >= progress.downloadedBytes {
+		instant := float64(downloaded-progress.downloadedBytes) / elapsed
+		if progress.rate == 0 {
+			progress.rate = instant
+		} else {
+			progress.rate = 0.7*progress.rate + 0.3*instant
+		}
+	}
+	progress.downloadedBytes = downloaded
+	progress.totalBytes = total
+	progress.updated = now
+}
+
 func (dq *DownloadQueue) CompleteCurrentDownload(actualSize int64) {
 	if dq.currentIndex >= 0 && dq.currentIndex < len(dq.progress) {
 		finalSize := actualSize
@@ -129,19 +224,99 @@ func (dq *DownloadQueue) CompleteCurrentDownload(actualSize int64) {
 			finalSize = dq.progress[dq.currentIndex].downloadedBytes
 		}
 
-		dq.progress[dq.currentIndex] = DownloadProgress{
-			downloadedBytes: finalSize,
-			totalBytes:      finalSize,
-			completed:       true,
+		progress := &dq.progress[dq.currentIndex]
+		progress.downloadedBytes = finalSize
+		progress.totalBytes = finalSize
+		progress.completed = true
+		progress.finished = time.Now()
+		if elapsed := progress.finished.Sub(progress.started).Seconds(); !progress.started.IsZero() && elapsed > 0 {
+			progress.rate = float64(finalSize) / elapsed
 		}
 	}
 }
 
+// PauseCurrent marks the current download as paused with downloaded bytes kept
+func (dq *DownloadQueue) PauseCurrent(downloaded int64) {
+	if dq.currentIndex >= 0 && dq.currentIndex < len(dq.progress) {
+		progress := &dq.progress[dq.currentIndex]
+		progress.paused = true
+		progress.downloadedBytes = downloaded
+		progress.rate = 0
+	}
+}
+
+// ResumeCurrent clears the paused mark of the current download and returns
+// the offset to resume it from
+func (dq *DownloadQueue) ResumeCurrent() int64 {
+	if dq.currentIndex >= 0 && dq.currentIndex < len(dq.progress) && dq.progress[dq.currentIndex].paused {
+		dq.progress[dq.currentIndex].paused = false
+		return dq.progress[dq.currentIndex].downloadedBytes
+	}
+	return 0
+}
+
+// MarkCurrentUpToDate marks the current download as not needed
+func (dq *DownloadQueue) MarkCurrentUpToDate() {
+	if dq.currentIndex >= 0 && dq.currentIndex < len(dq.progress) {
+		dq.progress[dq.currentIndex].upToDate = true
+		dq.progress[dq.currentIndex].completed = true
+	}
+}
+
+// FailCurrent marks the current download as failed for reason
+func (dq *DownloadQueue) FailCurrent(reason string) {
+	if dq.currentIndex >= 0 && dq.currentIndex < len(dq.progress) {
+		dq.progress[dq.currentIndex].failed = true
+		dq.progress[dq.currentIndex].err = reason
+	}
+}
+
+// FailedAssets returns the assets whose download failed
+func (dq *DownloadQueue) FailedAssets() []AssetInfo {
+	var failed []AssetInfo
+	for i, progress := range dq.progress {
+		if progress.failed {
+			failed = append(failed, dq.assets[i])
+		}
+	}
+	return failed
+}
+
+// SkippedCount returns the number of skipped downloads
+func (dq *DownloadQueue) SkippedCount() int {
+	count := 0
+	for _, progress := range dq.progress {
+		if progress.skipped {
+			count++
+		}
+	}
+	return count
+}
+
+// SkipCurrent marks the current download as skipped
+func (dq *DownloadQueue) SkipCurrent() {
+	if dq.currentIndex >= 0 && dq.currentIndex < len(dq.progress) {
+		dq.progress[dq.currentIndex].skipped = true
+	}
+}
+
+// SetSignatureStatus records the signature status of the current download
+func (dq *DownloadQueue) SetSignatureStatus(status string) {
+	if dq.currentIndex >= 0 && dq.currentIndex < len(dq.progress) {
+		dq.progress[dq.currentIndex].signature = status
+	}
+}
+
 func (dq *DownloadQueue) NextDownload() bool {
 	dq.currentIndex++
 	return dq.currentIndex < len(dq.assets)
 }
 
+// Finish records the end of the batch
+func (dq *DownloadQueue) Finish(now time.Time) {
+	dq.finished = now
+}
+
 func (dq *DownloadQueue) IsEmpty() bool {
 	return len(dq.assets) == 0
 }
@@ -150,13 +325,18 @@ func (dq *DownloadQueue) Reset() {
 	dq.assets = []AssetInfo{}
 	dq.progress = []DownloadProgress{}
 	dq.currentIndex = 0
+	dq.started = time.Time{}
+	dq.finished = time.Time{}
 }
 
 // ViewState represents the current state of the application
 type ViewState int
 
 const (
-	StateReleases ViewState = iota
+	StateRecent ViewState = iota
+	StateRepositories
+	StateReleases
+	StateRuns
 	StateAssets
 	StateDownloading
 	StateFinished
@@ -168,25 +348,59 @@ type errorMsg string
 type releasesData struct {
 	assets   []AssetInfo
 	releases []Release
+	owner    string
+	name     string
 }
 
 type releasesMsg releasesData
+type repositoriesMsg []Repository
+
+// repositoryReleasesMsg is the release check result of one repository
+type repositoryReleasesMsg struct {
+	checks   <-chan repositoryReleasesMsg // the batch of checks it belongs to
+	fullName string
+	result   repositoryReleases
+}
+
 type downloadErrorMsg string
-type cancelDownloadMsg struct{}
+
+// downloadCancelledMsg reports a download stopped by skipping it or
+// cancelling the batch
+type downloadCancelledMsg struct{}
+
+// downloadUpToDateMsg reports a download not needed because filename
+// already has the asset's digest
+type downloadUpToDateMsg struct {
+	filename string
+}
+
+// downloadPausedMsg reports a paused download and the bytes kept of it
+type downloadPausedMsg struct {
+	downloaded int64
+}
 
 // checksumVerifiedMsg message to indicate checksum verification result
 type checksumVerifiedMsg struct {
-	filename string
-	success  bool
-	err      string
+	filename  string
+	success   bool
+	err       string
+	signature string
+	hookErr   string
 }
 
-// startDownloadProgressMsg message to start download progress updates
-type startDownloadProgressMsg struct {
-	asset AssetInfo
+// signatureFailedMsg reports a download removed because of its signature
+type signatureFailedMsg struct {
+	filename  string
+	signature string
+	err       string
 }
 
-// updateDownloadProgressMsg message to update download progress
-type updateDownloadProgressMsg struct {
-	asset AssetInfo
+// downloadProgressMsg is a progress event of the queued download at index
+type downloadProgressMsg struct {
+	index      int
+	downloaded int64
+	total      int64
 }
+
+// progressTickMsg redraws the elapsed time while downloads are stalled
+type progressTickMsg time.Time
@@ -2,46 +2,167 @@ package main
 
 import (
 	"fmt"
+	"path"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 )
 
-// NavigationHandler handles common navigation keys
-type NavigationHandler struct {
-	cursor   *int
-	maxItems int
-}
+// listKind identifies which kind of item a UnifiedListView is showing
+type listKind int
 
-func (nh NavigationHandler) HandleKey(key string) bool {
-	switch key {
-	case "up", "k":
-		if *nh.cursor > 0 {
-			(*nh.cursor)--
-		}
-		return true
-	case "down", "j":
-		if *nh.cursor < nh.maxItems-1 {
-			(*nh.cursor)++
-		}
-		return true
-	}
-	return false
-}
+const (
+	listReleases listKind = iota
+	listAssets
+	listRepositories
+	listRecent
+	listRuns
+)
 
-// UnifiedListView handles both releases and assets display
+// UnifiedListView handles repositories, releases and assets display
 type UnifiedListView struct {
-	items         []interface{}
-	cursor        int
-	selected      []bool
-	multiSelect   bool
-	title         string
-	instructions  string
+	kind            listKind
+	items           []interface{}
+	cursor          int
+	selected        []bool
+	multiSelect     bool
+	title           string
+	instructions    string
 	filter          string
 	filteredItems   []interface{}
-	filteredIndices []int // original items index for each filteredItems entry; nil = 1:1
+	filteredIndices []int         // original items index for each filteredItems entry; nil = 1:1
+	filteredMatches []fuzzyResult // match details for each filteredItems entry; nil when unfiltered
 	searchEnabled   bool
 	searchActive    bool
+
+	// Column sort; SortNone keeps API order (or match score while searching)
+	sortColumn SortColumn
+	sortDesc   bool
+
+	// Detail pane for the highlighted asset
+	showDetails bool
+
+	// Bulk selection: glob prompt and visual (range) mode
+	globActive     bool
+	globInput      string
+	visualActive   bool
+	visualAnchor   int
+	visualSnapshot []bool
+
+	// One-line feedback shown under the list (glob matches, clipboard copies)
+	status string
+
+	// Viewport state; width and height are zero until the terminal size is known
+	offset int
+	width  int
+	height int
+}
+
+func (ulv *UnifiedListView) SetRepositories(repos []Repository) {
+	ulv.items = make([]interface{}, len(repos))
+	for i, repo := range repos {
+		ulv.items[i] = repo
+	}
+	ulv.kind = listRepositories
+	ulv.cursor = 0
+	ulv.offset = 0
+	ulv.selected = nil
+	ulv.multiSelect = false
+	ulv.searchEnabled = true
+	ulv.searchActive = false
+	ulv.filter = ""
+	ulv.sortColumn = SortNone
+	ulv.sortDesc = false
+	ulv.filteredItems = ulv.items
+	ulv.filteredIndices = nil
+	ulv.filteredMatches = nil
+	ulv.resetBulkSelection()
+	ulv.title = "Select repository:"
+	ulv.instructions = "Press '/' to search, '↑/↓' or 'j/k' to navigate, 'pgup/pgdn' and 'g/G' to jump, 's/r' to sort/reverse, 'S' to search GitHub, 'b' to bookmark, 'enter' to browse releases, 'q' to quit"
+}
+
+func (ulv *UnifiedListView) SetRecentRepositories(history []RepositoryHistory) {
+	ulv.items = make([]interface{}, len(history))
+	for i, entry := range history {
+		ulv.items[i] = entry
+	}
+	ulv.kind = listRecent
+	ulv.cursor = 0
+	ulv.offset = 0
+	ulv.selected = nil
+	ulv.multiSelect = false
+	ulv.searchEnabled = true
+	ulv.searchActive = false
+	ulv.filter = ""
+	ulv.sortColumn = SortNone
+	ulv.sortDesc = false
+	ulv.filteredItems = ulv.items
+	ulv.filteredIndices = nil
+	ulv.filteredMatches = nil
+	ulv.resetBulkSelection()
+	ulv.title = "Recent and bookmarked repositories:"
+	ulv.instructions = "Press '/' to search, '↑/↓' or 'j/k' to navigate, 's/r' to sort/reverse, 'b' to toggle bookmark, 'enter' to browse releases, 'q' to quit"
+}
+
+// UpdateRecentRepositories replaces the listed history entries, keeping the
+// filter, sort order and highlighted entry
+func (ulv *UnifiedListView) UpdateRecentRepositories(history []RepositoryHistory) {
+	if ulv.kind != listRecent {
+		return
+	}
+	current := ulv.GetCurrentRecent()
+	ulv.items = make([]interface{}, len(history))
+	for i, entry := range history {
+		ulv.items[i] = entry
+	}
+	ulv.applyFilterAndSort()
+	ulv.cursor = 0
+	for i, item := range ulv.filteredItems {
+		if current != nil && item.(RepositoryHistory).FullName() == current.FullName() {
+			ulv.cursor = i
+			break
+		}
+	}
+	ulv.ensureCursorVisible()
+}
+
+// UpdateRepositories replaces the listed repositories, keeping the filter,
+// sort order and highlighted repository
+func (ulv *UnifiedListView) UpdateRepositories(repos []Repository) {
+	if ulv.kind != listRepositories {
+		return
+	}
+	ulv.items = make([]interface{}, len(repos))
+	for i, repo := range repos {
+		ulv.items[i] = repo
+	}
+	ulv.SetSort(ulv.sortColumn, ulv.sortDesc)
+}
+
+func (ulv *UnifiedListView) SetWorkflowRuns(runs []WorkflowRun) {
+	ulv.items = make([]interface{}, len(runs))
+	for i, run := range runs {
+		ulv.items[i] = run
+	}
+	ulv.kind = listRuns
+	ulv.cursor = 0
+	ulv.offset = 0
+	ulv.selected = nil
+	ulv.multiSelect = false
+	ulv.searchEnabled = true
+	ulv.searchActive = false
+	ulv.filter = ""
+	ulv.sortColumn = SortNone
+	ulv.sortDesc = false
+	ulv.filteredItems = ulv.items
+	ulv.filteredIndices = nil
+	ulv.filteredMatches = nil
+	ulv.resetBulkSelection()
+	ulv.title = "Select workflow run:"
+	ulv.instructions = "Press '/' to filter by workflow, branch or status, '↑/↓' or 'j/k' to navigate, 's/r' to sort/reverse, 'enter' to list artifacts, 'q' to go back"
 }
 
 func (ulv *UnifiedListView) SetReleases(releases []Release) {
@@ -49,15 +170,22 @@ func (ulv *UnifiedListView) SetReleases(releases []Release) {
 	for i, release := range releases {
 		ulv.items[i] = release
 	}
+	ulv.kind = listReleases
 	ulv.cursor = 0
+	ulv.offset = 0
 	ulv.selected = nil
 	ulv.multiSelect = false
 	ulv.searchEnabled = true
 	ulv.searchActive = false
 	ulv.filter = ""
+	ulv.sortColumn = SortNone
+	ulv.sortDesc = false
 	ulv.filteredItems = ulv.items
+	ulv.filteredIndices = nil
+	ulv.filteredMatches = nil
+	ulv.resetBulkSelection()
 	ulv.title = "Select release:"
-	ulv.instructions = "Press '/' to search, '↑/↓' or 'j/k' to navigate, 'enter' to select, 'q' to quit"
+	ulv.instructions = "Press '/' to search, '↑/↓' or 'j/k' to navigate, 'pgup/pgdn' and 'g/G' to jump, 's/r' to sort/reverse, 'b' to bookmark repo, 'A' for workflow artifacts, 'enter' to select, 'q' to quit"
 }
 
 func (ulv *UnifiedListView) SetAssets(assets []AssetInfo) {
@@ -65,38 +193,97 @@ func (ulv *UnifiedListView) SetAssets(assets []AssetInfo) {
 	for i, asset := range assets {
 		ulv.items[i] = asset
 	}
+	ulv.kind = listAssets
 	ulv.cursor = 0
+	ulv.offset = 0
 	ulv.selected = make([]bool, len(assets))
 	ulv.multiSelect = true
 	ulv.searchEnabled = true
 	ulv.searchActive = false
 	ulv.filter = ""
+	ulv.sortColumn = SortNone
+	ulv.sortDesc = false
 	ulv.filteredItems = ulv.items
 	ulv.filteredIndices = nil
+	ulv.filteredMatches = nil
+	ulv.resetBulkSelection()
 	ulv.title = "Select assets to download (press space to select, enter to download):"
-	ulv.instructions = "Press '/' to search, '↑/↓' or 'j/k' to navigate, 'space' to select, 'enter' to download, 'q' to go back"
+	ulv.instructions = "Press '/' to search, '↑/↓' or 'j/k' to navigate, 'pgup/pgdn' and 'g/G' to jump, 'space' to select, 'a/x/i' all/none/invert, '*' glob, 'v' range, 's/r' to sort/reverse, 'd' details, 'c/C' copy URL/digest, 'enter' to download, 'q' to go back"
 }
 
 func (ulv *UnifiedListView) SetFilter(f string) {
 	ulv.filter = f
 	ulv.cursor = 0
-	if f == "" || !ulv.searchEnabled {
+	ulv.offset = 0
+	ulv.visualActive = false
+	ulv.visualSnapshot = nil
+	ulv.applyFilterAndSort()
+}
+
+// applyFilterAndSort rebuilds filteredItems from the filter and sort settings
+func (ulv *UnifiedListView) applyFilterAndSort() {
+	filtering := ulv.filter != "" && ulv.searchEnabled
+	if !filtering && ulv.sortColumn == SortNone {
 		ulv.filteredItems = ulv.items
 		ulv.filteredIndices = nil
+		ulv.filteredMatches = nil
 		return
 	}
-	ulv.filteredItems = []interface{}{}
-	ulv.filteredIndices = []int{}
+
+	type rankedItem struct {
+		index int
+		match fuzzyResult
+	}
+	var ranked []rankedItem
 	for i, item := range ulv.items {
+		if !filtering {
+			ranked = append(ranked, rankedItem{index: i})
+			continue
+		}
+		var match fuzzyResult
 		var matches bool
 		if r, ok := item.(Release); ok {
-			matches = fuzzyMatch(f, r.TagName) || fuzzyMatch(f, r.Name)
+			match, matches = bestFuzzyMatch(ulv.filter, r.TagName, r.Name)
 		} else if a, ok := item.(AssetInfo); ok {
-			matches = fuzzyMatch(f, a.Name) || fuzzyMatch(f, a.ReleaseTag)
+			match, matches = bestFuzzyMatch(ulv.filter, a.Name, a.ReleaseTag)
+		} else if repo, ok := item.(Repository); ok {
+			match, matches = bestFuzzyMatch(ulv.filter, repo.FullName, repo.Description)
+		} else if entry, ok := item.(RepositoryHistory); ok {
+			match, matches = bestFuzzyMatch(ulv.filter, entry.FullName())
+		} else if run, ok := item.(WorkflowRun); ok {
+			match, matches = bestFuzzyMatch(ulv.filter, run.Name, run.HeadBranch, run.Result(), run.DisplayTitle)
 		}
 		if matches {
-			ulv.filteredItems = append(ulv.filteredItems, item)
-			ulv.filteredIndices = append(ulv.filteredIndices, i)
+			ranked = append(ranked, rankedItem{index: i, match: match})
+		}
+	}
+	if ulv.sortColumn != SortNone {
+		// An explicit column sort takes precedence over match score
+		sort.SliceStable(ranked, func(a, b int) bool {
+			c := compareItems(ulv.items[ranked[a].index], ulv.items[ranked[b].index], ulv.sortColumn)
+			if ulv.sortDesc {
+				return c > 0
+			}
+			return c < 0
+		})
+	} else {
+		// Best matches first; equal scores keep their original order
+		sort.SliceStable(ranked, func(a, b int) bool {
+			return ranked[a].match.score > ranked[b].match.score
+		})
+	}
+
+	ulv.filteredItems = make([]interface{}, len(ranked))
+	ulv.filteredIndices = make([]int, len(ranked))
+	ulv.filteredMatches = nil
+	if filtering {
+		ulv.filteredMatches = make([]fuzzyResult, len(ranked))
+	}
+	for i, r := range ranked {
+		ulv.filteredItems[i] = ulv.items[r.index]
+		ulv.filteredIndices[i] = r.index
+		if filtering {
+			ulv.filteredMatches[i] = r.match
 		}
 	}
 }
@@ -112,8 +299,121 @@ func (ulv *UnifiedListView) BackspaceFilter() {
 	}
 }
 
-func fuzzyMatch(pattern, text string) bool {
-	return strings.Contains(strings.ToLower(text), strings.ToLower(pattern))
+// SetSize updates the terminal dimensions used for windowing and truncation
+func (ulv *UnifiedListView) SetSize(width, height int) {
+	ulv.width = width
+	ulv.height = height
+	ulv.ensureCursorVisible()
+}
+
+// visibleRows returns how many list rows fit in the terminal
+func (ulv *UnifiedListView) visibleRows() int {
+	if ulv.height <= 0 {
+		return len(ulv.filteredItems)
+	}
+	// Title, column header, instructions and the scroll indicator are always rendered
+	reserved := 6
+	if ulv.searchEnabled && (ulv.searchActive || ulv.filter != "") {
+		reserved += 2
+	}
+	if ulv.globActive {
+		reserved += 2
+	}
+	// Long instructions wrap onto extra lines on narrow terminals
+	if ulv.width > 0 {
+		reserved += (ansi.StringWidth(ulv.instructions) - 1) / ulv.width
+	}
+	if ulv.multiSelect || ulv.status != "" {
+		reserved += 2
+	}
+	if ulv.showDetails && ulv.multiSelect {
+		reserved += detailPaneHeight()
+	}
+	rows := ulv.height - reserved
+	if rows < 1 {
+		rows = 1
+	}
+	return rows
+}
+
+// ensureCursorVisible scrolls the viewport so the cursor stays on screen
+func (ulv *UnifiedListView) ensureCursorVisible() {
+	rows := ulv.visibleRows()
+	if ulv.cursor < ulv.offset {
+		ulv.offset = ulv.cursor
+	}
+	if ulv.cursor >= ulv.offset+rows {
+		ulv.offset = ulv.cursor - rows + 1
+	}
+	if maxOffset := len(ulv.filteredItems) - rows; ulv.offset > maxOffset {
+		ulv.offset = maxOffset
+	}
+	if ulv.offset < 0 {
+		ulv.offset = 0
+	}
+}
+
+// moveCursor moves the cursor by delta rows, clamped to the filtered list
+func (ulv *UnifiedListView) moveCursor(delta int) {
+	ulv.cursor += delta
+	if ulv.cursor > len(ulv.filteredItems)-1 {
+		ulv.cursor = len(ulv.filteredItems) - 1
+	}
+	if ulv.cursor < 0 {
+		ulv.cursor = 0
+	}
+	ulv.ensureCursorVisible()
+	if ulv.visualActive {
+		ulv.applyVisualRange()
+	}
+}
+
+func (ulv *UnifiedListView) CursorUp() { ulv.moveCursor(-1) }
+
+func (ulv *UnifiedListView) CursorDown() { ulv.moveCursor(1) }
+
+func (ulv *UnifiedListView) PageUp() { ulv.moveCursor(-ulv.visibleRows()) }
+
+func (ulv *UnifiedListView) PageDown() { ulv.moveCursor(ulv.visibleRows()) }
+
+func (ulv *UnifiedListView) CursorHome() { ulv.moveCursor(-len(ulv.filteredItems)) }
+
+func (ulv *UnifiedListView) CursorEnd() { ulv.moveCursor(len(ulv.filteredItems)) }
+
+// HandleNavigationKey applies scrolling keys shared by every list screen.
+// Letter shortcuts are ignored while a search query is being typed.
+func (ulv *UnifiedListView) HandleNavigationKey(key string) bool {
+	switch key {
+	case "up":
+		ulv.CursorUp()
+	case "down":
+		ulv.CursorDown()
+	case "pgup", "ctrl+b":
+		ulv.PageUp()
+	case "pgdown", "ctrl+f":
+		ulv.PageDown()
+	case "home":
+		ulv.CursorHome()
+	case "end":
+		ulv.CursorEnd()
+	default:
+		if ulv.searchActive {
+			return false
+		}
+		switch key {
+		case "k":
+			ulv.CursorUp()
+		case "j":
+			ulv.CursorDown()
+		case "g":
+			ulv.CursorHome()
+		case "G":
+			ulv.CursorEnd()
+		default:
+			return false
+		}
+	}
+	return true
 }
 
 func (ulv *UnifiedListView) GetSelectedCount() int {
@@ -142,6 +442,217 @@ func (ulv *UnifiedListView) ToggleSelection() {
 	}
 }
 
+// originalIndex maps a filteredItems position to its index in items
+func (ulv *UnifiedListView) originalIndex(i int) int {
+	if ulv.filteredIndices != nil && i < len(ulv.filteredIndices) {
+		return ulv.filteredIndices[i]
+	}
+	return i
+}
+
+// setFilteredSelection applies fn to the selection state of every filtered item
+func (ulv *UnifiedListView) setFilteredSelection(fn func(i int, selected bool) bool) {
+	if !ulv.multiSelect {
+		return
+	}
+	for i := range ulv.filteredItems {
+		idx := ulv.originalIndex(i)
+		if idx < len(ulv.selected) {
+			ulv.selected[idx] = fn(i, ulv.selected[idx])
+		}
+	}
+}
+
+// SelectAll selects every item in the current filtered set
+func (ulv *UnifiedListView) SelectAll() {
+	ulv.setFilteredSelection(func(int, bool) bool { return true })
+}
+
+// SelectNone deselects every item in the current filtered set
+func (ulv *UnifiedListView) SelectNone() {
+	ulv.setFilteredSelection(func(int, bool) bool { return false })
+}
+
+// InvertSelection flips the selection of every item in the current filtered set
+func (ulv *UnifiedListView) InvertSelection() {
+	ulv.setFilteredSelection(func(_ int, selected bool) bool { return !selected })
+}
+
+// SelectByGlob selects filtered assets whose name matches pattern and
+// returns how many matched
+func (ulv *UnifiedListView) SelectByGlob(pattern string) (int, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return 0, err
+	}
+	count := 0
+	ulv.setFilteredSelection(func(i int, selected bool) bool {
+		if asset, ok := ulv.filteredItems[i].(AssetInfo); ok {
+			if matched, _ := path.Match(pattern, asset.Name); matched {
+				count++
+				return true
+			}
+		}
+		return selected
+	})
+	return count, nil
+}
+
+// ActivateGlobPrompt opens the "select by glob" prompt
+func (ulv *UnifiedListView) ActivateGlobPrompt() {
+	ulv.globActive = true
+	ulv.globInput = ""
+	ulv.status = ""
+}
+
+// SubmitGlobPrompt closes the glob prompt and applies the typed pattern.
+// It returns the pattern if it was valid.
+func (ulv *UnifiedListView) SubmitGlobPrompt() string {
+	ulv.globActive = false
+	if ulv.globInput == "" {
+		return ""
+	}
+	count, err := ulv.SelectByGlob(ulv.globInput)
+	if err != nil {
+		ulv.status = fmt.Sprintf("invalid pattern %q: %v", ulv.globInput, err)
+		return ""
+	}
+	ulv.status = fmt.Sprintf("%d asset(s) matched %q", count, ulv.globInput)
+	return ulv.globInput
+}
+
+// CancelGlobPrompt closes the glob prompt without selecting anything
+func (ulv *UnifiedListView) CancelGlobPrompt() {
+	ulv.globActive = false
+	ulv.globInput = ""
+}
+
+// HandleGlobPromptKey edits the glob prompt input and returns the pattern
+// once a valid one has been submitted
+func (ulv *UnifiedListView) HandleGlobPromptKey(key string) string {
+	switch key {
+	case "esc":
+		ulv.CancelGlobPrompt()
+	case "enter":
+		return ulv.SubmitGlobPrompt()
+	case "backspace":
+		if runes := []rune(ulv.globInput); len(runes) > 0 {
+			ulv.globInput = string(runes[:len(runes)-1])
+		}
+	default:
+		if len([]rune(key)) == 1 {
+			ulv.globInput += key
+		}
+	}
+	return ""
+}
+
+// ToggleVisualMode starts range selection at the cursor, or keeps the
+// selected range and leaves range selection if it is already active
+func (ulv *UnifiedListView) ToggleVisualMode() {
+	if !ulv.multiSelect {
+		return
+	}
+	if ulv.visualActive {
+		ulv.visualActive = false
+		ulv.visualSnapshot = nil
+		return
+	}
+	ulv.visualActive = true
+	ulv.visualAnchor = ulv.cursor
+	ulv.visualSnapshot = append([]bool(nil), ulv.selected...)
+	ulv.applyVisualRange()
+}
+
+// CancelVisualMode leaves range selection and restores the previous selection
+func (ulv *UnifiedListView) CancelVisualMode() {
+	if !ulv.visualActive {
+		return
+	}
+	copy(ulv.selected, ulv.visualSnapshot)
+	ulv.visualActive = false
+	ulv.visualSnapshot = nil
+}
+
+// applyVisualRange selects everything between the anchor and the cursor on
+// top of the selection that existed when range selection started
+func (ulv *UnifiedListView) applyVisualRange() {
+	lo, hi := ulv.visualAnchor, ulv.cursor
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	copy(ulv.selected, ulv.visualSnapshot)
+	ulv.setFilteredSelection(func(i int, selected bool) bool {
+		return selected || (i >= lo && i <= hi)
+	})
+}
+
+// ExtendSelection selects the current row, moves the cursor by delta and
+// selects the new row (shift+arrow style range selection)
+func (ulv *UnifiedListView) ExtendSelection(delta int) {
+	if !ulv.multiSelect || len(ulv.filteredItems) == 0 {
+		return
+	}
+	mark := func() {
+		if idx := ulv.originalIndex(ulv.cursor); idx < len(ulv.selected) {
+			ulv.selected[idx] = true
+		}
+	}
+	mark()
+	ulv.moveCursor(delta)
+	mark()
+}
+
+// HandleSelectionKey applies bulk selection keys in the asset view
+func (ulv *UnifiedListView) HandleSelectionKey(key string) bool {
+	if !ulv.multiSelect {
+		return false
+	}
+	switch key {
+	case " ":
+		ulv.ToggleSelection()
+	case "a":
+		ulv.SelectAll()
+	case "x":
+		ulv.SelectNone()
+	case "i":
+		ulv.InvertSelection()
+	case "*":
+		ulv.ActivateGlobPrompt()
+	case "v", "V":
+		ulv.ToggleVisualMode()
+	case "shift+up", "K":
+		ulv.ExtendSelection(-1)
+	case "shift+down", "J":
+		ulv.ExtendSelection(1)
+	default:
+		return false
+	}
+	return true
+}
+
+// ToggleDetails shows or hides the detail pane for the highlighted asset
+func (ulv *UnifiedListView) ToggleDetails() {
+	if !ulv.multiSelect {
+		return
+	}
+	ulv.showDetails = !ulv.showDetails
+	ulv.ensureCursorVisible()
+}
+
+// resetBulkSelection clears prompt and range selection state
+func (ulv *UnifiedListView) resetBulkSelection() {
+	ulv.globActive = false
+	ulv.globInput = ""
+	ulv.visualActive = false
+	ulv.visualSnapshot = nil
+	ulv.status = ""
+}
+
+// InputActive reports whether the list is capturing typed text
+func (ulv *UnifiedListView) InputActive() bool {
+	return ulv.searchActive || ulv.globActive
+}
+
 func (ulv *UnifiedListView) GetSelectedAssets() []AssetInfo {
 	var result []AssetInfo
 	if !ulv.multiSelect {
@@ -167,6 +678,44 @@ func (ulv *UnifiedListView) GetCurrentAsset() *AssetInfo {
 	return nil
 }
 
+func (ulv *UnifiedListView) GetCurrentRepository() *Repository {
+	if ulv.cursor < len(ulv.filteredItems) {
+		if repo, ok := ulv.filteredItems[ulv.cursor].(Repository); ok {
+			return &repo
+		}
+	}
+	return nil
+}
+
+func (ulv *UnifiedListView) GetCurrentRecent() *RepositoryHistory {
+	if ulv.cursor < len(ulv.filteredItems) {
+		if entry, ok := ulv.filteredItems[ulv.cursor].(RepositoryHistory); ok {
+			return &entry
+		}
+	}
+	return nil
+}
+
+// SelectReleaseTag moves the cursor to the release tagged tag, if listed
+func (ulv *UnifiedListView) SelectReleaseTag(tag string) {
+	for i, item := range ulv.filteredItems {
+		if release, ok := item.(Release); ok && release.TagName == tag {
+			ulv.cursor = i
+			ulv.ensureCursorVisible()
+			return
+		}
+	}
+}
+
+func (ulv *UnifiedListView) GetCurrentRun() *WorkflowRun {
+	if ulv.cursor < len(ulv.filteredItems) {
+		if run, ok := ulv.filteredItems[ulv.cursor].(WorkflowRun); ok {
+			return &run
+		}
+	}
+	return nil
+}
+
 func (ulv *UnifiedListView) GetCurrentRelease() *Release {
 	if ulv.cursor < len(ulv.filteredItems) {
 		if release, ok := ulv.filteredItems[ulv.cursor].(Release); ok {
@@ -183,6 +732,7 @@ func (ulv *UnifiedListView) Render() string {
 	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("170")).Bold(true)
 	defaultStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("15"))
 	selectedAssetStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("46")) // Green
+	matchStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Underline(true)
 	infoStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
 	searchStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
 
@@ -194,20 +744,39 @@ func (ulv *UnifiedListView) Render() string {
 			s += searchStyle.Render("/ "+ulv.filter) + "\n\n"
 		}
 	}
+	if ulv.globActive {
+		s += searchStyle.Render("select glob: "+ulv.globInput+"█") + "\n\n"
+	}
+
+	// Column header
+	columns := ulv.columns()
+	header := "  "
+	if ulv.multiSelect {
+		header += "     "
+	}
+	header += ulv.formatHeader(columns)
+	if ulv.width > 0 {
+		header = ansi.Truncate(header, ulv.width-1, "…")
+	}
+	s += infoStyle.Render(header) + "\n"
 
 	// Display filtered items
 	items := ulv.filteredItems
 	if len(items) == 0 && ulv.filter != "" {
 		s += infoStyle.Render("  no results found") + "\n"
 	}
-	for i, item := range items {
-		var line string
+	ulv.ensureCursorVisible()
+	start := ulv.offset
+	end := start + ulv.visibleRows()
+	if end > len(items) {
+		end = len(items)
+	}
+	for i := start; i < end; i++ {
+		item := items[i]
+		line := formatRow(columns, item)
 		var selectionMarker string
 
-		if release, ok := item.(Release); ok {
-			line = fmt.Sprintf("[%s] %s", release.TagName, release.Name)
-		} else if asset, ok := item.(AssetInfo); ok {
-			line = asset.DisplayLine
+		if _, ok := item.(AssetInfo); ok {
 			if ulv.multiSelect {
 				originalIdx := i
 				if ulv.filteredIndices != nil && i < len(ulv.filteredIndices) {
@@ -220,91 +789,312 @@ func (ulv *UnifiedListView) Render() string {
 			}
 		}
 
+		prefix := "  "
+		style := defaultStyle
 		if i == ulv.cursor {
-			s += selectedStyle.Render("> "+selectionMarker+line) + "\n"
-		} else {
-			s += defaultStyle.Render("  "+selectionMarker+line) + "\n"
+			prefix = "> "
+			style = selectedStyle
+		}
+		if selectionMarker == " [ ] " {
+			selectionMarker = style.Render(selectionMarker)
+		}
+
+		var highlighted map[int]bool
+		if i < len(ulv.filteredMatches) {
+			highlighted = highlightPositions(line, ulv.filteredMatches[i])
+		}
+		row := style.Render(prefix) + selectionMarker + renderHighlighted(line, highlighted, style, matchStyle.Bold(style.GetBold()))
+		if ulv.width > 0 {
+			row = ansi.Truncate(row, ulv.width-1, "…")
+		}
+		s += row + "\n"
+	}
+
+	// Scroll position indicator
+	if len(items) > end-start {
+		s += infoStyle.Render(fmt.Sprintf("  [%d-%d of %d]", start+1, end, len(items))) + "\n"
+	}
+
+	// Detail pane for the highlighted asset
+	if ulv.showDetails {
+		if asset := ulv.GetCurrentAsset(); asset != nil {
+			s += renderAssetDetails(*asset, ulv.width)
 		}
 	}
 
-	// Display selection info for multi-select mode
+	// Display selection info for multi-select mode and status feedback
+	var info []string
 	if ulv.multiSelect {
-		selectedCount := ulv.GetSelectedCount()
-		if selectedCount > 0 {
-			s += "\n" + infoStyle.Render(fmt.Sprintf("%d asset(s) selected", selectedCount)) + "\n"
+		if selectedCount := ulv.GetSelectedCount(); selectedCount > 0 {
+			info = append(info, fmt.Sprintf("%d asset(s) selected", selectedCount))
+		}
+		if ulv.visualActive {
+			info = append(info, "-- RANGE -- ('v' to keep, 'esc' to cancel)")
 		}
 	}
+	if ulv.status != "" {
+		info = append(info, ulv.status)
+	}
+	if len(info) > 0 {
+		s += "\n" + infoStyle.Render(strings.Join(info, " · ")) + "\n"
+	}
 
 	s += "\n" + ulv.instructions + "\n"
 	return s
 }
 
+// renderHighlighted styles line with base, using match for the runes in highlighted
+func renderHighlighted(line string, highlighted map[int]bool, base, match lipgloss.Style) string {
+	if len(highlighted) == 0 {
+		return base.Render(line)
+	}
+	var b strings.Builder
+	var segment []rune
+	segmentMatched := false
+	flush := func() {
+		if len(segment) == 0 {
+			return
+		}
+		if segmentMatched {
+			b.WriteString(match.Render(string(segment)))
+		} else {
+			b.WriteString(base.Render(string(segment)))
+		}
+		segment = segment[:0]
+	}
+	for i, r := range []rune(line) {
+		if highlighted[i] != segmentMatched {
+			flush()
+			segmentMatched = highlighted[i]
+		}
+		segment = append(segment, r)
+	}
+	flush()
+	return b.String()
+}
+
 // ProgressFormatter handles progress display formatting
 type ProgressFormatter struct{}
 
-func (pf ProgressFormatter) FormatProgress(asset AssetInfo, progress DownloadProgress) (string, string) {
+// Width of the progress bars in the download table, the time without
+// progress events after which a download's rate is shown as unknown, and
+// the width of failure reasons in the table
+const (
+	progressBarWidth = 20
+	progressStalled  = 3 * time.Second
+	maxFailureWidth  = 60
+)
+
+func (pf ProgressFormatter) FormatProgress(asset AssetInfo, progress DownloadProgress, now time.Time) (string, string) {
 	var status, progressInfo string
 
-	if progress.completed {
+	totalSize := progress.totalBytes
+	if totalSize == 0 && asset.Size > 0 {
+		totalSize = asset.Size
+	}
+
+	if progress.upToDate {
+		status = "[=]"
+		progressInfo = "already up to date"
+	} else if progress.failed {
+		status = "[✗]"
+		progressInfo = truncateString(progress.err, maxFailureWidth)
+	} else if progress.skipped {
+		status = "skipped"
+		if totalSize > 0 {
+			progressInfo = fmt.Sprintf("%s / %s", formatTransferred(progress.downloadedBytes), formatSize(totalSize))
+		} else {
+			progressInfo = formatTransferred(progress.downloadedBytes) + " / Unknown"
+		}
+	} else if progress.completed {
 		status = "[✓]"
-		if progress.totalBytes > 0 {
-			progressInfo = fmt.Sprintf("%s / %s", formatSize(progress.totalBytes), formatSize(progress.totalBytes))
-		} else if asset.Size > 0 {
-			progressInfo = fmt.Sprintf("%s / %s", formatSize(asset.Size), formatSize(asset.Size))
+		size := totalSize
+		if size == 0 {
+			size = progress.downloadedBytes
+		}
+		progressInfo = fmt.Sprintf("%s 100%%  %s / %s", progressBar(1), formatSize(size), formatSize(size))
+		if !progress.started.IsZero() {
+			progressInfo += fmt.Sprintf("  %s  in %s", formatRate(progress.rate), formatDuration(progress.finished.Sub(progress.started)))
+		}
+	} else if progress.paused {
+		status = "paused"
+		if totalSize > 0 {
+			fraction := float64(progress.downloadedBytes) / float64(totalSize)
+			progressInfo = fmt.Sprintf("%s %3.0f%%  %s / %s",
+				progressBar(fraction), fraction*100, formatTransferred(progress.downloadedBytes), formatSize(totalSize))
 		} else {
-			progressInfo = formatSize(progress.downloadedBytes) + " / " + formatSize(progress.downloadedBytes)
+			progressInfo = fmt.Sprintf("%s  %s / Unknown", progressBar(0), formatTransferred(progress.downloadedBytes))
 		}
-	} else if progress.downloadedBytes > 0 || progress.totalBytes > 0 {
+	} else if !progress.started.IsZero() || progress.downloadedBytes > 0 {
 		status = "[-]"
-		totalSize := progress.totalBytes
-		if totalSize == 0 && asset.Size > 0 {
-			totalSize = asset.Size
+		if now.Sub(progress.updated) > progressStalled {
+			progress.rate = 0
 		}
 		if totalSize > 0 {
-			progressInfo = fmt.Sprintf("%s / %s", formatSize(progress.downloadedBytes), formatSize(totalSize))
+			fraction := float64(progress.downloadedBytes) / float64(totalSize)
+			progressInfo = fmt.Sprintf("%s %3.0f%%  %s / %s  %s  ETA %s",
+				progressBar(fraction), fraction*100,
+				formatTransferred(progress.downloadedBytes), formatSize(totalSize),
+				formatRate(progress.rate), formatETA(totalSize-progress.downloadedBytes, progress.rate))
 		} else {
-			progressInfo = formatSize(progress.downloadedBytes) + " / Unknown"
+			progressInfo = fmt.Sprintf("%s  %s / Unknown  %s",
+				progressBar(0), formatTransferred(progress.downloadedBytes), formatRate(progress.rate))
 		}
 	} else {
 		status = "[ ]"
-		if asset.Size > 0 {
-			progressInfo = fmt.Sprintf("%s / %s", formatSize(0), formatSize(asset.Size))
+		if totalSize > 0 {
+			progressInfo = fmt.Sprintf("%s   0%%  %s / %s", progressBar(0), formatTransferred(0), formatSize(totalSize))
 		} else {
-			progressInfo = "0B / Unknown"
+			progressInfo = progressBar(0) + "  0B / Unknown"
 		}
 	}
 
 	return status, progressInfo
 }
 
-func (pf ProgressFormatter) RenderProgressTable(assets []AssetInfo, progresses []DownloadProgress) string {
+func (pf ProgressFormatter) RenderProgressTable(dq DownloadQueue, now time.Time) string {
 	headerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("231")).Bold(true)
-	s := headerStyle.Render("Filename                                 Status          Tag                            Progress") + "\n"
+	s := headerStyle.Render("Filename                                 Status          Signature       Tag                            Progress") + "\n"
 
-	for i, asset := range assets {
+	for i, asset := range dq.assets {
 		var progress DownloadProgress
-		if i < len(progresses) {
-			progress = progresses[i]
+		if i < len(dq.progress) {
+			progress = dq.progress[i]
 		}
 
-		status, progressInfo := pf.FormatProgress(asset, progress)
+		status, progressInfo := pf.FormatProgress(asset, progress, now)
 
-		s += fmt.Sprintf("%-40s %-15s %-30s %s\n",
+		s += fmt.Sprintf("%-40s %-15s %s %-30s %s\n",
 			truncateString(asset.Name, 40),
 			status,
+			alignCell(ansi.Truncate(progress.signature, 15, "…"), 15, false),
 			truncateString(asset.ReleaseTag, 30),
 			progressInfo)
 	}
 
+	s += "\n" + pf.FormatBatchProgress(dq, now) + "\n"
 	return s
 }
 
+// FormatBatchProgress summarizes the files, bytes, rate and time of the
+// whole download queue
+func (pf ProgressFormatter) FormatBatchProgress(dq DownloadQueue, now time.Time) string {
+	var completed int
+	var downloaded, total int64
+	sizesKnown, paused := true, false
+	for i, asset := range dq.assets {
+		var progress DownloadProgress
+		if i < len(dq.progress) {
+			progress = dq.progress[i]
+		}
+		if progress.completed {
+			completed++
+		}
+		if progress.upToDate {
+			// Nothing was transferred for files already present
+			continue
+		}
+		paused = paused || progress.paused
+		size := progress.totalBytes
+		if size == 0 {
+			size = asset.Size
+		}
+		if size <= 0 {
+			sizesKnown = false
+		}
+		downloaded += progress.downloadedBytes
+		total += size
+	}
+
+	elapsed := time.Duration(0)
+	if !dq.started.IsZero() {
+		end := now
+		if !dq.finished.IsZero() {
+			end = dq.finished
+		}
+		elapsed = end.Sub(dq.started)
+	}
+	var rate float64
+	if elapsed > 0 {
+		rate = float64(downloaded) / elapsed.Seconds()
+	}
+
+	s := fmt.Sprintf("Total: %d/%d files  ", completed, len(dq.assets))
+	if sizesKnown && total > 0 {
+		fraction := float64(downloaded) / float64(total)
+		s += fmt.Sprintf("%s %3.0f%%  %s / %s", progressBar(fraction), fraction*100, formatTransferred(downloaded), formatSize(total))
+	} else {
+		s += fmt.Sprintf("%s / Unknown", formatTransferred(downloaded))
+	}
+	s += fmt.Sprintf("  %s  elapsed %s", formatRate(rate), formatDuration(elapsed))
+	if paused {
+		s += "  paused"
+	} else if dq.finished.IsZero() && sizesKnown && total > 0 {
+		s += "  ETA " + formatETA(total-downloaded, rate)
+	}
+	return s
+}
+
+// progressBar draws a bar filled to fraction (0..1)
+func progressBar(fraction float64) string {
+	filled := int(fraction * progressBarWidth)
+	if filled < 0 {
+		filled = 0
+	} else if filled > progressBarWidth {
+		filled = progressBarWidth
+	}
+	return "[" + strings.Repeat("█", filled) + strings.Repeat("░", progressBarWidth-filled) + "]"
+}
+
+// formatTransferred formats a byte count that may still be zero
+func formatTransferred(n int64) string {
+	if n <= 0 {
+		return "0B"
+	}
+	return formatSize(n)
+}
+
+// formatRate formats a transfer rate in bytes per second
+func formatRate(rate float64) string {
+	if rate < 1 {
+		return "-/s"
+	}
+	return formatSize(int64(rate)) + "/s"
+}
+
+// formatETA estimates the time left for remaining bytes at rate
+func formatETA(remaining int64, rate float64) string {
+	if remaining <= 0 {
+		return formatDuration(0)
+	}
+	if rate < 1 {
+		return "--:--"
+	}
+	return formatDuration(time.Duration(float64(remaining) / rate * float64(time.Second)))
+}
+
+// formatDuration formats d as m:ss or h:mm:ss
+func formatDuration(d time.Duration) string {
+	seconds := int(d.Round(time.Second).Seconds())
+	if seconds < 0 {
+		seconds = 0
+	}
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
 // AssetFormatter handles asset information formatting
 type AssetFormatter struct{}
 
 func (af AssetFormatter) FormatAssetInfo(asset Asset, release Release) AssetInfo {
 	formattedDate := formatCreatedAt(asset.CreatedAt)
 	sizeStr := formatSize(asset.Size)
+	var uploader string
+	if asset.Uploader != nil {
+		uploader = asset.Uploader.Login
+	}
 
 	return AssetInfo{
 		Name:          asset.Name,
@@ -313,13 +1103,110 @@ func (af AssetFormatter) FormatAssetInfo(asset Asset, release Release) AssetInfo
 		DownloadURL:   asset.BrowserDownloadURL,
 		Size:          asset.Size,
 		CreatedAt:     asset.CreatedAt,
+		UpdatedAt:     asset.UpdatedAt,
 		Digest:        asset.Digest,
+		ContentType:   asset.ContentType,
+		State:         asset.State,
+		Uploader:      uploader,
 		ReleaseTag:    release.TagName,
 		ReleaseName:   release.Name,
+		DownloadCount: asset.DownloadCount,
 		FormattedDate: formattedDate,
 		SizeStr:       sizeStr,
 		DisplayLine:   af.createDisplayLine(asset.Name, sizeStr, formattedDate, release.TagName),
+		Signatures:    findSignatures(asset.Name, release.Assets),
+	}
+}
+
+// FormatArtifactInfo converts a workflow run artifact into an asset that is
+// downloaded as <name>.zip and extracted into <name>/
+func (af AssetFormatter) FormatArtifactInfo(artifact Artifact, run WorkflowRun) AssetInfo {
+	formattedDate := formatCreatedAt(artifact.CreatedAt)
+	sizeStr := formatSize(artifact.SizeInBytes)
+
+	return AssetInfo{
+		Name:          artifact.Name,
+		ID:            int(artifact.ID),
+		URL:           artifact.ArchiveDownloadURL,
+		DownloadURL:   artifact.ArchiveDownloadURL,
+		Size:          artifact.SizeInBytes,
+		CreatedAt:     artifact.CreatedAt,
+		UpdatedAt:     artifact.UpdatedAt,
+		Digest:        artifact.Digest,
+		ContentType:   "application/zip",
+		ReleaseTag:    run.Label(),
+		ReleaseName:   run.DisplayTitle,
+		FormattedDate: formattedDate,
+		SizeStr:       sizeStr,
+		DisplayLine:   af.createDisplayLineWithoutTag(artifact.Name, sizeStr, formattedDate),
+		Artifact:      true,
+		FileName:      artifactDirName(artifact.Name) + ".zip",
+	}
+}
+
+// FormatOCILayerInfo converts a titled layer of an OCI artifact into an
+// asset saved under its title
+func (af AssetFormatter) FormatOCILayerInfo(layer ociDescriptor, ref ociReference, createdAt string) AssetInfo {
+	title := layer.Annotations[ociTitleAnnotation]
+	formattedDate := formatCreatedAt(createdAt)
+	sizeStr := formatSize(layer.Size)
+	blobURL := ref.blobURL(layer.Digest)
+
+	return AssetInfo{
+		Name:          title,
+		URL:           blobURL,
+		DownloadURL:   blobURL,
+		Size:          layer.Size,
+		CreatedAt:     createdAt,
+		Digest:        layer.Digest,
+		ContentType:   layer.MediaType,
+		ReleaseTag:    ref.Reference,
+		ReleaseName:   ref.Registry + "/" + ref.Repository,
+		FormattedDate: formattedDate,
+		SizeStr:       sizeStr,
+		DisplayLine:   af.createDisplayLineWithoutTag(title, sizeStr, formattedDate),
+		OCIBlob:       true,
+		FileName:      ociLayerFileName(title),
+	}
+}
+
+// SourceArchiveAssets returns the "Source code" pseudo-assets of release,
+// saved as <repo>-<tag>.tar.gz and <repo>-<tag>.zip
+func (af AssetFormatter) SourceArchiveAssets(release Release, repoName string) []AssetInfo {
+	archives := []struct {
+		format      string
+		extension   string
+		contentType string
+		url         string
+	}{
+		{"tar.gz", ".tar.gz", "application/x-gzip", release.TarballURL},
+		{"zip", ".zip", "application/zip", release.ZipballURL},
+	}
+
+	baseName := strings.NewReplacer("/", "-", "\\", "-").Replace(repoName + "-" + release.TagName)
+	var assets []AssetInfo
+	for _, archive := range archives {
+		if archive.url == "" {
+			continue
+		}
+		name := fmt.Sprintf("Source code (%s)", archive.format)
+		formattedDate := formatCreatedAt(release.PublishedAt)
+		assets = append(assets, AssetInfo{
+			Name:          name,
+			URL:           archive.url,
+			DownloadURL:   archive.url,
+			CreatedAt:     release.PublishedAt,
+			ContentType:   archive.contentType,
+			ReleaseTag:    release.TagName,
+			ReleaseName:   release.Name,
+			FormattedDate: formattedDate,
+			SizeStr:       formatSize(0),
+			DisplayLine:   af.createDisplayLineWithoutTag(name, formatSize(0), formattedDate),
+			SourceArchive: true,
+			FileName:      baseName + archive.extension,
+		})
 	}
+	return assets
 }
 
 func (af AssetFormatter) createDisplayLine(name, sizeStr, formattedDate, releaseTag string) string {
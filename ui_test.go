@@ -0,0 +1,146 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// newAssetList returns an asset list view of assets with the given names
+func newAssetList(names ...string) *UnifiedListView {
+	assets := make([]AssetInfo, len(names))
+	for i, name := range names {
+		assets[i] = AssetInfo{Name: name}
+	}
+	ulv := &UnifiedListView{}
+	ulv.SetAssets(assets)
+	return ulv
+}
+
+// selectedNames returns the names of the selected assets, sorted
+func selectedNames(ulv *UnifiedListView) []string {
+	var names []string
+	for _, asset := range ulv.GetSelectedAssets() {
+		names = append(names, asset.Name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// pressKeys sends keys to the navigation and selection handlers like the model does
+func pressKeys(t *testing.T, ulv *UnifiedListView, keys ...string) {
+	t.Helper()
+	for _, key := range keys {
+		if ulv.globActive {
+			ulv.HandleGlobPromptKey(key)
+			continue
+		}
+		if !ulv.HandleNavigationKey(key) && !ulv.HandleSelectionKey(key) {
+			t.Fatalf("key %q not handled", key)
+		}
+	}
+}
+
+var testAssetNames = []string{
+	"app_linux_amd64.tar.gz",
+	"app_windows_amd64.zip",
+	"app_linux_arm64.tar.gz",
+	"app_darwin_arm64.tar.gz",
+	"app_linux_386.tar.gz",
+	"checksums.txt",
+}
+
+func TestBulkSelectionOnFilteredList(t *testing.T) {
+	ulv := newAssetList(testAssetNames...)
+	pressKeys(t, ulv, "G", " ")
+	ulv.SetSort(SortName, false)
+	ulv.SetFilter("linux")
+
+	// Select-all and invert only touch the items that match the filter
+	pressKeys(t, ulv, "a")
+	want := []string{"app_linux_386.tar.gz", "app_linux_amd64.tar.gz", "app_linux_arm64.tar.gz", "checksums.txt"}
+	if got := selectedNames(ulv); !slices.Equal(got, want) {
+		t.Errorf("after select-all: %v, want %v", got, want)
+	}
+	pressKeys(t, ulv, " ", "i")
+	want = []string{"app_linux_386.tar.gz", "checksums.txt"}
+	if got := selectedNames(ulv); !slices.Equal(got, want) {
+		t.Errorf("after invert: %v, want %v", got, want)
+	}
+	pressKeys(t, ulv, "x")
+	if got := selectedNames(ulv); !slices.Equal(got, []string{"checksums.txt"}) {
+		t.Errorf("after select-none: %v", got)
+	}
+}
+
+func TestGlobSelectionOnFilteredList(t *testing.T) {
+	ulv := newAssetList(testAssetNames...)
+	ulv.SetFilter("linux")
+
+	// Hidden assets are not selected even if they match the pattern
+	count, err := ulv.SelectByGlob("*arm64*")
+	if err != nil || count != 1 {
+		t.Errorf("SelectByGlob = %d, %v, want 1 match", count, err)
+	}
+	if got := selectedNames(ulv); !slices.Equal(got, []string{"app_linux_arm64.tar.gz"}) {
+		t.Errorf("selected %v", got)
+	}
+
+	// The prompt adds to the selection and reports the matches
+	pressKeys(t, ulv, "*", "*", ".", "t", "a", "r", ".", "g", "z", "enter")
+	want := []string{"app_linux_386.tar.gz", "app_linux_amd64.tar.gz", "app_linux_arm64.tar.gz"}
+	if got := selectedNames(ulv); !slices.Equal(got, want) {
+		t.Errorf("after the prompt: %v, want %v", got, want)
+	}
+	if ulv.globActive || ulv.status != `3 asset(s) matched "*.tar.gz"` {
+		t.Errorf("prompt active %v, status %q", ulv.globActive, ulv.status)
+	}
+
+	if _, err := ulv.SelectByGlob("[arm"); err == nil {
+		t.Error("invalid pattern accepted")
+	}
+	pressKeys(t, ulv, "x", "*", "[", "enter")
+	if got := selectedNames(ulv); len(got) != 0 || ulv.status != `invalid pattern "[": syntax error in pattern` {
+		t.Errorf("invalid pattern: selected %v, status %q", got, ulv.status)
+	}
+}
+
+func TestVisualSelectionOnFilteredList(t *testing.T) {
+	ulv := newAssetList(testAssetNames...)
+	ulv.SetSort(SortName, false)
+	ulv.SetFilter("linux")
+
+	// The range covers rows of the filtered list, not of the full list:
+	// amd64 and arm64 are next to each other only while filtering
+	pressKeys(t, ulv, "j", "v", "j")
+	want := []string{"app_linux_amd64.tar.gz", "app_linux_arm64.tar.gz"}
+	if got := selectedNames(ulv); !slices.Equal(got, want) {
+		t.Errorf("range %v, want %v", got, want)
+	}
+	pressKeys(t, ulv, "k", "k")
+	want = []string{"app_linux_386.tar.gz", "app_linux_amd64.tar.gz"}
+	if got := selectedNames(ulv); !slices.Equal(got, want) {
+		t.Errorf("range above the anchor %v, want %v", got, want)
+	}
+
+	// Re-filtering keeps the range but ends range selection
+	ulv.SetFilter("arm")
+	if ulv.visualActive {
+		t.Fatal("range selection survived re-filtering")
+	}
+	pressKeys(t, ulv, "j")
+	if got := selectedNames(ulv); !slices.Equal(got, want) {
+		t.Errorf("moving after re-filtering changed the selection: %v", got)
+	}
+
+	// A new range is anchored in the re-filtered list
+	pressKeys(t, ulv, "v", "k")
+	want = []string{"app_darwin_arm64.tar.gz", "app_linux_386.tar.gz", "app_linux_amd64.tar.gz", "app_linux_arm64.tar.gz"}
+	if got := selectedNames(ulv); !slices.Equal(got, want) {
+		t.Errorf("range after re-filtering %v, want %v", got, want)
+	}
+	ulv.CancelVisualMode()
+	want = []string{"app_linux_386.tar.gz", "app_linux_amd64.tar.gz"}
+	if got := selectedNames(ulv); !slices.Equal(got, want) {
+		t.Errorf("cancelled range left %v, want %v", got, want)
+	}
+}